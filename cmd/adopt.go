@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/doodleEsc/ctx-tool/internal/apperr"
+	"github.com/doodleEsc/ctx-tool/internal/checksum"
+	"github.com/doodleEsc/ctx-tool/internal/config"
+	"github.com/doodleEsc/ctx-tool/internal/i18n"
+	"github.com/doodleEsc/ctx-tool/internal/logging"
+	"github.com/doodleEsc/ctx-tool/internal/tracker"
+	"github.com/spf13/cobra"
+)
+
+var adoptGlobalFlag bool
+
+var adoptCmd = &cobra.Command{
+	Use:     "adopt [directories...]",
+	Short:   "Track files that already exist as if ctx-tool had installed them",
+	Long:    "Fetch the upstream repository and take over local copies of its files, found at the path they would be installed to or, with identical content, elsewhere in the same directories. The files are recorded in the tracking file without being rewritten, so they can be updated and removed like installed files.",
+	Example: "  ctx-tool adopt\n  ctx-tool adopt .claude\n  ctx-tool adopt --global",
+	RunE:    runAdopt,
+}
+
+func init() {
+	rootCmd.AddCommand(adoptCmd)
+
+	adoptCmd.Flags().BoolVar(&adoptGlobalFlag, "global", false, "Adopt files of the global installation")
+	adoptCmd.Flags().BoolVar(&trustFlag, "trust", false, "Trust the repository without asking if it isn't a trusted source yet")
+}
+
+func runAdopt(cmd *cobra.Command, args []string) error {
+	scope, err := resolveScope(adoptGlobalFlag)
+	if err != nil {
+		return err
+	}
+
+	report.Scope = scope.Name
+	report.Target = scope.BasePath
+	report.TrackingFile = scope.TrackingFile
+
+	fmt.Fprintf(out, "%s\n", i18n.Tf(i18n.MsgInstallationScope, map[string]interface{}{"Scope": scope.Name}))
+	fmt.Fprintf(out, "%s\n", i18n.Tf(i18n.MsgTargetDirectory, map[string]interface{}{"Target": scope.BasePath}))
+
+	algo, err := checksum.ParseAlgorithm(cfg.Behavior.HashAlgorithm)
+	if err != nil {
+		return apperr.Wrap(apperr.Usage, fmt.Errorf("invalid behavior.hash_algorithm: %w", err))
+	}
+
+	trackerInstance := tracker.NewTracker(scope.TrackingFile, scope.Name, scope.BasePath)
+	unlock, err := trackerInstance.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	trackerInstance.Algorithm = algo
+	trackerInstance.Cache = checksum.LoadCache(config.GetHashCachePath())
+	defer func() {
+		if err := trackerInstance.Cache.Save(); err != nil {
+			fmt.Fprintf(out, "Warning: Failed to save hash cache: %v\n", err)
+		}
+	}()
+	done := logging.Phase("load tracking data")
+	if err := trackerInstance.Load(); err != nil {
+		return fmt.Errorf("load tracking data: %w", err)
+	}
+	done()
+
+	// Files join an existing installation at the revision it was installed
+	// from, so all of its files share one provenance
+	installation := trackerInstance.Installation
+	var src *upstream
+	var cleanup func()
+	if installation.Repository != "" && installation.Revision != "" {
+		src, cleanup, err = cloneRevision(installation.Repository, installation.Branch, installation.Tag, installation.Revision)
+	} else {
+		src, cleanup, err = cloneUpstream(cfg.Repository.URL, cfg.Repository.Branch)
+	}
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	syncer, err := newSyncer(scope, src, trackerInstance)
+	if err != nil {
+		return err
+	}
+	done = logging.Phase("adopt")
+	adopted, err := syncer.Adopt(args)
+	if err != nil {
+		return fmt.Errorf("adopt files: %w", err)
+	}
+	done()
+
+	if len(adopted) == 0 {
+		fmt.Fprintln(out, i18n.T(i18n.MsgAdoptNothing))
+		return nil
+	}
+
+	differing := 0
+	for _, a := range adopted {
+		fr := fileReport{Path: a.Path, Action: "adopted", Hash: a.Hash, Algorithm: string(algo)}
+		key := i18n.MsgAdoptFile
+		if a.Moved {
+			fr.From = a.RepoPath
+			key = i18n.MsgAdoptMoved
+		}
+		if !a.Identical {
+			differing++
+			fr.Reason = tracker.StateModified
+			key = i18n.MsgAdoptDiffers
+		}
+		report.addFile(fr)
+		fmt.Fprintf(out, "  %s\n", i18n.Tf(key, map[string]interface{}{"File": a.Path, "Upstream": a.RepoPath}))
+	}
+
+	if installation.Revision == "" {
+		trackerInstance.SetSource(src.URL, src.Branch, src.Tag, src.Revision, src.Signature)
+	}
+	done = logging.Phase("save tracking data")
+	if err := trackerInstance.Save(); err != nil {
+		return fmt.Errorf("save tracking data: %w", err)
+	}
+	done()
+
+	registerProject(scope, trackerInstance)
+
+	fmt.Fprintf(out, "\n%s\n", i18n.Tn(i18n.MsgAdoptComplete, len(adopted), map[string]interface{}{"Count": len(adopted)}))
+	fmt.Fprintf(out, "%s\n", i18n.Tf(i18n.MsgTrackingFileSaved, map[string]interface{}{"Path": scope.TrackingFile}))
+	if differing > 0 {
+		fmt.Fprintf(out, "%s\n", i18n.Tn(i18n.MsgAdoptDiffering, differing, map[string]interface{}{"Count": differing}))
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/doodleEsc/ctx-tool/internal/apperr"
+	"github.com/doodleEsc/ctx-tool/internal/config"
+	"github.com/doodleEsc/ctx-tool/internal/doctor"
+	"github.com/doodleEsc/ctx-tool/internal/git"
+	"github.com/doodleEsc/ctx-tool/internal/i18n"
+	"github.com/doodleEsc/ctx-tool/internal/registry"
+	"github.com/spf13/cobra"
+)
+
+var doctorOfflineFlag bool
+
+var doctorCmd = &cobra.Command{
+	Use:     "doctor",
+	Short:   "Check the configuration, upstream and installations for problems",
+	Long:    "Check which config file is used, whether the upstream repository is reachable, whether tracking files are intact, and look for leftovers of interrupted installs. Each problem comes with a command that fixes it.",
+	Example: "  ctx-tool doctor\n  ctx-tool doctor --offline\n  ctx-tool doctor --output json",
+	Args:    cobra.NoArgs,
+	RunE:    runDoctor,
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+
+	doctorCmd.Flags().BoolVar(&doctorOfflineFlag, "offline", false, "Don't contact the upstream repository")
+}
+
+// checkReport is the machine-readable result of one doctor check
+type checkReport struct {
+	Check  string `json:"check"`
+	Status string `json:"status"`
+	Detail string `json:"detail"`
+	Fix    string `json:"fix,omitempty"`
+}
+
+var checkSymbols = map[string]string{
+	doctor.OK:      "✓",
+	doctor.Warning: "!",
+	doctor.Problem: "✗",
+}
+
+// addChecks prints and records the results of checks
+func addChecks(checks []doctor.Check) {
+	for _, c := range checks {
+		detail := i18n.Tf(c.Message, c.Data)
+		fmt.Fprintf(out, "%s %s\n", checkSymbols[c.Status], detail)
+		if c.Fix != "" {
+			fmt.Fprintf(out, "    %s\n", i18n.Tf(i18n.MsgDoctorFix, map[string]interface{}{"Command": c.Fix}))
+		}
+		report.Checks = append(report.Checks, checkReport{Check: c.Check, Status: c.Status, Detail: detail, Fix: c.Fix})
+		report.Summary[c.Status]++
+	}
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	addChecks(doctor.Config(configManager.File(), cfgFile))
+	if !doctorOfflineFlag {
+		checkRepository()
+	}
+
+	installs, err := doctorInstalls()
+	if err != nil {
+		return err
+	}
+	home, _ := os.UserHomeDir()
+	for _, inst := range installs {
+		addChecks(doctor.CheckInstall(inst, home, cfg.Tracking.File))
+	}
+	addChecks(doctor.TempClones(doctor.StaleCloneAge))
+	checkPermissions(installs)
+
+	problems, warnings := report.Summary[doctor.Problem], report.Summary[doctor.Warning]
+	fmt.Fprintln(out)
+	if problems == 0 && warnings == 0 {
+		fmt.Fprintln(out, i18n.T(i18n.MsgDoctorHealthy))
+		return nil
+	}
+	fmt.Fprintln(out, i18n.Tf(i18n.MsgDoctorSummary, map[string]interface{}{"Problems": problems, "Warnings": warnings}))
+	if problems > 0 {
+		return apperr.New(apperr.Partial, "doctor found %d problems", problems)
+	}
+	return nil
+}
+
+// checkRepository asks the configured upstream for its branch or tag
+func checkRepository() {
+	url := git.RedactURL(cfg.Repository.URL)
+	ref := cfg.Repository.Branch
+	refKey := "repository.branch"
+	if cfg.Repository.Tag != "" {
+		ref, refKey = "tag "+cfg.Repository.Tag, "repository.tag"
+	}
+	data := map[string]interface{}{"URL": url, "Ref": ref, "Source": settingSource("repository.url")}
+
+	client := newGitClient(cfg.Repository.URL, cfg.Repository.Branch)
+	client.SetTag(cfg.Repository.Tag)
+	revision, err := client.RemoteRevision()
+	if err == nil {
+		data["Revision"] = shortRevision(revision)
+		addChecks([]doctor.Check{{Check: "repository", Status: doctor.OK, Message: i18n.MsgDoctorRepositoryOK, Data: data}})
+		return
+	}
+
+	data["Error"] = err
+	fix := "git ls-remote " + doctor.ShellQuote(url)
+	switch apperr.KindOf(err) {
+	case apperr.NotFound:
+		data["Source"] = settingSource(refKey)
+		fix = "git ls-remote --heads --tags " + doctor.ShellQuote(url)
+	case apperr.Auth:
+		fix = "export CTX_TOOL_REPOSITORY_AUTH_TOKEN=<token>"
+	}
+	addChecks([]doctor.Check{{Check: "repository", Status: doctor.Problem, Message: i18n.MsgDoctorRepositoryFailed, Data: data, Fix: fix}})
+}
+
+// settingSource describes where a setting came from for messages
+func settingSource(key string) string {
+	if source := configManager.Source(key); source != "" {
+		return source
+	}
+	return i18n.T(i18n.MsgDoctorSourceDefault)
+}
+
+// doctorInstalls returns the installations to inspect: the current
+// project, the global installation and every registered project
+func doctorInstalls() ([]doctor.Install, error) {
+	var installs []doctor.Install
+	seen := make(map[string]bool)
+	add := func(inst doctor.Install) {
+		key, err := filepath.Abs(inst.TrackingFile)
+		if err != nil {
+			key = inst.TrackingFile
+		}
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		installs = append(installs, inst)
+	}
+
+	scope, err := projectScope(false)
+	if err != nil {
+		return nil, err
+	}
+	if config.FileExists(scope.TrackingFile) {
+		base, err := filepath.Abs(scope.BasePath)
+		if err != nil {
+			return nil, err
+		}
+		add(doctor.Install{TrackingFile: scope.TrackingFile, BasePath: base, Scope: scope.Name})
+	}
+
+	// Not resolveScope(true): that would migrate the legacy file we check
+	if home, err := os.UserHomeDir(); err == nil {
+		if global := config.GetGlobalTrackingPath(); config.FileExists(global) {
+			add(doctor.Install{TrackingFile: global, BasePath: filepath.Join(home, ".claude"), Scope: "global"})
+		}
+		if legacy := filepath.Join(home, config.LegacyGlobalTrackingFile); config.FileExists(legacy) {
+			add(doctor.Install{TrackingFile: legacy, BasePath: home, Scope: "project"})
+		}
+	}
+
+	reg, err := registry.Load(config.GetRegistryPath())
+	if err != nil {
+		addChecks([]doctor.Check{{Check: "registry", Status: doctor.Problem, Message: i18n.MsgDoctorUnreadable,
+			Data: map[string]interface{}{"Path": config.GetRegistryPath(), "Error": err},
+			Fix:  "mv " + doctor.ShellQuote(config.GetRegistryPath()) + " " + doctor.ShellQuote(config.GetRegistryPath()+".broken") + " && ctx-tool scan ~"}})
+		return installs, nil
+	}
+	for _, p := range reg.Projects {
+		if !config.FileExists(p.TrackingFile) {
+			addChecks([]doctor.Check{{Check: "registry", Status: doctor.Warning, Message: i18n.MsgDoctorProjectMissing,
+				Data: map[string]interface{}{"Path": p.Path}, Fix: "ctx-tool projects prune"}})
+			continue
+		}
+		add(doctor.Install{TrackingFile: p.TrackingFile, BasePath: p.Path, Scope: p.Scope, Registered: true})
+	}
+	return installs, nil
+}
+
+// checkPermissions checks that ctx-tool can write where it keeps its own
+// files and where it installs
+func checkPermissions(installs []doctor.Install) {
+	dirs := []string{
+		config.GetXDGConfigDir(),
+		filepath.Dir(config.GetGlobalTrackingPath()),
+		filepath.Dir(config.GetRegistryPath()),
+		filepath.Dir(config.GetHashCachePath()),
+	}
+	for _, inst := range installs {
+		dirs = append(dirs, inst.BasePath)
+	}
+	addChecks(doctor.Permissions(dirs))
+}
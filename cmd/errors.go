@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"github.com/doodleEsc/ctx-tool/internal/apperr"
+	"github.com/doodleEsc/ctx-tool/internal/i18n"
+)
+
+// hintKeys maps error kinds to the localized hint printed below the error
+var hintKeys = map[apperr.Kind]string{
+	apperr.Usage:      i18n.HintUsage,
+	apperr.Network:    i18n.HintNetwork,
+	apperr.Auth:       i18n.HintAuth,
+	apperr.NotFound:   i18n.HintNotFound,
+	apperr.NotAllowed: i18n.HintNotAllowed,
+	apperr.Conflict:   i18n.HintConflict,
+	apperr.Locked:     i18n.HintLocked,
+	apperr.Cancelled:  i18n.HintCancelled,
+	apperr.Partial:    i18n.HintPartial,
+	apperr.Untrusted:  i18n.HintUntrusted,
+}
+
+// errorHint returns a localized suggestion for err, or "" if there is none
+func errorHint(err error) string {
+	key, ok := hintKeys[apperr.KindOf(err)]
+	if !ok {
+		return ""
+	}
+	return i18n.T(key)
+}
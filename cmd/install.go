@@ -0,0 +1,368 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/doodleEsc/ctx-tool/internal/apperr"
+	"github.com/doodleEsc/ctx-tool/internal/checksum"
+	"github.com/doodleEsc/ctx-tool/internal/config"
+	"github.com/doodleEsc/ctx-tool/internal/event"
+	"github.com/doodleEsc/ctx-tool/internal/git"
+	"github.com/doodleEsc/ctx-tool/internal/i18n"
+	"github.com/doodleEsc/ctx-tool/internal/logging"
+	"github.com/doodleEsc/ctx-tool/internal/registry"
+	"github.com/doodleEsc/ctx-tool/internal/store"
+	"github.com/doodleEsc/ctx-tool/internal/sync"
+	"github.com/doodleEsc/ctx-tool/internal/tracker"
+)
+
+// storeLockTimeout is how long to wait for another run linking into the
+// store or pruning it
+const storeLockTimeout = time.Minute
+
+// upstream is a local clone of the configuration repository
+type upstream struct {
+	URL      string
+	Branch   string
+	Tag      string
+	Dir      string
+	Revision string
+	// Signature is set when the revision's signature was verified
+	Signature *tracker.Signature
+}
+
+// cloneUpstream checks that the repository is a trusted source, clones it
+// into a temporary directory and, if configured, verifies its signature.
+// The returned function removes the clone.
+func cloneUpstream(url, branch string) (*upstream, func(), error) {
+	// The configured tag belongs to the configured repository only
+	tag := ""
+	if git.RedactURL(url) == git.RedactURL(cfg.Repository.URL) {
+		tag = cfg.Repository.Tag
+	}
+	return cloneRevision(url, branch, tag, "")
+}
+
+// cloneRevision is cloneUpstream for a given tag and, unless empty, a
+// pinned revision of the branch or tag
+func cloneRevision(url, branch, tag, revision string) (*upstream, func(), error) {
+	if err := ensureTrusted(url); err != nil {
+		return nil, nil, err
+	}
+	keyring, err := loadKeyring()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	gitClient := newGitClient(url, branch)
+	gitClient.SetTag(tag)
+	gitClient.SetRevision(revision)
+	gitClient.SetObserver(observer)
+
+	done := logging.Phase("clone")
+	tempDir, err := gitClient.CloneToTemp()
+	if err != nil {
+		return nil, nil, err
+	}
+	done()
+
+	var signature *tracker.Signature
+	if keyring != nil {
+		done := logging.Phase("verify signature")
+		signature, err = verifySignature(tempDir, tag, gitClient.Revision(), keyring)
+		if err != nil {
+			os.RemoveAll(tempDir)
+			return nil, nil, err
+		}
+		done()
+	}
+
+	cleanup := func() {
+		// Always clean up temp directory
+		os.RemoveAll(tempDir)
+		fmt.Fprintf(out, "Cleaned up temporary directory\n")
+	}
+	return &upstream{
+		URL:       git.RedactURL(url),
+		Branch:    branch,
+		Tag:       tag,
+		Dir:       tempDir,
+		Revision:  gitClient.Revision(),
+		Signature: signature,
+	}, cleanup, nil
+}
+
+// newGitClient returns a client for url using the configured credentials.
+// The token is only sent to the host of the configured repository, so
+// registered projects from other hosts never receive it.
+func newGitClient(url, branch string) *git.Client {
+	authCfg := cfg.Repository.Auth
+	auth := git.Auth{
+		Username:         authCfg.Username,
+		SSHKey:           authCfg.SSHKey,
+		SSHKeyPassphrase: authCfg.SSHKeyPassphrase,
+		CredentialHelper: authCfg.CredentialHelper,
+	}
+	if git.SameHost(url, cfg.Repository.URL) {
+		auth.Token = authCfg.Token
+		if auth.Token == "" && authCfg.TokenEnv != "" {
+			auth.Token = os.Getenv(authCfg.TokenEnv)
+		}
+	}
+
+	client := git.NewClient(url, branch)
+	client.SetAuth(auth)
+	return client
+}
+
+// installInto syncs directories from src into scope, saves the tracking data
+// and registers the project. With all set every allowed directory is synced;
+// with neither all nor dirs the directories the scope already has installed
+// are updated.
+func installInto(scope *installScope, src *upstream, dirs []string, all bool) error {
+	algo, err := checksum.ParseAlgorithm(cfg.Behavior.HashAlgorithm)
+	if err != nil {
+		return apperr.Wrap(apperr.Usage, fmt.Errorf("invalid behavior.hash_algorithm: %w", err))
+	}
+
+	// Initialize tracker
+	trackerInstance := tracker.NewTracker(scope.TrackingFile, scope.Name, scope.BasePath)
+	unlock, err := trackerInstance.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	trackerInstance.Algorithm = algo
+	trackerInstance.Cache = checksum.LoadCache(config.GetHashCachePath())
+	defer func() {
+		if err := trackerInstance.Cache.Save(); err != nil {
+			fmt.Fprintf(out, "Warning: Failed to save hash cache: %v\n", err)
+		}
+	}()
+
+	// Load existing tracking data
+	done := logging.Phase("load tracking data")
+	if err := trackerInstance.Load(); err != nil {
+		return fmt.Errorf("load tracking data: %w", err)
+	}
+	done()
+	if err := migrateLayout(scope, trackerInstance); err != nil {
+		return err
+	}
+	if err := detachEditedLinks(trackerInstance); err != nil {
+		return err
+	}
+	unlockStore, err := lockStore()
+	if err != nil {
+		return err
+	}
+	defer unlockStore()
+	trackerInstance.SetSource(src.URL, src.Branch, src.Tag, src.Revision, src.Signature)
+
+	syncer, err := newSyncer(scope, src, trackerInstance)
+	if err != nil {
+		return err
+	}
+
+	if !all && len(dirs) == 0 {
+		dirs = installedDirs(trackerInstance)
+	}
+
+	// Review what the install adds before writing anything. Plan(nil)
+	// covers every directory, so it is only asked for with --all.
+	if all || len(dirs) > 0 {
+		done = logging.Phase("review")
+		planDirs := dirs
+		if all {
+			planDirs = nil
+		}
+		planned, err := syncer.Plan(planDirs)
+		if err != nil {
+			return fmt.Errorf("plan installation: %w", err)
+		}
+		if err := reviewIncoming(scope.BasePath, planned); err != nil {
+			return err
+		}
+		done()
+	}
+
+	// Determine what to sync
+	done = logging.Phase("sync")
+	if all {
+		// Sync all allowed directories
+		fmt.Fprintln(out, i18n.T(i18n.MsgSyncingAll))
+		if err := syncer.SyncAll(); err != nil {
+			return fmt.Errorf("sync all directories: %w", err)
+		}
+	} else {
+		// Sync specified directories
+		for _, dir := range dirs {
+			fmt.Fprintf(out, "%s\n", i18n.Tf(i18n.MsgSyncingDirectory, map[string]interface{}{"Dir": dir}))
+			if err := syncer.SyncDirectory(dir); err != nil {
+				return fmt.Errorf("sync directory %s: %w", dir, err)
+			}
+		}
+	}
+	done()
+
+	// Save tracking data
+	done = logging.Phase("save tracking data")
+	if err := trackerInstance.Save(); err != nil {
+		return fmt.Errorf("save tracking data: %w", err)
+	}
+	done()
+
+	registerProject(scope, trackerInstance)
+
+	fmt.Fprintf(out, "\n%s\n", i18n.T(i18n.MsgInstallationComplete))
+	fmt.Fprintf(out, "%s\n", i18n.Tf(i18n.MsgTrackingFileSaved, map[string]interface{}{"Path": scope.TrackingFile}))
+	fmt.Fprintf(out, "%s\n", i18n.Tn(i18n.MsgFilesInstalled, len(trackerInstance.GetTrackedFiles()), map[string]interface{}{"Count": len(trackerInstance.GetTrackedFiles())}))
+
+	return nil
+}
+
+// newSyncer returns a syncer installing from src into scope with the
+// configured install mode and template variables
+func newSyncer(scope *installScope, src *upstream, t *tracker.Tracker) (*sync.Syncer, error) {
+	installMode, err := store.ParseMode(cfg.Behavior.InstallMode)
+	if err != nil {
+		return nil, fmt.Errorf("invalid behavior.install_mode: %w", err)
+	}
+
+	syncer := sync.NewSyncer(src.Dir, scope.BasePath, t, cfg)
+	syncer.SetObserver(observer)
+	syncer.SetPathMap(scope.PathMap)
+	syncer.SetStore(store.New(config.GetStorePath(), t.Algorithm), installMode)
+	data, err := templateData(scope)
+	if err != nil {
+		return nil, fmt.Errorf("collect template variables: %w", err)
+	}
+	syncer.SetTemplateData(data)
+	return syncer, nil
+}
+
+// installedDirs returns the allowed repository directories that have files
+// tracked in t
+func installedDirs(t *tracker.Tracker) []string {
+	allowed := make(map[string]bool)
+	for _, dir := range cfg.Directories.Allowed {
+		allowed[dir] = true
+	}
+
+	seen := make(map[string]bool)
+	var dirs []string
+	for _, relPath := range t.GetTrackedFiles() {
+		entry, _ := t.GetEntry(relPath)
+		repoPath := entry.Path
+		if entry.Upstream != "" {
+			repoPath = entry.Upstream
+		}
+
+		dir, _, _ := strings.Cut(filepath.ToSlash(repoPath), "/")
+		if allowed[dir] && !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+// lockStore takes the store lock when files are installed as links into
+// it. The lock is held until the project is registered, so a concurrent
+// prune doesn't remove the objects linked in meanwhile.
+func lockStore() (func() error, error) {
+	if mode, err := store.ParseMode(cfg.Behavior.InstallMode); err != nil || mode == store.Copy {
+		// newSyncer reports an invalid mode
+		return func() error { return nil }, nil
+	}
+	return store.Lock(config.GetStorePath(), storeLockTimeout)
+}
+
+// registerProject records the installation in the machine-wide registry.
+// A failure only costs the registry entry, so it is logged and ignored,
+// unless the installation has symlinks into the store: a prune would then
+// remove what they point to, so the user is warned.
+func registerProject(scope *installScope, t *tracker.Tracker) {
+	project, err := registryEntry(scope, t)
+	if err == nil {
+		err = registry.Update(config.GetRegistryPath(), func(r *registry.Registry) error {
+			r.Register(project)
+			return nil
+		})
+	}
+	if err != nil {
+		slog.Warn("failed to register project", "path", scope.BasePath, "error", err)
+		if linkMode(t) == store.Symlink {
+			observer.Notify(event.Event{Kind: event.Warning, Reason: event.ReasonRegisterFailed, Path: scope.BasePath, Err: err})
+		}
+	}
+}
+
+// linkMode returns the strongest link t's files have into the store
+func linkMode(t *tracker.Tracker) string {
+	mode := store.Copy
+	for _, entry := range t.Installation.Files {
+		switch entry.Link {
+		case store.Symlink:
+			return store.Symlink
+		case store.Hardlink:
+			mode = store.Hardlink
+		}
+	}
+	return mode
+}
+
+// unregisterProject removes the installation from the registry
+func unregisterProject(scope *installScope) {
+	path, err := filepath.Abs(scope.BasePath)
+	if err == nil {
+		err = registry.Update(config.GetRegistryPath(), func(r *registry.Registry) error {
+			r.Unregister(path)
+			return nil
+		})
+	}
+	if err != nil {
+		slog.Warn("failed to unregister project", "path", scope.BasePath, "error", err)
+	}
+}
+
+// loadRegistryEntry reads the tracking file of scope and describes it for
+// the registry
+func loadRegistryEntry(scope *installScope) (registry.Project, error) {
+	t := tracker.NewTracker(scope.TrackingFile, scope.Name, scope.BasePath)
+	if err := t.Load(); err != nil {
+		return registry.Project{}, err
+	}
+	return registryEntry(scope, t)
+}
+
+// registryEntry describes an installation for the registry
+func registryEntry(scope *installScope, t *tracker.Tracker) (registry.Project, error) {
+	path, err := filepath.Abs(scope.BasePath)
+	if err != nil {
+		return registry.Project{}, err
+	}
+	trackingFile, err := filepath.Abs(scope.TrackingFile)
+	if err != nil {
+		return registry.Project{}, err
+	}
+
+	installation := t.Installation
+	return registry.Project{
+		Path:         path,
+		Scope:        scope.Name,
+		TrackingFile: trackingFile,
+		Repository:   installation.Repository,
+		Branch:       installation.Branch,
+		Revision:     installation.Revision,
+		InstallMode:  linkMode(t),
+		UpdatedAt:    installation.Timestamp,
+	}, nil
+}
@@ -0,0 +1,266 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+
+	"github.com/doodleEsc/ctx-tool/internal/apperr"
+	"github.com/doodleEsc/ctx-tool/internal/event"
+)
+
+// Output formats accepted by --output
+const (
+	outputText   = "text"
+	outputJSON   = "json"
+	outputNDJSON = "ndjson"
+)
+
+var (
+	outputFormat string
+
+	// out receives human readable output; it is discarded in JSON modes
+	out io.Writer = os.Stdout
+
+	// report collects the structured result of the running command
+	report *commandReport
+)
+
+// fileReport is the machine-readable outcome for a single file
+type fileReport struct {
+	Path         string `json:"path"`
+	Action       string `json:"action"`
+	Reason       string `json:"reason,omitempty"`
+	Hash         string `json:"hash,omitempty"`
+	PreviousHash string `json:"previous_hash,omitempty"`
+	Algorithm    string `json:"algorithm,omitempty"`
+	Backup       string `json:"backup,omitempty"`
+	From         string `json:"from,omitempty"`
+	Link         string `json:"link,omitempty"`
+}
+
+// repositoryReport describes the upstream a command installed from
+type repositoryReport struct {
+	URL      string `json:"url"`
+	Branch   string `json:"branch,omitempty"`
+	Tag      string `json:"tag,omitempty"`
+	Revision string `json:"revision,omitempty"`
+	// Signer and SignerKey identify who signed the revision, if verified
+	Signer    string `json:"signer,omitempty"`
+	SignerKey string `json:"signer_key,omitempty"`
+}
+
+// commandReport is the document printed by --output json and the final
+// record of --output ndjson
+type commandReport struct {
+	Type         string            `json:"type"`
+	Command      string            `json:"command"`
+	OK           bool              `json:"ok"`
+	ConfigFile   string            `json:"config_file,omitempty"`
+	Scope        string            `json:"scope,omitempty"`
+	Target       string            `json:"target,omitempty"`
+	TrackingFile string            `json:"tracking_file,omitempty"`
+	Repository   *repositoryReport `json:"repository,omitempty"`
+	Files        []fileReport      `json:"files"`
+	Projects     []projectReport   `json:"projects,omitempty"`
+	// Capabilities lists what the security review found the install adds
+	// or removes
+	Capabilities []capabilityReport `json:"capabilities,omitempty"`
+	// Checks are the results of doctor
+	Checks    []checkReport  `json:"checks,omitempty"`
+	Summary   map[string]int `json:"summary"`
+	Warnings  []string       `json:"warnings,omitempty"`
+	Errors    []string       `json:"errors,omitempty"`
+	ErrorKind string         `json:"error_kind,omitempty"`
+	ExitCode  int            `json:"exit_code"`
+}
+
+func newCommandReport(command string) *commandReport {
+	return &commandReport{
+		Type:    "result",
+		Command: command,
+		Files:   []fileReport{},
+		Summary: map[string]int{},
+	}
+}
+
+// addFile records a file outcome and counts it in the summary
+func (r *commandReport) addFile(f fileReport) {
+	r.Files = append(r.Files, f)
+	r.Summary[f.Action]++
+}
+
+// eventRecord is the NDJSON representation of an event
+type eventRecord struct {
+	Type         string `json:"type"`
+	Kind         string `json:"kind"`
+	Path         string `json:"path,omitempty"`
+	Dir          string `json:"dir,omitempty"`
+	Backup       string `json:"backup,omitempty"`
+	From         string `json:"from,omitempty"`
+	Reason       string `json:"reason,omitempty"`
+	Message      string `json:"message,omitempty"`
+	Name         string `json:"name,omitempty"`
+	Variable     string `json:"variable,omitempty"`
+	Error        string `json:"error,omitempty"`
+	Hash         string `json:"hash,omitempty"`
+	PreviousHash string `json:"previous_hash,omitempty"`
+	Algorithm    string `json:"algorithm,omitempty"`
+	Repository   string `json:"repository,omitempty"`
+	Branch       string `json:"branch,omitempty"`
+	Revision     string `json:"revision,omitempty"`
+	Signer       string `json:"signer,omitempty"`
+	Key          string `json:"key,omitempty"`
+}
+
+// jsonRenderer feeds events into the command report and, when streaming,
+// writes each event as one JSON line
+type jsonRenderer struct {
+	enc    *json.Encoder
+	stream bool
+	// backups remembers backups until the matching install event arrives
+	backups map[string]string
+}
+
+func newJSONRenderer(w io.Writer, stream bool) *jsonRenderer {
+	return &jsonRenderer{enc: json.NewEncoder(w), stream: stream, backups: map[string]string{}}
+}
+
+// Notify records and optionally streams a single event
+func (r *jsonRenderer) Notify(e event.Event) {
+	if r.stream {
+		record := eventRecord{
+			Type:         "event",
+			Kind:         string(e.Kind),
+			Path:         e.Path,
+			Dir:          e.Dir,
+			Backup:       e.Backup,
+			From:         e.From,
+			Reason:       e.Reason,
+			Message:      e.Message,
+			Name:         e.Name,
+			Variable:     e.Variable,
+			Hash:         e.Hash,
+			PreviousHash: e.PreviousHash,
+			Algorithm:    e.Algorithm,
+			Repository:   e.Repository,
+			Branch:       e.Branch,
+			Revision:     e.Revision,
+			Signer:       e.Signer,
+			Key:          e.Key,
+		}
+		if e.Err != nil {
+			record.Error = e.Err.Error()
+		}
+		r.enc.Encode(record)
+	}
+
+	switch e.Kind {
+	case event.ConfigLoaded, event.ConfigCreated:
+		report.ConfigFile = e.Path
+	case event.CloneFinished:
+		report.Repository = &repositoryReport{URL: e.Repository, Branch: e.Branch, Revision: e.Revision}
+	case event.SignatureVerified:
+		if report.Repository != nil {
+			report.Repository.Signer, report.Repository.SignerKey = e.Signer, e.Key
+		}
+	case event.FileInstalled:
+		report.addFile(fileReport{Path: e.Path, Action: "installed", Hash: e.Hash, PreviousHash: e.PreviousHash, Algorithm: e.Algorithm, Backup: r.backups[e.Path]})
+		delete(r.backups, e.Path)
+	case event.FileBackedUp:
+		r.backups[e.Path] = e.Backup
+	case event.FileSkipped:
+		action := "skipped"
+		if e.Reason == event.ReasonModified {
+			action = "kept"
+		}
+		report.addFile(fileReport{Path: e.Path, Action: action, Reason: e.Reason, Hash: e.Hash, Algorithm: e.Algorithm})
+	case event.FileDetached:
+		report.addFile(fileReport{Path: e.Path, Action: "detached", Hash: e.Hash, PreviousHash: e.PreviousHash, Algorithm: e.Algorithm})
+	case event.FileMoved:
+		report.addFile(fileReport{Path: e.Path, Action: "moved", From: e.From, Hash: e.Hash, Algorithm: e.Algorithm})
+	case event.FileRemoved:
+		report.addFile(fileReport{Path: e.Path, Action: "removed", Hash: e.Hash, Algorithm: e.Algorithm})
+	case event.Warning:
+		if e.Reason == event.ReasonRemoveFailed {
+			report.addFile(fileReport{Path: e.Path, Action: "failed", Reason: errString(e.Err)})
+			return
+		}
+		report.Warnings = append(report.Warnings, warningText(e))
+	}
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// warningText builds a stable, non-localized description of a warning
+func warningText(e event.Event) string {
+	text := e.Reason
+	for _, part := range []string{e.Dir, e.From, e.Path, e.Name, e.Variable, e.Message, errString(e.Err)} {
+		if part != "" {
+			text += ": " + part
+		}
+	}
+	return text
+}
+
+// setupOutput selects the renderer for the requested output format
+func setupOutput(command string) error {
+	report = newCommandReport(command)
+
+	switch outputFormat {
+	case "", outputText:
+		out = os.Stdout
+		if quietFlag {
+			out = io.Discard
+		}
+		observer = newTextRenderer(out)
+	case outputJSON:
+		out = io.Discard
+		observer = newJSONRenderer(os.Stdout, false)
+	case outputNDJSON:
+		out = io.Discard
+		observer = newJSONRenderer(os.Stdout, true)
+	default:
+		return apperr.New(apperr.Usage, "unsupported output format %q (use text, json or ndjson)", outputFormat)
+	}
+	return nil
+}
+
+// promptOut returns where interactive prompts go; unlike out it is kept
+// with --quiet so a confirmation is never asked blind
+func promptOut() io.Writer {
+	if machineOutput() {
+		return io.Discard
+	}
+	return os.Stdout
+}
+
+// machineOutput reports whether a JSON format was requested
+func machineOutput() bool {
+	return outputFormat == outputJSON || outputFormat == outputNDJSON
+}
+
+// flushReport prints the final report in JSON modes
+func flushReport(err error) {
+	if !machineOutput() || report == nil {
+		return
+	}
+
+	report.OK = err == nil
+	report.ExitCode = apperr.ExitCode(err)
+	if err != nil {
+		report.Errors = append(report.Errors, err.Error())
+		report.ErrorKind = apperr.KindOf(err).String()
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	if outputFormat == outputJSON {
+		enc.SetIndent("", "  ")
+	}
+	enc.Encode(report)
+}
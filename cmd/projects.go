@@ -0,0 +1,360 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/doodleEsc/ctx-tool/internal/apperr"
+	"github.com/doodleEsc/ctx-tool/internal/checksum"
+	"github.com/doodleEsc/ctx-tool/internal/config"
+	"github.com/doodleEsc/ctx-tool/internal/event"
+	"github.com/doodleEsc/ctx-tool/internal/i18n"
+	"github.com/doodleEsc/ctx-tool/internal/logging"
+	"github.com/doodleEsc/ctx-tool/internal/registry"
+	"github.com/doodleEsc/ctx-tool/internal/store"
+	"github.com/doodleEsc/ctx-tool/internal/tracker"
+	"github.com/spf13/cobra"
+)
+
+// Project states reported by projects list and projects update
+const (
+	stateCurrent  = "current"
+	stateOutdated = "outdated"
+	stateMissing  = "missing"
+	stateUnknown  = "unknown"
+	stateUpdated  = "updated"
+	stateFailed   = "failed"
+)
+
+var (
+	projectsOfflineFlag   bool
+	projectsUpdateAllFlag bool
+)
+
+var projectsCmd = &cobra.Command{
+	Use:     "projects",
+	Short:   "Manage the registry of projects with installed configurations",
+	Long:    "Every installation made by add is recorded in a machine-wide registry. These commands list, update and clean up the registered projects.",
+	Example: "  ctx-tool projects list\n  ctx-tool projects update --all\n  ctx-tool projects prune",
+}
+
+var projectsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List registered projects and whether they are up to date",
+	Args:  cobra.NoArgs,
+	RunE:  runProjectsList,
+}
+
+var projectsUpdateCmd = &cobra.Command{
+	Use:     "update [paths...]",
+	Short:   "Update registered projects from their upstream repository",
+	Example: "  ctx-tool projects update --all\n  ctx-tool projects update ~/src/api ~/src/web",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 && !projectsUpdateAllFlag {
+			return apperr.New(apperr.Usage, "requires at least one project path or --all flag")
+		}
+		return nil
+	},
+	RunE: runProjectsUpdate,
+}
+
+var projectsPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Forget projects whose tracking file no longer exists and clean up the store",
+	Args:  cobra.NoArgs,
+	RunE:  runProjectsPrune,
+}
+
+func init() {
+	rootCmd.AddCommand(projectsCmd)
+	projectsCmd.AddCommand(projectsListCmd, projectsUpdateCmd, projectsPruneCmd)
+
+	projectsListCmd.Flags().BoolVar(&projectsOfflineFlag, "offline", false, "Don't contact upstream repositories to check for updates")
+	projectsUpdateCmd.Flags().BoolVar(&projectsUpdateAllFlag, "all", false, "Update every registered project")
+	projectsUpdateCmd.Flags().BoolVar(&trustFlag, "trust", false, "Trust the repositories without asking if they aren't trusted sources yet")
+	projectsUpdateCmd.Flags().BoolVar(&approveFlag, "approve", false, "Install without asking when the review finds new hooks, scripts or other risky content")
+}
+
+// projectReport is the machine-readable view of a registered project
+type projectReport struct {
+	Path         string `json:"path"`
+	Scope        string `json:"scope"`
+	TrackingFile string `json:"tracking_file"`
+	Repository   string `json:"repository,omitempty"`
+	Branch       string `json:"branch,omitempty"`
+	Revision     string `json:"revision,omitempty"`
+	Latest       string `json:"latest_revision,omitempty"`
+	State        string `json:"state,omitempty"`
+	Error        string `json:"error,omitempty"`
+	UpdatedAt    string `json:"updated_at,omitempty"`
+}
+
+func newProjectReport(p registry.Project) projectReport {
+	return projectReport{
+		Path:         p.Path,
+		Scope:        p.Scope,
+		TrackingFile: p.TrackingFile,
+		Repository:   p.Repository,
+		Branch:       p.Branch,
+		Revision:     p.Revision,
+		UpdatedAt:    p.UpdatedAt,
+	}
+}
+
+// projectUpstream returns the repository and branch a project installs from,
+// falling back to the configured ones
+func projectUpstream(p registry.Project) (string, string) {
+	url, branch := p.Repository, p.Branch
+	if url == "" {
+		url = cfg.Repository.URL
+	}
+	if branch == "" {
+		branch = cfg.Repository.Branch
+	}
+	return url, branch
+}
+
+// projectStateKeys maps project states to their localized label
+var projectStateKeys = map[string]string{
+	stateCurrent:  i18n.MsgProjectStateCurrent,
+	stateOutdated: i18n.MsgProjectStateOutdated,
+	stateMissing:  i18n.MsgProjectStateMissing,
+	stateUnknown:  i18n.MsgProjectStateUnknown,
+}
+
+func runProjectsList(cmd *cobra.Command, args []string) error {
+	reg, err := registry.Load(config.GetRegistryPath())
+	if err != nil {
+		return err
+	}
+	if len(reg.Projects) == 0 {
+		fmt.Fprintln(out, i18n.T(i18n.MsgNoProjects))
+		return nil
+	}
+
+	// Ask each upstream only once for its latest revision
+	latest := make(map[string]string)
+	latestErr := make(map[string]error)
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	for _, p := range reg.Projects {
+		pr := newProjectReport(p)
+
+		switch {
+		case !config.FileExists(p.TrackingFile):
+			pr.State = stateMissing
+		case projectsOfflineFlag:
+		default:
+			url, branch := projectUpstream(p)
+			key := url + "#" + branch
+			if _, ok := latest[key]; !ok {
+				latest[key], latestErr[key] = newGitClient(url, branch).RemoteRevision()
+			}
+
+			pr.Latest = latest[key]
+			pr.Error = errString(latestErr[key])
+			switch {
+			case pr.Latest == "":
+				pr.State = stateUnknown
+			case pr.Latest == p.Revision:
+				pr.State = stateCurrent
+			default:
+				pr.State = stateOutdated
+			}
+		}
+
+		report.Projects = append(report.Projects, pr)
+		if pr.State != "" {
+			report.Summary[pr.State]++
+		}
+
+		state := ""
+		if key, ok := projectStateKeys[pr.State]; ok {
+			state = i18n.T(key)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", p.Path, p.Scope, shortRevision(p.Revision), state)
+	}
+	return tw.Flush()
+}
+
+func runProjectsUpdate(cmd *cobra.Command, args []string) error {
+	reg, err := registry.Load(config.GetRegistryPath())
+	if err != nil {
+		return err
+	}
+
+	projects := reg.Projects
+	if !projectsUpdateAllFlag {
+		projects = nil
+		for _, arg := range args {
+			path, err := filepath.Abs(arg)
+			if err != nil {
+				return err
+			}
+			p, ok := reg.Find(path)
+			if !ok {
+				return apperr.New(apperr.NotFound, "%s", i18n.Tf(i18n.MsgProjectNotRegistered, map[string]interface{}{"Path": path}))
+			}
+			projects = append(projects, p)
+		}
+	}
+	if len(projects) == 0 {
+		fmt.Fprintln(out, i18n.T(i18n.MsgNoProjects))
+		return nil
+	}
+
+	// Clone each upstream once and install it into all of its projects
+	var order []string
+	groups := make(map[string][]registry.Project)
+	for _, p := range projects {
+		url, branch := projectUpstream(p)
+		key := url + "#" + branch
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], p)
+	}
+
+	failed := 0
+	for _, key := range order {
+		group := groups[key]
+		url, branch := projectUpstream(group[0])
+
+		src, cleanup, err := cloneUpstream(url, branch)
+		if err != nil {
+			for _, p := range group {
+				failed++
+				reportProjectFailure(p, err)
+			}
+			continue
+		}
+
+		for _, p := range group {
+			fmt.Fprintf(out, "\n%s\n", i18n.Tf(i18n.MsgUpdatingProject, map[string]interface{}{"Path": p.Path}))
+			if !config.FileExists(p.TrackingFile) {
+				failed++
+				reportProjectFailure(p, apperr.New(apperr.NotFound, "%s", i18n.Tf(i18n.MsgNoTrackedFiles, map[string]interface{}{"Path": p.TrackingFile})))
+				continue
+			}
+			if err := installInto(registeredScope(p), src, nil, false); err != nil {
+				failed++
+				reportProjectFailure(p, err)
+				continue
+			}
+
+			pr := newProjectReport(p)
+			pr.State = stateUpdated
+			pr.Revision = src.Revision
+			report.Projects = append(report.Projects, pr)
+			report.Summary[stateUpdated]++
+		}
+		cleanup()
+	}
+
+	updated := len(projects) - failed
+	fmt.Fprintf(out, "\n%s\n", i18n.Tn(i18n.MsgProjectsUpdated, updated, map[string]interface{}{"Count": updated}))
+	if failed > 0 {
+		return apperr.New(apperr.Partial, "%d of %d projects could not be updated", failed, len(projects))
+	}
+	return nil
+}
+
+// reportProjectFailure prints and records a project that couldn't be updated
+func reportProjectFailure(p registry.Project, err error) {
+	fmt.Fprintf(out, "%s\n", i18n.Tf(i18n.MsgProjectUpdateFailed, map[string]interface{}{"Path": p.Path, "Error": err}))
+
+	pr := newProjectReport(p)
+	pr.State = stateFailed
+	pr.Error = err.Error()
+	report.Projects = append(report.Projects, pr)
+	report.Summary[stateFailed]++
+}
+
+func runProjectsPrune(cmd *cobra.Command, args []string) error {
+	// Installs linking into the store hold the lock until their project
+	// is registered, so every object in use is known
+	unlock, err := store.Lock(config.GetStorePath(), storeLockTimeout)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	var pruned, kept []registry.Project
+	err = registry.Update(config.GetRegistryPath(), func(r *registry.Registry) error {
+		pruned = r.Prune()
+		kept = r.Projects
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, p := range pruned {
+		fmt.Fprintf(out, "  %s\n", i18n.Tf(i18n.MsgProjectPruned, map[string]interface{}{"Path": p.Path}))
+		pr := newProjectReport(p)
+		pr.State = stateMissing
+		report.Projects = append(report.Projects, pr)
+	}
+	report.Summary["pruned"] = len(pruned)
+	fmt.Fprintf(out, "%s\n", i18n.Tn(i18n.MsgProjectsPruned, len(pruned), map[string]interface{}{"Count": len(pruned)}))
+
+	// A forgotten project may only be unreachable, e.g. on an unmounted
+	// drive; its symlinks would dangle if it came back, so its objects are
+	// only removed by the next prune
+	deferred := false
+	for _, p := range pruned {
+		if p.InstallMode == "" || p.InstallMode == store.Symlink {
+			observer.Notify(event.Event{Kind: event.Warning, Reason: event.ReasonForgottenSymlinks, Path: p.Path})
+			deferred = true
+		}
+	}
+	if deferred {
+		report.Summary["objects_removed"] = 0
+		return nil
+	}
+
+	removed, err := pruneStore(kept)
+	if err != nil {
+		return fmt.Errorf("prune store: %w", err)
+	}
+	report.Summary["objects_removed"] = removed
+	fmt.Fprintf(out, "%s\n", i18n.Tn(i18n.MsgStorePruned, removed, map[string]interface{}{"Count": removed}))
+	return nil
+}
+
+// pruneStore removes the store objects none of projects links to and
+// returns how many were removed. Nothing is removed unless every project's
+// tracking file can be read.
+func pruneStore(projects []registry.Project) (int, error) {
+	keep := make(map[checksum.Algorithm]map[string]bool)
+	for _, p := range projects {
+		t := tracker.NewTracker(p.TrackingFile, p.Scope, p.Path)
+		if err := t.Load(); err != nil {
+			return 0, fmt.Errorf("load %s: %w", p.TrackingFile, err)
+		}
+		for _, entry := range t.Installation.Files {
+			if entry.Link == "" {
+				continue
+			}
+			if keep[entry.Algorithm] == nil {
+				keep[entry.Algorithm] = make(map[string]bool)
+			}
+			keep[entry.Algorithm][entry.Hash] = true
+		}
+	}
+
+	algos, err := store.Algorithms(config.GetStorePath())
+	if err != nil {
+		return 0, err
+	}
+	removed := 0
+	for _, algo := range algos {
+		sums, err := store.New(config.GetStorePath(), algo).Prune(keep[algo], store.PruneGrace)
+		removed += len(sums)
+		if err != nil {
+			return removed, err
+		}
+	}
+	logging.Component("store").Info("pruned store", "removed", removed)
+	return removed, nil
+}
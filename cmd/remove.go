@@ -4,24 +4,29 @@ import (
 	"bufio"
 	"fmt"
 	"os"
-	"path/filepath"
 	"strings"
 
+	"github.com/doodleEsc/ctx-tool/internal/apperr"
+	"github.com/doodleEsc/ctx-tool/internal/checksum"
+	"github.com/doodleEsc/ctx-tool/internal/config"
 	"github.com/doodleEsc/ctx-tool/internal/i18n"
+	"github.com/doodleEsc/ctx-tool/internal/logging"
+	"github.com/doodleEsc/ctx-tool/internal/sync"
 	"github.com/doodleEsc/ctx-tool/internal/tracker"
 	"github.com/spf13/cobra"
 )
 
 var (
-	forceFlag        bool
-	removeGlobalFlag bool
+	forceFlag         bool
+	forceModifiedFlag bool
+	removeGlobalFlag  bool
 )
 
 var removeCmd = &cobra.Command{
-	Use:     "remove",
+	Use:     "remove [paths...]",
 	Short:   "Remove tracked configurations",
 	Long:    "Remove previously installed configurations based on the tracking file.",
-	Example: "  ctx-tool remove\n  ctx-tool remove --global",
+	Example: "  ctx-tool remove\n  ctx-tool remove .claude/commands/typescript\n  ctx-tool remove '**/experimental/**' --global",
 	RunE:    runRemove,
 }
 
@@ -30,52 +35,116 @@ func init() {
 
 	// Local flags for remove command
 	removeCmd.Flags().BoolVar(&forceFlag, "force", false, "Skip confirmation prompt")
+	removeCmd.Flags().BoolVar(&forceModifiedFlag, "force-modified", false, "Also delete files modified since installation")
 	removeCmd.Flags().BoolVar(&removeGlobalFlag, "global", false, "Remove from global location")
 }
 
 func runRemove(cmd *cobra.Command, args []string) error {
 	// Determine scope
-	scope := "project"
-	trackingFile := cfg.Tracking.File
-
-	if removeGlobalFlag {
-		scope = "global"
-		homeDir, err := os.UserHomeDir()
-		if err != nil {
-			return fmt.Errorf("get home directory: %w", err)
-		}
-		trackingFile = filepath.Join(homeDir, ".ctx-tool-tracking.json")
+	scope, err := resolveScope(removeGlobalFlag)
+	if err != nil {
+		return err
 	}
+	trackingFile := scope.TrackingFile
+
+	report.Scope = scope.Name
+	report.TrackingFile = trackingFile
 
-	fmt.Printf("%s\n", i18n.Tf(i18n.MsgRemovalScope, map[string]interface{}{"Scope": scope}))
-	fmt.Printf("Tracking file: %s\n", trackingFile)
+	fmt.Fprintf(out, "%s\n", i18n.Tf(i18n.MsgRemovalScope, map[string]interface{}{"Scope": scope.Name}))
+	fmt.Fprintf(out, "Tracking file: %s\n", trackingFile)
 
 	// Check if tracking file exists
 	if _, err := os.Stat(trackingFile); os.IsNotExist(err) {
-		return fmt.Errorf("%s", i18n.Tf(i18n.MsgNoTrackedFiles, map[string]interface{}{"Path": trackingFile}))
+		return apperr.New(apperr.NotFound, "%s", i18n.Tf(i18n.MsgNoTrackedFiles, map[string]interface{}{"Path": trackingFile}))
+	}
+
+	algo, err := checksum.ParseAlgorithm(cfg.Behavior.HashAlgorithm)
+	if err != nil {
+		return apperr.Wrap(apperr.Usage, fmt.Errorf("invalid behavior.hash_algorithm: %w", err))
 	}
 
 	// Load tracker
-	trackerInstance := tracker.NewTracker(trackingFile, scope, "")
+	trackerInstance := tracker.NewTracker(trackingFile, scope.Name, scope.BasePath)
+	unlock, err := trackerInstance.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	trackerInstance.Algorithm = algo
+	trackerInstance.Cache = checksum.LoadCache(config.GetHashCachePath())
+	defer func() {
+		if err := trackerInstance.Cache.Save(); err != nil {
+			fmt.Fprintf(out, "Warning: Failed to save hash cache: %v\n", err)
+		}
+	}()
+	done := logging.Phase("load tracking data")
 	if err := trackerInstance.Load(); err != nil {
 		return fmt.Errorf("load tracking data: %w", err)
 	}
+	done()
+	if err := migrateLayout(scope, trackerInstance); err != nil {
+		return err
+	}
+	if err := detachEditedLinks(trackerInstance); err != nil {
+		return err
+	}
 
 	// Get list of tracked files
-	trackedFiles := trackerInstance.GetTrackedFiles()
-	if len(trackedFiles) == 0 {
-		fmt.Println("No tracked files found - nothing to remove")
+	if len(trackerInstance.GetTrackedFiles()) == 0 {
+		fmt.Fprintln(out, "No tracked files found - nothing to remove")
 		return nil
 	}
 
-	fmt.Printf("\n%s\n", i18n.Tn(i18n.MsgFoundTrackedFiles, len(trackedFiles), map[string]interface{}{"Count": len(trackedFiles)}))
-	for _, file := range trackedFiles {
-		fmt.Printf("  - %s\n", file)
+	remover := sync.NewRemover(trackerInstance, sync.RemoveOptions{
+		ForceModified:  forceModifiedFlag,
+		CleanEmptyDirs: cfg.Behavior.CleanEmptyDirs,
+	})
+	remover.SetObserver(observer)
+
+	// Narrow down to the requested paths, directories or globs
+	paths, err := scopePaths(scope, args)
+	if err != nil {
+		return err
+	}
+	plan := remover.Plan(paths)
+	if len(plan) == 0 {
+		return apperr.New(apperr.NotFound, "%s", i18n.Tf(i18n.MsgNoMatchingFiles, map[string]interface{}{"Patterns": strings.Join(args, ", ")}))
+	}
+
+	// The plan stays visible with --quiet when it has to be confirmed
+	listOut := out
+	if !forceFlag {
+		listOut = promptOut()
+	}
+
+	modifiedCount := 0
+	fmt.Fprintf(listOut, "\n%s\n", i18n.Tn(i18n.MsgFoundTrackedFiles, len(plan), map[string]interface{}{"Count": len(plan)}))
+	for _, item := range plan {
+		switch {
+		case item.Modified && forceModifiedFlag:
+			fmt.Fprintf(listOut, "  - %s %s\n", item.Path, i18n.T(i18n.MsgModifiedWillDelete))
+		case item.Modified:
+			fmt.Fprintf(listOut, "  - %s %s\n", item.Path, i18n.T(i18n.MsgModifiedWillKeep))
+		default:
+			fmt.Fprintf(listOut, "  - %s\n", item.Path)
+		}
+		if item.Modified {
+			modifiedCount++
+		}
+	}
+
+	if modifiedCount > 0 && !forceModifiedFlag {
+		fmt.Fprintf(listOut, "\n%s\n", i18n.Tn(i18n.MsgModifiedFilesKept, modifiedCount, map[string]interface{}{"Count": modifiedCount}))
+	}
+
+	// Machine-readable runs can't answer the prompt
+	if !forceFlag && machineOutput() {
+		return apperr.New(apperr.Usage, "confirmation required: pass --force together with --output %s", outputFormat)
 	}
 
 	// Ask for confirmation unless --force is used
 	if !forceFlag {
-		fmt.Printf("\n%s", i18n.T(i18n.MsgConfirmRemoval))
+		fmt.Fprintf(promptOut(), "\n%s", i18n.T(i18n.MsgConfirmRemoval))
 		reader := bufio.NewReader(os.Stdin)
 		response, err := reader.ReadString('\n')
 		if err != nil {
@@ -84,88 +153,47 @@ func runRemove(cmd *cobra.Command, args []string) error {
 
 		response = strings.TrimSpace(strings.ToLower(response))
 		if response != "yes" && response != "y" {
-			fmt.Println(i18n.T(i18n.MsgRemovalCancelled))
-			return nil
+			return apperr.New(apperr.Cancelled, "%s", i18n.T(i18n.MsgRemovalCancelled))
 		}
 	}
 
 	// Remove files
-	basePath := trackerInstance.Installation.BasePath
-	removedCount := 0
-	failedCount := 0
-	directories := make(map[string]bool)
-
-	for _, relPath := range trackedFiles {
-		fullPath := filepath.Join(basePath, relPath)
-
-		// Track parent directories for cleanup
-		dir := filepath.Dir(fullPath)
-		directories[dir] = true
-
-		// Check if file exists
-		if _, err := os.Stat(fullPath); os.IsNotExist(err) {
-			fmt.Printf("  Skip %s (already removed)\n", relPath)
-			continue
-		}
-
-		// Remove the file
-		if err := os.Remove(fullPath); err != nil {
-			fmt.Printf("  ❌ Failed to remove %s: %v\n", relPath, err)
-			failedCount++
-			continue
-		}
-
-		fmt.Printf("  Removed %s\n", relPath)
-		removedCount++
-
-		// Update tracker
-		trackerInstance.RemoveFile(relPath)
-	}
-
-	// Clean up empty directories if configured
-	if cfg.Behavior.CleanEmptyDirs {
-		fmt.Println("\nCleaning up empty directories...")
-		for dir := range directories {
-			// Don't remove base directories like $HOME/.claude
-			if dir == basePath {
-				continue
-			}
-
-			// Check if directory is empty
-			entries, err := os.ReadDir(dir)
-			if err != nil {
-				continue
-			}
-
-			if len(entries) == 0 {
-				if err := os.Remove(dir); err == nil {
-					fmt.Printf("  Removed empty directory: %s\n", dir)
-				}
-			}
-		}
-	}
+	report.Target = trackerInstance.Installation.BasePath
+	done = logging.Phase("remove")
+	result := remover.Remove(plan)
+	done()
 
 	// Save updated tracking file or remove it if empty
 	if len(trackerInstance.GetTrackedFiles()) == 0 {
 		// No more tracked files, remove the tracking file
 		if err := os.Remove(trackingFile); err != nil {
-			fmt.Printf("Warning: Failed to remove tracking file: %v\n", err)
+			fmt.Fprintf(out, "Warning: Failed to remove tracking file: %v\n", err)
 		} else {
-			fmt.Printf("\nRemoved tracking file (no files left to track)\n")
+			fmt.Fprintf(out, "\nRemoved tracking file (no files left to track)\n")
+			unregisterProject(scope)
 		}
 	} else {
 		// Save updated tracking data
 		if err := trackerInstance.Save(); err != nil {
 			return fmt.Errorf("save tracking data: %w", err)
 		}
+		registerProject(scope, trackerInstance)
 	}
 
 	// Summary
-	fmt.Printf("\n%s\n", i18n.T(i18n.MsgRemovalComplete))
-	fmt.Printf("%s\n", i18n.Tn(i18n.MsgFilesRemoved, removedCount, map[string]interface{}{"Count": removedCount}))
-	if failedCount > 0 {
-		fmt.Printf("Files failed: %d\n", failedCount)
+	fmt.Fprintf(out, "\n%s\n", i18n.T(i18n.MsgRemovalComplete))
+	fmt.Fprintf(out, "%s\n", i18n.Tn(i18n.MsgFilesRemoved, result.Removed, map[string]interface{}{"Count": result.Removed}))
+	if result.Kept > 0 {
+		fmt.Fprintf(out, "%s\n", i18n.Tn(i18n.MsgFilesKeptModified, result.Kept, map[string]interface{}{"Count": result.Kept}))
+	}
+	if result.Failed > 0 {
+		fmt.Fprintf(out, "Files failed: %d\n", result.Failed)
 	}
 
+	// Keeping edits is the default, not a failure
+	report.Summary["kept"] = result.Kept
+	if result.Failed > 0 {
+		return apperr.New(apperr.Partial, "%d of %d files could not be removed", result.Failed, len(plan))
+	}
 	return nil
 }
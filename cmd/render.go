@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/doodleEsc/ctx-tool/internal/config"
+	"github.com/doodleEsc/ctx-tool/internal/event"
+	"github.com/doodleEsc/ctx-tool/internal/i18n"
+)
+
+// textRenderer prints events as localized, human readable lines
+type textRenderer struct {
+	w io.Writer
+	// progress is set while a clone progress line is being redrawn
+	progress bool
+}
+
+func newTextRenderer(w io.Writer) *textRenderer {
+	return &textRenderer{w: w}
+}
+
+// Notify renders a single event
+func (r *textRenderer) Notify(e event.Event) {
+	if e.Kind == event.CloneProgress {
+		// Redraw the progress line in place like git does
+		fmt.Fprintf(r.w, "\r%s", e.Message)
+		r.progress = true
+		return
+	}
+	if r.progress {
+		fmt.Fprintln(r.w)
+		r.progress = false
+	}
+
+	switch e.Kind {
+	case event.ConfigLoaded:
+		fmt.Fprintf(r.w, "%s\n", i18n.Tf(i18n.MsgUsingConfigFile, map[string]interface{}{"Path": e.Path}))
+	case event.ConfigCreated:
+		fmt.Fprintf(r.w, "%s\n", i18n.Tf(i18n.MsgCreatedDefaultConfig, map[string]interface{}{"Path": e.Path}))
+	case event.ConfigDefault:
+		fmt.Fprintf(r.w, "%s\n", i18n.T(i18n.MsgNoConfigFile))
+	case event.CloneStarted:
+		fmt.Fprintf(r.w, "%s\n", i18n.Tf(i18n.MsgCloningRepository, map[string]interface{}{"Repo": e.Repository, "Branch": e.Branch}))
+	case event.CloneFinished:
+		fmt.Fprintf(r.w, "%s\n", i18n.Tf(i18n.MsgRepositoryCloned, map[string]interface{}{"Path": e.Dir}))
+	case event.SignatureVerified:
+		fmt.Fprintf(r.w, "%s\n", i18n.Tf(i18n.MsgSignatureVerified, map[string]interface{}{"Signer": e.Signer, "Key": e.Key}))
+	case event.DirectoryStarted:
+		fmt.Fprintf(r.w, "%s\n", i18n.Tf(i18n.MsgSyncingDir, map[string]interface{}{"Dir": e.Dir}))
+	case event.FileSkipped:
+		r.renderSkipped(e)
+	case event.FileBackedUp:
+		fmt.Fprintf(r.w, "  %s\n", i18n.Tf(i18n.MsgBackedUp, map[string]interface{}{"Original": e.Path, "Backup": filepath.Base(e.Backup)}))
+	case event.FileInstalled:
+		fmt.Fprintf(r.w, "  %s\n", i18n.Tf(i18n.MsgInstalled, map[string]interface{}{"File": e.Path}))
+	case event.FileRemoved:
+		fmt.Fprintf(r.w, "  %s\n", i18n.Tf(i18n.MsgRemoved, map[string]interface{}{"File": e.Path}))
+	case event.FileDetached:
+		fmt.Fprintf(r.w, "  %s\n", i18n.Tf(i18n.MsgDetachedFile, map[string]interface{}{"File": e.Path}))
+	case event.FileMoved:
+		fmt.Fprintf(r.w, "  %s\n", i18n.Tf(i18n.MsgMovedFile, map[string]interface{}{"From": e.From, "File": e.Path}))
+	case event.DirectoryRemoved:
+		fmt.Fprintf(r.w, "  %s\n", i18n.Tf(i18n.MsgRemovedEmptyDir, map[string]interface{}{"Dir": e.Dir}))
+	case event.Warning:
+		r.renderWarning(e)
+	}
+}
+
+func (r *textRenderer) renderSkipped(e event.Event) {
+	switch e.Reason {
+	case event.ReasonIdentical:
+		fmt.Fprintf(r.w, "  %s\n", i18n.Tf(i18n.MsgSkipIdentical, map[string]interface{}{"File": e.Path}))
+	case event.ReasonModified:
+		fmt.Fprintf(r.w, "  %s\n", i18n.Tf(i18n.MsgSkipModified, map[string]interface{}{"File": e.Path}))
+	case event.ReasonMissing:
+		fmt.Fprintf(r.w, "  %s\n", i18n.Tf(i18n.MsgSkipMissing, map[string]interface{}{"File": e.Path}))
+	}
+}
+
+func (r *textRenderer) renderWarning(e event.Event) {
+	switch e.Reason {
+	case event.ReasonDirNotFound:
+		fmt.Fprintf(r.w, "%s\n", i18n.Tf(i18n.MsgWarningDirNotFound, map[string]interface{}{"Dir": e.Dir}))
+	case event.ReasonRemoveFailed:
+		fmt.Fprintf(r.w, "  %s\n", i18n.Tf(i18n.MsgRemoveFailed, map[string]interface{}{"File": e.Path, "Error": e.Err}))
+	case event.ReasonMoveConflict:
+		fmt.Fprintf(r.w, "  %s\n", i18n.Tf(i18n.MsgMoveConflict, map[string]interface{}{"From": e.From, "File": e.Path}))
+	case event.ReasonServerExists:
+		fmt.Fprintf(r.w, "  %s\n", i18n.Tf(i18n.MsgServerExists, map[string]interface{}{"Name": e.Name, "File": e.Path}))
+	case event.ReasonUnsetVar:
+		fmt.Fprintf(r.w, "  %s\n", i18n.Tf(i18n.MsgUnsetVariable, map[string]interface{}{"Name": e.Name, "Variable": e.Variable, "File": e.Path}))
+	case event.ReasonWholeFileEdited:
+		fmt.Fprintf(r.w, "  %s\n", i18n.Tf(i18n.MsgWholeFileEdited, map[string]interface{}{"File": e.Path, "Backup": e.Backup}))
+	case event.ReasonRegisterFailed:
+		fmt.Fprintf(r.w, "%s\n", i18n.Tf(i18n.MsgRegisterFailed, map[string]interface{}{"Path": e.Path, "Error": e.Err}))
+	case event.ReasonForgottenSymlinks:
+		fmt.Fprintf(r.w, "%s\n", i18n.Tf(i18n.MsgForgottenSymlinks, map[string]interface{}{"Path": e.Path}))
+	case event.ReasonSharedLinkEdited:
+		fmt.Fprintf(r.w, "  %s\n", i18n.Tf(i18n.MsgSharedLinkEdited, map[string]interface{}{"File": e.Path}))
+	case event.ReasonConfigCreate:
+		fmt.Fprintf(r.w, "%s\n", i18n.Tf(i18n.MsgConfigCreateFailed, map[string]interface{}{"Error": e.Err}))
+	case event.ReasonLegacyConfig:
+		fmt.Fprintf(r.w, "%s\n", i18n.Tf(i18n.MsgLegacyConfigHint, map[string]interface{}{
+			"Path":    e.Path,
+			"XDGPath": config.GetXDGConfigPath(),
+			"XDGDir":  config.GetXDGConfigDir(),
+		}))
+	default:
+		if e.Err != nil {
+			fmt.Fprintf(r.w, "Warning: %v\n", e.Err)
+		} else if e.Message != "" {
+			fmt.Fprintf(r.w, "Warning: %s\n", e.Message)
+		}
+	}
+}
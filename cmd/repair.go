@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/doodleEsc/ctx-tool/internal/apperr"
+	"github.com/doodleEsc/ctx-tool/internal/checksum"
+	"github.com/doodleEsc/ctx-tool/internal/config"
+	"github.com/doodleEsc/ctx-tool/internal/i18n"
+	"github.com/doodleEsc/ctx-tool/internal/logging"
+	"github.com/doodleEsc/ctx-tool/internal/sync"
+	"github.com/doodleEsc/ctx-tool/internal/tracker"
+	"github.com/spf13/cobra"
+)
+
+var (
+	repairGlobalFlag        bool
+	repairForceModifiedFlag bool
+)
+
+var repairCmd = &cobra.Command{
+	Use:     "repair [paths...]",
+	Short:   "Restore missing or corrupted files from the installed revision",
+	Long:    "Restore tracked files that were deleted or corrupted from the upstream revision they were installed from. Files edited since installation are kept unless --force-modified is given.",
+	Example: "  ctx-tool repair\n  ctx-tool repair .claude/commands\n  ctx-tool repair --force-modified",
+	RunE:    runRepair,
+}
+
+func init() {
+	rootCmd.AddCommand(repairCmd)
+
+	repairCmd.Flags().BoolVar(&repairGlobalFlag, "global", false, "Repair the global installation")
+	repairCmd.Flags().BoolVar(&repairForceModifiedFlag, "force-modified", false, "Also restore files modified since installation")
+	repairCmd.Flags().BoolVar(&trustFlag, "trust", false, "Trust the recorded repository without asking if it isn't a trusted source yet")
+}
+
+func runRepair(cmd *cobra.Command, args []string) error {
+	scope, err := resolveScope(repairGlobalFlag)
+	if err != nil {
+		return err
+	}
+
+	report.Scope = scope.Name
+	report.Target = scope.BasePath
+	report.TrackingFile = scope.TrackingFile
+
+	if !config.FileExists(scope.TrackingFile) {
+		return apperr.New(apperr.NotFound, "%s", i18n.Tf(i18n.MsgNoTrackedFiles, map[string]interface{}{"Path": scope.TrackingFile}))
+	}
+
+	algo, err := checksum.ParseAlgorithm(cfg.Behavior.HashAlgorithm)
+	if err != nil {
+		return apperr.Wrap(apperr.Usage, fmt.Errorf("invalid behavior.hash_algorithm: %w", err))
+	}
+
+	trackerInstance := tracker.NewTracker(scope.TrackingFile, scope.Name, scope.BasePath)
+	unlock, err := trackerInstance.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	trackerInstance.Algorithm = algo
+	trackerInstance.Cache = checksum.LoadCache(config.GetHashCachePath())
+	defer func() {
+		if err := trackerInstance.Cache.Save(); err != nil {
+			fmt.Fprintf(out, "Warning: Failed to save hash cache: %v\n", err)
+		}
+	}()
+	done := logging.Phase("load tracking data")
+	if err := trackerInstance.Load(); err != nil {
+		return fmt.Errorf("load tracking data: %w", err)
+	}
+	done()
+	if err := detachEditedLinks(trackerInstance); err != nil {
+		return err
+	}
+
+	paths, err := scopePaths(scope, args)
+	if err != nil {
+		return err
+	}
+	states, err := trackerInstance.States(paths)
+	if err != nil {
+		return err
+	}
+	if len(states) == 0 && len(args) > 0 {
+		return apperr.New(apperr.NotFound, "%s", i18n.Tf(i18n.MsgNoMatchingFiles, map[string]interface{}{"Patterns": strings.Join(args, ", ")}))
+	}
+
+	// Edits are kept unless asked otherwise
+	plan := sync.PlanRepair(states, repairForceModifiedFlag)
+	for _, s := range plan.Kept {
+		fmt.Fprintf(out, "  %s\n", i18n.Tf(i18n.MsgSkipModified, map[string]interface{}{"File": s.Path}))
+		report.addFile(fileReport{Path: s.Path, Action: "skipped", Reason: tracker.StateModified})
+	}
+	restore, kept := plan.Restore, len(plan.Kept)
+	report.Summary["kept"] = kept
+
+	if len(restore) == 0 {
+		fmt.Fprintln(out, i18n.T(i18n.MsgRepairNothing))
+		return nil
+	}
+
+	installation := trackerInstance.Installation
+	if installation.Repository == "" || installation.Revision == "" {
+		return apperr.New(apperr.NotFound, "%s records no upstream revision; reinstall with 'ctx-tool add' instead", scope.TrackingFile)
+	}
+	fmt.Fprintf(out, "%s\n", i18n.Tn(i18n.MsgRepairRestoring, len(restore), map[string]interface{}{"Count": len(restore), "Revision": shortRevision(installation.Revision)}))
+
+	src, cleanup, err := cloneRevision(installation.Repository, installation.Branch, installation.Tag, installation.Revision)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	syncer, err := newSyncer(scope, src, trackerInstance)
+	if err != nil {
+		return err
+	}
+
+	failures := syncer.Repair(restore)
+	for _, f := range failures {
+		reportRepairFailure(f.Path, f.Err)
+	}
+	failed := len(failures)
+
+	if err := trackerInstance.Save(); err != nil {
+		return fmt.Errorf("save tracking data: %w", err)
+	}
+
+	repaired := len(restore) - failed
+	fmt.Fprintf(out, "\n%s\n", i18n.Tn(i18n.MsgRepairComplete, repaired, map[string]interface{}{"Count": repaired}))
+	if kept > 0 {
+		fmt.Fprintf(out, "%s\n", i18n.Tn(i18n.MsgRepairKeptModified, kept, map[string]interface{}{"Count": kept}))
+	}
+	if failed > 0 {
+		return apperr.New(apperr.Partial, "%d of %d files could not be repaired", failed, len(restore))
+	}
+	return nil
+}
+
+// reportRepairFailure prints and records a file that couldn't be restored
+func reportRepairFailure(path string, err error) {
+	fmt.Fprintf(out, "  %s\n", i18n.Tf(i18n.MsgRepairFailed, map[string]interface{}{"File": path, "Error": err}))
+	report.addFile(fileReport{Path: path, Action: "failed", Reason: err.Error()})
+}
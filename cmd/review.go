@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/doodleEsc/ctx-tool/internal/apperr"
+	"github.com/doodleEsc/ctx-tool/internal/i18n"
+	"github.com/doodleEsc/ctx-tool/internal/logging"
+	"github.com/doodleEsc/ctx-tool/internal/review"
+	"github.com/doodleEsc/ctx-tool/internal/sync"
+)
+
+var approveFlag bool
+
+// capabilityReport is a capability an install adds or removes
+type capabilityReport struct {
+	Path   string `json:"path"`
+	Change string `json:"change"`
+	Kind   string `json:"kind"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// reviewIncoming shows the hooks, permissions, MCP servers, scripts and
+// other risky content the planned files add to the installation below
+// base, and asks for approval before any of it is installed. Runs that
+// can't prompt need --approve.
+func reviewIncoming(base string, planned []sync.PlannedFile) error {
+	var changes []review.Change
+	added := 0
+	for _, p := range planned {
+		installed := review.File{}
+		target := filepath.Join(base, p.Path)
+		if info, err := os.Stat(target); err == nil && info.Mode().IsRegular() {
+			content, err := os.ReadFile(target)
+			if err != nil {
+				return fmt.Errorf("read %s: %w", p.Path, err)
+			}
+			installed = review.File{Content: content, Mode: info.Mode().Perm()}
+		}
+
+		change := review.Compare(p.Path, review.File{Content: p.Content, Mode: p.Mode}, installed)
+		if p.Merged {
+			// A merge keeps what's installed, so nothing is taken away
+			change.Removed = nil
+		}
+		if len(change.Added) == 0 && len(change.Removed) == 0 {
+			continue
+		}
+		changes = append(changes, change)
+		added += len(change.Added)
+
+		for _, f := range change.Added {
+			report.Capabilities = append(report.Capabilities, capabilityReport{Path: change.Path, Change: "added", Kind: f.Kind, Detail: f.Detail})
+		}
+		for _, f := range change.Removed {
+			report.Capabilities = append(report.Capabilities, capabilityReport{Path: change.Path, Change: "removed", Kind: f.Kind, Detail: f.Detail})
+		}
+	}
+	logging.Component("review").Info("reviewed incoming files", "files", len(planned), "changed", len(changes), "added", added, "approve_flag", approveFlag)
+	if len(changes) == 0 {
+		return nil
+	}
+
+	// The report stays visible with --quiet when it comes with a question
+	w := out
+	if added > 0 && !approveFlag {
+		w = promptOut()
+	}
+	fmt.Fprintf(w, "\n%s\n", i18n.T(i18n.MsgReviewHeader))
+	for _, change := range changes {
+		fmt.Fprintf(w, "  %s\n", change.Path)
+		for _, f := range change.Added {
+			fmt.Fprintf(w, "    + %s\n", findingText(f))
+		}
+		for _, f := range change.Removed {
+			fmt.Fprintf(w, "    - %s\n", findingText(f))
+		}
+	}
+	fmt.Fprintln(w)
+
+	// Dropping capabilities needs no approval
+	if added == 0 || approveFlag {
+		return nil
+	}
+
+	message := i18n.Tn(i18n.MsgReviewNeedsApproval, added, nil)
+	if machineOutput() || !stdinIsTerminal() {
+		return apperr.New(apperr.Untrusted, "%s", message)
+	}
+
+	fmt.Fprintf(w, "%s\n%s", message, i18n.T(i18n.MsgConfirmReview))
+	response, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("read user input: %w", err)
+	}
+	response = strings.TrimSpace(strings.ToLower(response))
+	if response != "yes" && response != "y" {
+		return apperr.New(apperr.Cancelled, "%s", i18n.T(i18n.MsgReviewCancelled))
+	}
+	return nil
+}
+
+// kindLabels maps capability kinds to their message keys
+var kindLabels = map[string]string{
+	review.KindHook:        i18n.MsgReviewKindHook,
+	review.KindPermission:  i18n.MsgReviewKindPermission,
+	review.KindMCPServer:   i18n.MsgReviewKindMCPServer,
+	review.KindToolGrant:   i18n.MsgReviewKindToolGrant,
+	review.KindScript:      i18n.MsgReviewKindScript,
+	review.KindPipeToShell: i18n.MsgReviewKindPipeToShell,
+	review.KindURL:         i18n.MsgReviewKindURL,
+}
+
+// findingText renders a finding as "label: detail"
+func findingText(f review.Finding) string {
+	label := f.Kind
+	if key, ok := kindLabels[f.Kind]; ok {
+		label = i18n.T(key)
+	}
+	if f.Detail == "" {
+		return label
+	}
+	return label + ": " + f.Detail
+}
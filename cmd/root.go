@@ -3,27 +3,61 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strings"
 
+	"github.com/doodleEsc/ctx-tool/internal/apperr"
 	"github.com/doodleEsc/ctx-tool/internal/config"
+	"github.com/doodleEsc/ctx-tool/internal/event"
 	"github.com/doodleEsc/ctx-tool/internal/i18n"
+	"github.com/doodleEsc/ctx-tool/internal/logging"
 	"github.com/spf13/cobra"
 )
 
 var (
 	cfgFile       string
 	lang          string
+	targetDir     string
 	configManager *config.Manager
 	cfg           *config.Config
+
+	// noDiscoverFlag keeps the project scope in the current directory
+	noDiscoverFlag bool
+
+	// Diagnostics flags
+	quietFlag   bool
+	verboseFlag bool
+	debugFlag   bool
+	logFile     string
+	closeLog    = func() error { return nil }
+
+	// observer renders progress events of the sync engine
+	observer event.Observer
+
+	// commandStarted is set once flags and arguments were accepted; errors
+	// before that point are usage errors
+	commandStarted bool
 )
 
 var rootCmd = &cobra.Command{
 	Use:   "ctx-tool",
 	Short: "Manage Claude Code configurations",
 	Long:  "ctx-tool is a CLI application for managing Claude Code configurations across projects.",
+	// Execute prints errors itself, together with a hint
+	SilenceErrors: true,
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// Usage is only useful for flag and argument errors
+		commandStarted = true
+		cmd.SilenceUsage = true
+
 		if err := initI18n(); err != nil {
 			return err
 		}
+		if err := setupLogging(); err != nil {
+			return err
+		}
+		if err := setupOutput(strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()+" ")); err != nil {
+			return err
+		}
 		if err := initConfig(); err != nil {
 			return err
 		}
@@ -33,10 +67,21 @@ var rootCmd = &cobra.Command{
 	},
 }
 
+// Execute runs the root command and exits with the code documented for the
+// kind of error that stopped it
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+	err := rootCmd.Execute()
+	if err != nil && !commandStarted {
+		err = apperr.Wrap(apperr.Usage, err)
+	}
+	flushReport(err)
+	closeLog()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		if hint := errorHint(err); hint != "" {
+			fmt.Fprintln(os.Stderr, hint)
+		}
+		os.Exit(apperr.ExitCode(err))
 	}
 }
 
@@ -50,15 +95,35 @@ func init() {
 	// Persistent flags available to all subcommands
 	rootCmd.PersistentFlags().StringVarP(&cfgFile, "config", "c", "", "config file (default is ~/.config/ctx-tool/config.yaml)")
 	rootCmd.PersistentFlags().StringVarP(&lang, "lang", "l", "", "language (en, zh-Hans)")
-	rootCmd.PersistentFlags().BoolP("verbose", "v", false, "verbose output")
+	rootCmd.PersistentFlags().StringVar(&targetDir, "target", "", "project directory to manage (default is the discovered project root)")
+	rootCmd.PersistentFlags().BoolVar(&noDiscoverFlag, "no-discover", false, "use the current directory instead of searching for the project root")
+	rootCmd.PersistentFlags().BoolVarP(&quietFlag, "quiet", "q", false, "only print errors")
+	rootCmd.PersistentFlags().BoolVarP(&verboseFlag, "verbose", "v", false, "verbose output")
+	rootCmd.PersistentFlags().BoolVar(&debugFlag, "debug", false, "print debug diagnostics")
+	rootCmd.PersistentFlags().StringVar(&logFile, "log-file", "", "write debug diagnostics to a file")
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", outputText, "output format (text, json, ndjson)")
 }
 
 func initI18n() error {
 	return i18n.Init(lang)
 }
 
+// setupLogging installs the diagnostics logger selected by the flags
+func setupLogging() error {
+	closer, err := logging.Setup(logging.Options{
+		Level: logging.LevelFromFlags(quietFlag, verboseFlag, debugFlag),
+		File:  logFile,
+	})
+	if err != nil {
+		return err
+	}
+	closeLog = closer
+	return nil
+}
+
 func initConfig() error {
 	configManager = config.NewManager()
+	configManager.SetObserver(observer)
 	if err := configManager.Load(cfgFile); err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
@@ -89,6 +154,44 @@ func updateCommandDescriptions(rootCmd *cobra.Command) {
 			cmd.Short = i18n.T(i18n.CmdRemoveShort)
 			cmd.Long = i18n.T(i18n.CmdRemoveLong)
 			cmd.Example = i18n.T(i18n.CmdRemoveExample)
+		case "status":
+			cmd.Short = i18n.T(i18n.CmdStatusShort)
+			cmd.Long = i18n.T(i18n.CmdStatusLong)
+			cmd.Example = i18n.T(i18n.CmdStatusExample)
+		case "projects":
+			cmd.Short = i18n.T(i18n.CmdProjectsShort)
+			cmd.Long = i18n.T(i18n.CmdProjectsLong)
+			cmd.Example = i18n.T(i18n.CmdProjectsExample)
+			for _, sub := range cmd.Commands() {
+				switch sub.Name() {
+				case "list":
+					sub.Short = i18n.T(i18n.CmdProjectsListShort)
+				case "update":
+					sub.Short = i18n.T(i18n.CmdProjectsUpdateShort)
+				case "prune":
+					sub.Short = i18n.T(i18n.CmdProjectsPruneShort)
+				}
+			}
+		case "scan":
+			cmd.Short = i18n.T(i18n.CmdScanShort)
+			cmd.Long = i18n.T(i18n.CmdScanLong)
+			cmd.Example = i18n.T(i18n.CmdScanExample)
+		case "doctor":
+			cmd.Short = i18n.T(i18n.CmdDoctorShort)
+			cmd.Long = i18n.T(i18n.CmdDoctorLong)
+			cmd.Example = i18n.T(i18n.CmdDoctorExample)
+		case "verify":
+			cmd.Short = i18n.T(i18n.CmdVerifyShort)
+			cmd.Long = i18n.T(i18n.CmdVerifyLong)
+			cmd.Example = i18n.T(i18n.CmdVerifyExample)
+		case "repair":
+			cmd.Short = i18n.T(i18n.CmdRepairShort)
+			cmd.Long = i18n.T(i18n.CmdRepairLong)
+			cmd.Example = i18n.T(i18n.CmdRepairExample)
+		case "adopt":
+			cmd.Short = i18n.T(i18n.CmdAdoptShort)
+			cmd.Long = i18n.T(i18n.CmdAdoptLong)
+			cmd.Example = i18n.T(i18n.CmdAdoptExample)
 		}
 	}
 }
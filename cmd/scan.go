@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/doodleEsc/ctx-tool/internal/apperr"
+	"github.com/doodleEsc/ctx-tool/internal/config"
+	"github.com/doodleEsc/ctx-tool/internal/i18n"
+	"github.com/doodleEsc/ctx-tool/internal/registry"
+	"github.com/spf13/cobra"
+)
+
+// scanCmd registers existing installations found on disk
+var scanCmd = &cobra.Command{
+	Use:     "scan <dir>",
+	Short:   "Find existing installations and add them to the project registry",
+	Example: "  ctx-tool scan ~/src",
+	Args:    cobra.ExactArgs(1),
+	RunE:    runScan,
+}
+
+func init() {
+	rootCmd.AddCommand(scanCmd)
+}
+
+func runScan(cmd *cobra.Command, args []string) error {
+	info, err := os.Stat(args[0])
+	if err != nil || !info.IsDir() {
+		return apperr.New(apperr.NotFound, "directory %s does not exist", args[0])
+	}
+
+	found, err := registry.Scan(args[0], cfg.Tracking.File)
+	if err != nil {
+		return fmt.Errorf("scan %s: %w", args[0], err)
+	}
+
+	var projects []registry.Project
+	for _, trackingFile := range found {
+		scope := &installScope{Name: "project", BasePath: filepath.Dir(trackingFile), TrackingFile: trackingFile}
+		p, err := loadRegistryEntry(scope)
+		if err != nil {
+			fmt.Fprintf(out, "Warning: Skipping %s: %v\n", trackingFile, err)
+			continue
+		}
+
+		fmt.Fprintf(out, "  %s\n", i18n.Tf(i18n.MsgScanFound, map[string]interface{}{"Path": p.Path}))
+		projects = append(projects, p)
+		report.Projects = append(report.Projects, newProjectReport(p))
+	}
+
+	if len(projects) > 0 {
+		err := registry.Update(config.GetRegistryPath(), func(r *registry.Registry) error {
+			for _, p := range projects {
+				r.Register(p)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	report.Summary["registered"] = len(projects)
+	fmt.Fprintf(out, "%s\n", i18n.Tn(i18n.MsgScanRegistered, len(projects), map[string]interface{}{"Count": len(projects)}))
+	return nil
+}
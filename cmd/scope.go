@@ -0,0 +1,195 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/doodleEsc/ctx-tool/internal/apperr"
+	"github.com/doodleEsc/ctx-tool/internal/config"
+	"github.com/doodleEsc/ctx-tool/internal/i18n"
+	"github.com/doodleEsc/ctx-tool/internal/project"
+	"github.com/doodleEsc/ctx-tool/internal/registry"
+	"github.com/doodleEsc/ctx-tool/internal/store"
+	"github.com/doodleEsc/ctx-tool/internal/sync"
+	"github.com/doodleEsc/ctx-tool/internal/tracker"
+)
+
+// installScope describes where a scope installs files and keeps its
+// tracking data
+type installScope struct {
+	Name         string
+	BasePath     string
+	TrackingFile string
+	// PathMap places repository paths below BasePath
+	PathMap sync.PathMap
+}
+
+// rootReasonKeys describes why a directory was chosen as project root
+var rootReasonKeys = map[string]string{
+	project.ReasonTrackingFile: i18n.MsgRootTrackingFile,
+	project.ReasonClaudeDir:    i18n.MsgRootClaudeDir,
+	project.ReasonGitRoot:      i18n.MsgRootGitRoot,
+	project.ReasonWorkingDir:   i18n.MsgRootWorkingDir,
+}
+
+// resolveScope returns the project scope, or the global scope when global
+// is set. The project scope lives in --target or, unless --no-discover is
+// given, in the project root found above the current directory. Relative
+// tracking file paths resolve against it. Global tracking data
+// written by older versions is moved to the XDG state directory on the way.
+func resolveScope(global bool) (*installScope, error) {
+	if global && targetDir != "" {
+		return nil, apperr.New(apperr.Usage, "--target can't be combined with --global")
+	}
+
+	if !global {
+		return projectScope(true)
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("get home directory: %w", err)
+	}
+
+	trackingFile := config.GetGlobalTrackingPath()
+	legacyFile := filepath.Join(homeDir, config.LegacyGlobalTrackingFile)
+	if err := migrateTrackingFile(legacyFile, trackingFile); err != nil {
+		return nil, fmt.Errorf("migrate global tracking file: %w", err)
+	}
+
+	return &installScope{
+		Name:         "global",
+		BasePath:     filepath.Join(homeDir, ".claude"),
+		TrackingFile: trackingFile,
+		PathMap:      sync.GlobalPathMap,
+	}, nil
+}
+
+// projectScope returns the project scope of --target or the discovered
+// project root, which is printed when announce is set
+func projectScope(announce bool) (*installScope, error) {
+	basePath := "."
+	switch {
+	case targetDir != "":
+		info, err := os.Stat(targetDir)
+		if err != nil || !info.IsDir() {
+			return nil, apperr.New(apperr.NotFound, "target directory %s does not exist", targetDir)
+		}
+		basePath = filepath.Clean(targetDir)
+	case !noDiscoverFlag:
+		root, err := project.FindRoot(".", cfg.Tracking.File)
+		if err != nil {
+			return nil, fmt.Errorf("discover project root: %w", err)
+		}
+		basePath = root.Path
+		slog.Info("discovered project root", "root", root.Path, "reason", root.Reason)
+		if announce {
+			fmt.Fprintf(out, "%s\n", i18n.Tf(i18n.MsgProjectRoot, map[string]interface{}{"Root": root.Path, "Reason": i18n.T(rootReasonKeys[root.Reason])}))
+		}
+	}
+
+	trackingFile := cfg.Tracking.File
+	if !filepath.IsAbs(trackingFile) {
+		trackingFile = filepath.Join(basePath, trackingFile)
+	}
+
+	return &installScope{
+		Name:         "project",
+		BasePath:     basePath,
+		TrackingFile: trackingFile,
+		PathMap:      sync.ProjectPathMap,
+	}, nil
+}
+
+// scopePaths resolves path arguments given from the current directory
+// against the scope's base directory
+func scopePaths(s *installScope, args []string) ([]string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("get working directory: %w", err)
+	}
+	return project.RelativeArgs(s.BasePath, cwd, args)
+}
+
+// registeredScope returns the scope of a project from the registry
+func registeredScope(p registry.Project) *installScope {
+	scope := &installScope{
+		Name:         p.Scope,
+		BasePath:     p.Path,
+		TrackingFile: p.TrackingFile,
+		PathMap:      sync.ProjectPathMap,
+	}
+	if p.Scope == "global" {
+		scope.PathMap = sync.GlobalPathMap
+	}
+	return scope
+}
+
+// migrateTrackingFile moves a tracking file from its legacy location unless
+// the new one already exists
+func migrateTrackingFile(legacyFile, trackingFile string) error {
+	if config.FileExists(trackingFile) || !config.FileExists(legacyFile) {
+		return nil
+	}
+
+	data, err := os.ReadFile(legacyFile)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(trackingFile), 0755); err != nil {
+		return err
+	}
+	// Copy rather than rename, the state directory may be on another device
+	if err := os.WriteFile(trackingFile, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Remove(legacyFile); err != nil {
+		return err
+	}
+
+	slog.Info("migrated tracking file", "from", legacyFile, "to", trackingFile)
+	fmt.Fprintf(out, "%s\n", i18n.Tf(i18n.MsgMigratedTrackingFile, map[string]interface{}{"From": legacyFile, "Path": trackingFile}))
+	return nil
+}
+
+// migrateLayout moves files a scope installed before its path map existed
+// and saves the tracker if anything changed
+func migrateLayout(s *installScope, t *tracker.Tracker) error {
+	if len(s.PathMap) == 0 {
+		return nil
+	}
+
+	moved, err := sync.MigrateLayout(t, s.PathMap, observer)
+	if err != nil {
+		return fmt.Errorf("migrate installed files: %w", err)
+	}
+	if moved == 0 {
+		return nil
+	}
+	return t.Save()
+}
+
+// detachEditedLinks breaks off linked files the user edited in place and
+// saves the tracker if anything changed. It holds the store lock while
+// evicting edited objects.
+func detachEditedLinks(t *tracker.Tracker) error {
+	if linkMode(t) == store.Copy {
+		return nil
+	}
+	unlock, err := store.Lock(config.GetStorePath(), storeLockTimeout)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	changed, err := sync.DetachEditedLinks(t, store.New(config.GetStorePath(), t.Algorithm), observer)
+	if err != nil {
+		return fmt.Errorf("detach edited links: %w", err)
+	}
+	if changed == 0 {
+		return nil
+	}
+	return t.Save()
+}
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"github.com/doodleEsc/ctx-tool/internal/apperr"
+	"github.com/doodleEsc/ctx-tool/internal/event"
+	"github.com/doodleEsc/ctx-tool/internal/git"
+	"github.com/doodleEsc/ctx-tool/internal/logging"
+	"github.com/doodleEsc/ctx-tool/internal/tracker"
+)
+
+// loadKeyring returns the keys trusted to sign upstream revisions, or nil
+// when signatures aren't required
+func loadKeyring() (*git.Keyring, error) {
+	if !cfg.Verification.RequireSignature {
+		return nil, nil
+	}
+
+	keyring, err := git.LoadKeyring(cfg.Verification.Keyring)
+	if err != nil {
+		return nil, apperr.Wrap(apperr.Usage, err)
+	}
+	if keyring.Len() == 0 {
+		return nil, apperr.New(apperr.Usage, "verification.require_signature is set but verification.keyring holds no keys")
+	}
+	return keyring, nil
+}
+
+// verifySignature checks the signature of the revision cloned into dir, so
+// untrusted content is refused before anything is installed
+func verifySignature(dir, tag, revision string, keyring *git.Keyring) (*tracker.Signature, error) {
+	sig, err := git.VerifyRevision(dir, tag, keyring)
+	if err != nil {
+		return nil, err
+	}
+
+	logging.Component("git").Info("signature verified", "object", sig.Object, "format", sig.Format, "key", sig.Key, "signer", sig.Signer)
+	observer.Notify(event.Event{Kind: event.SignatureVerified, Revision: revision, Signer: sig.Signer, Key: sig.Key})
+	return &tracker.Signature{Object: sig.Object, Format: sig.Format, Key: sig.Key, Signer: sig.Signer}, nil
+}
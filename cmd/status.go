@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/doodleEsc/ctx-tool/internal/apperr"
+	"github.com/doodleEsc/ctx-tool/internal/checksum"
+	"github.com/doodleEsc/ctx-tool/internal/config"
+	"github.com/doodleEsc/ctx-tool/internal/i18n"
+	"github.com/doodleEsc/ctx-tool/internal/tracker"
+	"github.com/spf13/cobra"
+)
+
+var statusGlobalFlag bool
+
+var statusCmd = &cobra.Command{
+	Use:     "status",
+	Short:   "Show the state of installed configurations",
+	Long:    "Show where configurations are installed and which tracked files changed since installation.",
+	Example: "  ctx-tool status\n  ctx-tool status --target packages/api\n  ctx-tool status --global",
+	Args:    cobra.NoArgs,
+	RunE:    runStatus,
+}
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+
+	statusCmd.Flags().BoolVar(&statusGlobalFlag, "global", false, "Show the global installation")
+}
+
+func runStatus(cmd *cobra.Command, args []string) error {
+	scope, err := resolveScope(statusGlobalFlag)
+	if err != nil {
+		return err
+	}
+
+	report.Scope = scope.Name
+	report.Target = scope.BasePath
+	report.TrackingFile = scope.TrackingFile
+
+	fmt.Fprintf(out, "%s\n", i18n.Tf(i18n.MsgInstallationScope, map[string]interface{}{"Scope": scope.Name}))
+	fmt.Fprintf(out, "%s\n", i18n.Tf(i18n.MsgTargetDirectory, map[string]interface{}{"Target": scope.BasePath}))
+	fmt.Fprintf(out, "%s\n", i18n.Tf(i18n.MsgStatusTrackingFile, map[string]interface{}{"Path": scope.TrackingFile}))
+
+	if !config.FileExists(scope.TrackingFile) {
+		return apperr.New(apperr.NotFound, "%s", i18n.Tf(i18n.MsgNoTrackedFiles, map[string]interface{}{"Path": scope.TrackingFile}))
+	}
+
+	trackerInstance := tracker.NewTracker(scope.TrackingFile, scope.Name, scope.BasePath)
+	trackerInstance.Cache = checksum.LoadCache(config.GetHashCachePath())
+	defer trackerInstance.Cache.Save()
+	if err := trackerInstance.Load(); err != nil {
+		return fmt.Errorf("load tracking data: %w", err)
+	}
+
+	installation := trackerInstance.Installation
+	if installation.Repository != "" {
+		report.Repository = &repositoryReport{URL: installation.Repository, Branch: installation.Branch, Tag: installation.Tag, Revision: installation.Revision}
+		ref := installation.Branch
+		if installation.Tag != "" {
+			ref = "tag " + installation.Tag
+		}
+		fmt.Fprintf(out, "%s\n", i18n.Tf(i18n.MsgStatusRepository, map[string]interface{}{
+			"Repo":     installation.Repository,
+			"Branch":   ref,
+			"Revision": shortRevision(installation.Revision),
+		}))
+	}
+	if sig := installation.Signature; sig != nil && report.Repository != nil {
+		report.Repository.Signer, report.Repository.SignerKey = sig.Signer, sig.Key
+		fmt.Fprintf(out, "%s\n", i18n.Tf(i18n.MsgStatusSignature, map[string]interface{}{"Signer": sig.Signer, "Key": sig.Key}))
+	}
+	fmt.Fprintf(out, "%s\n\n", i18n.Tf(i18n.MsgStatusInstalledAt, map[string]interface{}{"Time": installation.Timestamp}))
+
+	var modified, missing int
+	for _, relPath := range trackerInstance.GetTrackedFiles() {
+		entry, _ := trackerInstance.GetEntry(relPath)
+		fullPath := filepath.Join(scope.BasePath, relPath)
+		file := fileReport{Path: relPath, Hash: entry.Hash, Algorithm: string(entry.Algorithm), Link: entry.Link}
+
+		if _, err := os.Stat(fullPath); os.IsNotExist(err) {
+			missing++
+			file.Action = "missing"
+			fmt.Fprintf(out, "  ! %s (%s)\n", relPath, i18n.T(i18n.MsgStatusMissing))
+		} else if changed, err := trackerInstance.IsModified(relPath, fullPath); err != nil {
+			return err
+		} else if changed {
+			modified++
+			file.Action = "modified"
+			fmt.Fprintf(out, "  M %s (%s)\n", relPath, i18n.T(i18n.MsgStatusModified))
+		} else {
+			file.Action = "unchanged"
+		}
+		report.addFile(file)
+	}
+
+	total := len(report.Files)
+	if modified == 0 && missing == 0 {
+		fmt.Fprintln(out, i18n.T(i18n.MsgStatusClean))
+	}
+	fmt.Fprintf(out, "%s\n", i18n.Tn(i18n.MsgStatusSummary, total, map[string]interface{}{"Count": total, "Modified": modified, "Missing": missing}))
+
+	return nil
+}
+
+// shortRevision abbreviates a commit hash the way git does
+func shortRevision(revision string) string {
+	if len(revision) > 12 {
+		return revision[:12]
+	}
+	return revision
+}
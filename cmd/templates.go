@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/doodleEsc/ctx-tool/internal/config"
+	"github.com/doodleEsc/ctx-tool/internal/git"
+	"github.com/doodleEsc/ctx-tool/internal/logging"
+	"github.com/doodleEsc/ctx-tool/internal/project"
+	"github.com/doodleEsc/ctx-tool/internal/render"
+)
+
+// templateVarPrefix marks environment variables that set template variables
+const templateVarPrefix = "CTX_TOOL_VAR_"
+
+// templateData collects what templates installed into scope can use.
+// Variables come from templates.vars in the configuration, then the
+// project manifest, then CTX_TOOL_VAR_* environment variables; later
+// sources win. Only those variables are exposed as Env, so upstream
+// templates can't copy secrets from the environment into installed files.
+// Project facts are only detected for the project scope.
+func templateData(scope *installScope) (*render.Data, error) {
+	data := &render.Data{Vars: map[string]string{}, Env: map[string]string{}}
+
+	for name, value := range cfg.Templates.Vars {
+		data.Vars[name] = value
+	}
+
+	if scope.Name == "project" {
+		root, err := filepath.Abs(scope.BasePath)
+		if err != nil {
+			return nil, err
+		}
+		data.Project = project.Detect(root)
+		// Credentials in the origin URL must not end up in installed files
+		data.Project.Remote = git.RedactURL(data.Project.Remote)
+
+		manifest, err := config.LoadProjectManifest(root)
+		if err != nil {
+			return nil, err
+		}
+		for name, value := range manifest.Vars {
+			data.Vars[name] = value
+		}
+	}
+
+	for _, env := range os.Environ() {
+		name, value, _ := strings.Cut(env, "=")
+		if varName, ok := strings.CutPrefix(name, templateVarPrefix); ok && varName != "" {
+			data.Env[name] = value
+			data.Vars[strings.ToLower(varName)] = value
+		}
+	}
+
+	logging.Component("render").Debug("template data", "project", data.Project.Name, "remote", data.Project.Remote, "language", data.Project.Language, "vars", len(data.Vars))
+	return data, nil
+}
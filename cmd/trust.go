@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/doodleEsc/ctx-tool/internal/apperr"
+	"github.com/doodleEsc/ctx-tool/internal/config"
+	"github.com/doodleEsc/ctx-tool/internal/git"
+	"github.com/doodleEsc/ctx-tool/internal/i18n"
+	"github.com/doodleEsc/ctx-tool/internal/logging"
+	"golang.org/x/term"
+)
+
+var trustFlag bool
+
+// ensureTrusted refuses to install from a repository the user hasn't
+// trusted yet. The first install from a new source asks for confirmation;
+// runs that can't prompt need --trust. Confirmed sources are added to the
+// loaded user config; with only a project config they are trusted for this
+// run.
+func ensureTrusted(url string) error {
+	origin := i18n.T(i18n.MsgSourceRegistry)
+	if git.RedactURL(url) == git.RedactURL(cfg.Repository.URL) {
+		origin = configManager.Source("repository.url")
+		if origin == "" {
+			// The built-in default ships with ctx-tool
+			return nil
+		}
+	}
+
+	userConfig := config.TrustConfigPath(configManager.File(), cfgFile != "")
+	if userConfig != "" {
+		sources, err := config.LoadTrustedSources(userConfig)
+		if err != nil {
+			return err
+		}
+		if config.IsTrusted(sources, url) {
+			return nil
+		}
+	}
+
+	displayURL := git.RedactURL(url)
+	logging.Component("trust").Info("untrusted source", "url", displayURL, "origin", origin, "trust_flag", trustFlag)
+	message := i18n.Tf(i18n.MsgUntrustedSource, map[string]interface{}{"URL": displayURL, "Origin": origin})
+
+	if !trustFlag {
+		if machineOutput() || !stdinIsTerminal() {
+			return apperr.New(apperr.Untrusted, "%s", message)
+		}
+
+		fmt.Fprintf(promptOut(), "\n%s\n%s", message, i18n.T(i18n.MsgConfirmTrust))
+		response, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil {
+			return fmt.Errorf("read user input: %w", err)
+		}
+		response = strings.TrimSpace(strings.ToLower(response))
+		if response != "yes" && response != "y" {
+			return apperr.New(apperr.Cancelled, "%s", i18n.T(i18n.MsgTrustCancelled))
+		}
+	}
+
+	if userConfig == "" {
+		fmt.Fprintf(out, "%s\n", i18n.Tf(i18n.MsgSourceTrustedOnce, map[string]interface{}{"URL": displayURL, "Path": configManager.File()}))
+		return nil
+	}
+	if err := config.AddTrustedSource(userConfig, url); err != nil {
+		return fmt.Errorf("save trusted source: %w", err)
+	}
+	fmt.Fprintf(out, "%s\n", i18n.Tf(i18n.MsgSourceTrusted, map[string]interface{}{"URL": displayURL, "Path": userConfig}))
+	return nil
+}
+
+// stdinIsTerminal reports whether someone can answer a prompt
+func stdinIsTerminal() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
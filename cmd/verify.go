@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/doodleEsc/ctx-tool/internal/apperr"
+	"github.com/doodleEsc/ctx-tool/internal/checksum"
+	"github.com/doodleEsc/ctx-tool/internal/config"
+	"github.com/doodleEsc/ctx-tool/internal/i18n"
+	"github.com/doodleEsc/ctx-tool/internal/tracker"
+	"github.com/spf13/cobra"
+)
+
+// stateUpstream marks a file whose recorded content differs from upstream
+// at the recorded revision
+const stateUpstream = "upstream_mismatch"
+
+var (
+	verifyGlobalFlag   bool
+	verifyUpstreamFlag bool
+)
+
+var verifyCmd = &cobra.Command{
+	Use:     "verify [paths...]",
+	Short:   "Check installed files against their recorded checksums",
+	Long:    "Re-hash every tracked file and compare it with the checksum recorded at install time. Exits with a non-zero code when a file is modified, missing or corrupted, so it can run in CI or a pre-commit hook.",
+	Example: "  ctx-tool verify\n  ctx-tool verify .claude/commands\n  ctx-tool verify --upstream",
+	RunE:    runVerify,
+}
+
+func init() {
+	rootCmd.AddCommand(verifyCmd)
+
+	verifyCmd.Flags().BoolVar(&verifyGlobalFlag, "global", false, "Verify the global installation")
+	verifyCmd.Flags().BoolVar(&verifyUpstreamFlag, "upstream", false, "Also compare the recorded checksums with upstream at the recorded revision")
+	verifyCmd.Flags().BoolVar(&trustFlag, "trust", false, "Trust the recorded repository without asking if it isn't a trusted source yet")
+}
+
+// verifyStateKeys maps file states to their localized label
+var verifyStateKeys = map[string]string{
+	tracker.StateModified:  i18n.MsgStatusModified,
+	tracker.StateMissing:   i18n.MsgStatusMissing,
+	tracker.StateCorrupted: i18n.MsgVerifyCorrupted,
+	stateUpstream:          i18n.MsgVerifyUpstream,
+}
+
+// verifyStateMarks are the markers printed before files in each state
+var verifyStateMarks = map[string]string{
+	tracker.StateModified:  "M",
+	tracker.StateMissing:   "!",
+	tracker.StateCorrupted: "C",
+	stateUpstream:          "U",
+}
+
+func runVerify(cmd *cobra.Command, args []string) error {
+	scope, err := resolveScope(verifyGlobalFlag)
+	if err != nil {
+		return err
+	}
+
+	report.Scope = scope.Name
+	report.Target = scope.BasePath
+	report.TrackingFile = scope.TrackingFile
+
+	if !config.FileExists(scope.TrackingFile) {
+		return apperr.New(apperr.NotFound, "%s", i18n.Tf(i18n.MsgNoTrackedFiles, map[string]interface{}{"Path": scope.TrackingFile}))
+	}
+
+	trackerInstance := tracker.NewTracker(scope.TrackingFile, scope.Name, scope.BasePath)
+	trackerInstance.Cache = checksum.LoadCache(config.GetHashCachePath())
+	defer func() {
+		if err := trackerInstance.Cache.Save(); err != nil {
+			fmt.Fprintf(out, "Warning: Failed to save hash cache: %v\n", err)
+		}
+	}()
+	if err := trackerInstance.Load(); err != nil {
+		return fmt.Errorf("load tracking data: %w", err)
+	}
+
+	paths, err := scopePaths(scope, args)
+	if err != nil {
+		return err
+	}
+	states, err := trackerInstance.States(paths)
+	if err != nil {
+		return err
+	}
+	if len(states) == 0 && len(args) > 0 {
+		return apperr.New(apperr.NotFound, "%s", i18n.Tf(i18n.MsgNoMatchingFiles, map[string]interface{}{"Patterns": strings.Join(args, ", ")}))
+	}
+
+	if verifyUpstreamFlag {
+		if err := compareUpstream(scope, trackerInstance, states); err != nil {
+			return err
+		}
+	}
+
+	failed := 0
+	for _, s := range states {
+		entry, _ := trackerInstance.GetEntry(s.Path)
+		report.addFile(fileReport{Path: s.Path, Action: s.State, Hash: entry.Hash, Algorithm: string(entry.Algorithm), Link: entry.Link})
+		if s.State == tracker.StateIntact {
+			continue
+		}
+		failed++
+		fmt.Fprintf(out, "  %s %s (%s)\n", verifyStateMarks[s.State], s.Path, i18n.T(verifyStateKeys[s.State]))
+	}
+
+	if failed == 0 {
+		fmt.Fprintln(out, i18n.T(i18n.MsgStatusClean))
+	}
+	fmt.Fprintf(out, "%s\n", i18n.Tn(i18n.MsgVerifySummary, len(states), map[string]interface{}{"Count": len(states), "Failed": failed}))
+	return tracker.Verify(states)
+}
+
+// compareUpstream marks intact files whose recorded checksum doesn't match
+// upstream at the recorded revision. Merged files are left out: their
+// checksum covers only what was merged into the file.
+func compareUpstream(scope *installScope, t *tracker.Tracker, states []tracker.FileState) error {
+	installation := t.Installation
+	if installation.Repository == "" || installation.Revision == "" {
+		return apperr.New(apperr.NotFound, "%s records no upstream revision; reinstall it to use --upstream", scope.TrackingFile)
+	}
+
+	src, cleanup, err := cloneRevision(installation.Repository, installation.Branch, installation.Tag, installation.Revision)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	syncer, err := newSyncer(scope, src, t)
+	if err != nil {
+		return err
+	}
+	planned, err := syncer.Plan(installedDirs(t))
+	if err != nil {
+		return fmt.Errorf("read upstream files: %w", err)
+	}
+	upstreamContent := make(map[string][]byte, len(planned))
+	for _, p := range planned {
+		upstreamContent[p.Path] = p.Content
+	}
+
+	for i, s := range states {
+		entry, _ := t.GetEntry(s.Path)
+		if s.State != tracker.StateIntact || entry.Mode != "" {
+			continue
+		}
+		content, ok := upstreamContent[s.Path]
+		if !ok {
+			states[i].State = stateUpstream
+			continue
+		}
+		sum, err := checksum.Reader(bytes.NewReader(content), entry.Algorithm)
+		if err != nil {
+			return err
+		}
+		if sum != entry.Hash {
+			states[i].State = stateUpstream
+		}
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package apperr
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Kind classifies an error so callers can decide how to react to it
+type Kind int
+
+const (
+	// Internal is any error that hasn't been classified
+	Internal Kind = iota
+	// Usage means invalid flags, arguments or configuration values
+	Usage
+	// Network means the upstream repository couldn't be reached
+	Network
+	// Auth means the upstream rejected or required credentials
+	Auth
+	// NotFound means a repository, branch, directory or tracking file is missing
+	NotFound
+	// NotAllowed means the operation targets something outside the allowed list
+	NotAllowed
+	// Conflict means local changes need a human decision
+	Conflict
+	// Locked means another ctx-tool process holds the tracking file
+	Locked
+	// Cancelled means the user declined to continue
+	Cancelled
+	// Partial means the operation finished but some files failed
+	Partial
+	// Untrusted means upstream content failed a signature or trust check
+	Untrusted
+)
+
+// exitCodes is the documented exit status of each kind
+var exitCodes = map[Kind]int{
+	Internal:   1,
+	Usage:      2,
+	Network:    3,
+	Auth:       4,
+	NotFound:   5,
+	NotAllowed: 6,
+	Conflict:   7,
+	Locked:     8,
+	Cancelled:  9,
+	Partial:    10,
+	Untrusted:  11,
+}
+
+var names = map[Kind]string{
+	Internal:   "internal",
+	Usage:      "usage",
+	Network:    "network",
+	Auth:       "auth",
+	NotFound:   "not_found",
+	NotAllowed: "not_allowed",
+	Conflict:   "conflict",
+	Locked:     "locked",
+	Cancelled:  "cancelled",
+	Partial:    "partial",
+	Untrusted:  "untrusted",
+}
+
+// String returns the stable name of the kind used in JSON output
+func (k Kind) String() string {
+	if name, ok := names[k]; ok {
+		return name
+	}
+	return names[Internal]
+}
+
+// ExitCode returns the process exit status for the kind
+func (k Kind) ExitCode() int {
+	if code, ok := exitCodes[k]; ok {
+		return code
+	}
+	return exitCodes[Internal]
+}
+
+// Error is an error tagged with a Kind
+type Error struct {
+	Kind Kind
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// New returns an error of the given kind with a formatted message
+func New(kind Kind, format string, args ...interface{}) error {
+	return &Error{Kind: kind, Err: fmt.Errorf(format, args...)}
+}
+
+// Wrap tags err with kind; it returns nil if err is nil
+func Wrap(kind Kind, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Kind: kind, Err: err}
+}
+
+// KindOf returns the kind of the outermost tagged error in err's chain, or
+// Internal if there is none
+func KindOf(err error) Kind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	return Internal
+}
+
+// ExitCode returns the exit status for err; 0 if err is nil
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	return KindOf(err).ExitCode()
+}
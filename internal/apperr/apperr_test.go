@@ -0,0 +1,48 @@
+package apperr
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestKindOfWrappedError(t *testing.T) {
+	base := errors.New("connection refused")
+	err := fmt.Errorf("clone repository: %w", Wrap(Network, base))
+
+	if got := KindOf(err); got != Network {
+		t.Errorf("KindOf() = %v, want %v", got, Network)
+	}
+	if !errors.Is(err, base) {
+		t.Error("Wrapped error should still match the underlying error")
+	}
+	if got := ExitCode(err); got != 3 {
+		t.Errorf("ExitCode() = %d, want 3", got)
+	}
+}
+
+func TestExitCodes(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{nil, 0},
+		{errors.New("plain"), 1},
+		{New(Usage, "bad flag"), 2},
+		{New(NotFound, "missing %s", "dir"), 5},
+		{New(Cancelled, "cancelled"), 9},
+		{New(Partial, "2 files failed"), 10},
+	}
+
+	for _, tt := range tests {
+		if got := ExitCode(tt.err); got != tt.want {
+			t.Errorf("ExitCode(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestWrapNil(t *testing.T) {
+	if Wrap(Conflict, nil) != nil {
+		t.Error("Wrap(nil) should return nil")
+	}
+}
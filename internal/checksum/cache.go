@@ -0,0 +1,173 @@
+package checksum
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// cacheEntry remembers the checksum of a file together with the stat data
+// that identified it when it was hashed
+type cacheEntry struct {
+	Size      int64     `json:"size"`
+	ModTime   int64     `json:"mtime"`
+	Inode     uint64    `json:"inode"`
+	Algorithm Algorithm `json:"algorithm"`
+	Hash      string    `json:"hash"`
+	Checked   int64     `json:"checked"`
+}
+
+// Cache maps (size, mtime, inode) of a file to its checksum so unchanged
+// files are not re-hashed on every run. A nil *Cache hashes every time.
+type Cache struct {
+	path    string
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+	dirty   bool
+}
+
+// NewCache creates an empty cache persisted at path
+func NewCache(path string) *Cache {
+	return &Cache{
+		path:    path,
+		entries: make(map[string]cacheEntry),
+	}
+}
+
+// LoadCache reads a cache from disk. A missing or corrupt file yields an empty cache.
+func LoadCache(path string) *Cache {
+	c := NewCache(path)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return c
+	}
+	if err := json.Unmarshal(data, &c.entries); err != nil {
+		c.entries = make(map[string]cacheEntry)
+	}
+	return c
+}
+
+// Save writes the cache to disk if anything changed
+func (c *Cache) Save() error {
+	if c == nil || c.path == "" {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.dirty {
+		return nil
+	}
+
+	// Drop entries for files that no longer exist
+	for path := range c.entries {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			delete(c.entries, path)
+		}
+	}
+
+	data, err := json.Marshal(c.entries)
+	if err != nil {
+		return fmt.Errorf("marshal hash cache: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return fmt.Errorf("create cache directory: %w", err)
+	}
+
+	if err := os.WriteFile(c.path, data, 0644); err != nil {
+		return fmt.Errorf("write hash cache: %w", err)
+	}
+
+	c.dirty = false
+	return nil
+}
+
+// File returns the checksum of a file, reusing the cached value when the
+// file's size, mtime and inode are unchanged
+func (c *Cache) File(filePath string, algo Algorithm) (string, error) {
+	if c == nil {
+		return File(filePath, algo)
+	}
+
+	key, info, err := cacheKey(filePath)
+	if err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+
+	if ok && entry.matches(info, algo) {
+		return entry.Hash, nil
+	}
+
+	sum, err := File(filePath, algo)
+	if err != nil {
+		return "", err
+	}
+
+	c.store(key, info, algo, sum)
+	return sum, nil
+}
+
+// Store records a checksum that was calculated elsewhere, e.g. while copying
+func (c *Cache) Store(filePath string, algo Algorithm, sum string) {
+	if c == nil {
+		return
+	}
+
+	key, info, err := cacheKey(filePath)
+	if err != nil {
+		return
+	}
+	c.store(key, info, algo, sum)
+}
+
+func (c *Cache) store(key string, info os.FileInfo, algo Algorithm, sum string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[key] = cacheEntry{
+		Size:      info.Size(),
+		ModTime:   info.ModTime().UnixNano(),
+		Inode:     inode(info),
+		Algorithm: algo,
+		Hash:      sum,
+		Checked:   time.Now().UnixNano(),
+	}
+	c.dirty = true
+}
+
+// matches reports whether the entry still describes the file. Files modified
+// within a second of being hashed are not trusted, since a later write in the
+// same mtime tick would go unnoticed.
+func (e cacheEntry) matches(info os.FileInfo, algo Algorithm) bool {
+	if e.Algorithm != algo || e.Size != info.Size() || e.Inode != inode(info) {
+		return false
+	}
+	if e.ModTime != info.ModTime().UnixNano() {
+		return false
+	}
+	return e.ModTime < e.Checked-int64(time.Second)
+}
+
+func cacheKey(filePath string) (string, os.FileInfo, error) {
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return "", nil, fmt.Errorf("resolve path: %w", err)
+	}
+
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return "", nil, fmt.Errorf("stat file: %w", err)
+	}
+
+	return absPath, info, nil
+}
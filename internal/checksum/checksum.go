@@ -0,0 +1,75 @@
+package checksum
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strings"
+)
+
+// Algorithm identifies the hash function used for content checksums
+type Algorithm string
+
+const (
+	// MD5 is only kept to read tracking files written by older versions
+	MD5    Algorithm = "md5"
+	SHA256 Algorithm = "sha256"
+
+	// Default is the algorithm used for newly recorded files
+	Default = SHA256
+)
+
+// ParseAlgorithm converts a configured algorithm name to an Algorithm for
+// new records. MD5 is refused: it is only read from legacy entries.
+func ParseAlgorithm(name string) (Algorithm, error) {
+	switch Algorithm(strings.ToLower(strings.TrimSpace(name))) {
+	case "":
+		return Default, nil
+	case SHA256, "sha-256":
+		return SHA256, nil
+	case MD5:
+		return "", fmt.Errorf("hash algorithm %q is only supported for reading legacy tracking files", name)
+	default:
+		return "", fmt.Errorf("unsupported hash algorithm %q", name)
+	}
+}
+
+// New returns a fresh hash.Hash for the algorithm
+func (a Algorithm) New() (hash.Hash, error) {
+	switch a {
+	case SHA256:
+		return sha256.New(), nil
+	case MD5:
+		return md5.New(), nil
+	default:
+		return nil, fmt.Errorf("unsupported hash algorithm %q", string(a))
+	}
+}
+
+// Reader calculates the checksum of everything read from r
+func Reader(r io.Reader, algo Algorithm) (string, error) {
+	h, err := algo.New()
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := io.Copy(h, r); err != nil {
+		return "", fmt.Errorf("calculate hash: %w", err)
+	}
+
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// File calculates the checksum of a file
+func File(filePath string, algo Algorithm) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", fmt.Errorf("open file: %w", err)
+	}
+	defer file.Close()
+
+	return Reader(file, algo)
+}
@@ -0,0 +1,134 @@
+package checksum
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFile(t *testing.T) {
+	tempDir := t.TempDir()
+	testFile := filepath.Join(tempDir, "test.txt")
+	if err := os.WriteFile(testFile, []byte("Hello, World!"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	tests := []struct {
+		algo Algorithm
+		want string
+	}{
+		{SHA256, "dffd6021bb2bd5b0af676290809ec3a53191dd81c7f70a4b28688a362182986f"},
+		{MD5, "65a8e27d8879283831b664bd8b7f0ad4"},
+	}
+
+	for _, tt := range tests {
+		sum, err := File(testFile, tt.algo)
+		if err != nil {
+			t.Fatalf("File(%s) failed: %v", tt.algo, err)
+		}
+		if sum != tt.want {
+			t.Errorf("%s mismatch: got %s, want %s", tt.algo, sum, tt.want)
+		}
+	}
+}
+
+func TestFile_NonExistentFile(t *testing.T) {
+	if _, err := File("/non/existent/file", Default); err == nil {
+		t.Error("Expected error for non-existent file, got nil")
+	}
+}
+
+func TestParseAlgorithm(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    Algorithm
+		wantErr bool
+	}{
+		{"", SHA256, false},
+		{"sha256", SHA256, false},
+		{"SHA-256", SHA256, false},
+		{"md5", "", true},
+		{"crc32", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseAlgorithm(tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseAlgorithm(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseAlgorithm(%q) = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCacheReusesHashForUnchangedStat(t *testing.T) {
+	tempDir := t.TempDir()
+	testFile := filepath.Join(tempDir, "prompt.md")
+	oldTime := time.Now().Add(-time.Hour)
+
+	writeFile := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+		if err := os.Chtimes(testFile, oldTime, oldTime); err != nil {
+			t.Fatalf("Failed to set mtime: %v", err)
+		}
+	}
+
+	writeFile("aaaa")
+	cachePath := filepath.Join(tempDir, "cache", "hashes.json")
+	cache := NewCache(cachePath)
+
+	first, err := cache.File(testFile, SHA256)
+	if err != nil {
+		t.Fatalf("Cache.File failed: %v", err)
+	}
+	if err := cache.Save(); err != nil {
+		t.Fatalf("Cache.Save failed: %v", err)
+	}
+
+	// Same size and mtime: a reloaded cache must answer without reading the file
+	writeFile("bbbb")
+	reloaded := LoadCache(cachePath)
+	cached, err := reloaded.File(testFile, SHA256)
+	if err != nil {
+		t.Fatalf("Cache.File failed: %v", err)
+	}
+	if cached != first {
+		t.Errorf("Expected cached hash %s, got %s", first, cached)
+	}
+
+	// A different mtime invalidates the entry
+	newTime := oldTime.Add(time.Minute)
+	if err := os.Chtimes(testFile, newTime, newTime); err != nil {
+		t.Fatalf("Failed to set mtime: %v", err)
+	}
+	fresh, err := reloaded.File(testFile, SHA256)
+	if err != nil {
+		t.Fatalf("Cache.File failed: %v", err)
+	}
+	want, _ := File(testFile, SHA256)
+	if fresh != want {
+		t.Errorf("Expected fresh hash %s, got %s", want, fresh)
+	}
+}
+
+func TestNilCacheHashesFile(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "test.txt")
+	if err := os.WriteFile(testFile, []byte("Hello, World!"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	var cache *Cache
+	sum, err := cache.File(testFile, MD5)
+	if err != nil {
+		t.Fatalf("Cache.File failed: %v", err)
+	}
+	if sum != "65a8e27d8879283831b664bd8b7f0ad4" {
+		t.Errorf("Unexpected hash from nil cache: %s", sum)
+	}
+}
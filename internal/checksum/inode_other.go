@@ -0,0 +1,10 @@
+//go:build !unix
+
+package checksum
+
+import "os"
+
+// inode is not available on this platform; size and mtime identify the file
+func inode(info os.FileInfo) uint64 {
+	return 0
+}
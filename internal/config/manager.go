@@ -2,25 +2,39 @@ package config
 
 import (
 	"fmt"
+	"os"
 	"strings"
 
+	"github.com/doodleEsc/ctx-tool/internal/event"
+	"github.com/doodleEsc/ctx-tool/internal/git"
+	"github.com/doodleEsc/ctx-tool/internal/logging"
 	"github.com/spf13/viper"
 )
 
 type Manager struct {
-	v      *viper.Viper
-	config *Config
+	v        *viper.Viper
+	config   *Config
+	file     string
+	observer event.Observer
 }
 
 func NewManager() *Manager {
 	v := viper.New()
 	return &Manager{
-		v:      v,
-		config: &Config{},
+		v:        v,
+		config:   &Config{},
+		observer: event.Discard,
 	}
 }
 
+// SetObserver sets the observer notified about config resolution
+func (m *Manager) SetObserver(o event.Observer) {
+	m.observer = event.OrDiscard(o)
+}
+
 func (m *Manager) Load(configPath string) error {
+	log := logging.Component("config")
+
 	// Set defaults
 	m.setDefaults()
 
@@ -38,12 +52,15 @@ func (m *Manager) Load(configPath string) error {
 		m.v.SetConfigFile(configPath)
 		foundPath = configPath
 		configFound = FileExists(configPath)
+		log.Debug("explicit config file", "path", configPath, "exists", configFound)
 	} else {
 		// Search for config files in priority order
 		searchPaths := GetConfigPaths()
 		
-		for _, path := range searchPaths {
-			if FileExists(path) {
+		for i, path := range searchPaths {
+			exists := FileExists(path)
+			log.Debug("config search", "priority", i+1, "path", path, "exists", exists)
+			if exists {
 				m.v.SetConfigFile(path)
 				foundPath = path
 				configFound = true
@@ -54,14 +71,14 @@ func (m *Manager) Load(configPath string) error {
 		// If no config file found, try to create XDG default config
 		if !configFound {
 			if err := EnsureConfigFile(); err != nil {
-				fmt.Printf("Warning: Failed to create default config file: %v\n", err)
+				m.observer.Notify(event.Event{Kind: event.Warning, Reason: event.ReasonConfigCreate, Path: GetXDGConfigPath(), Err: err})
 			} else {
 				xdgPath := GetXDGConfigPath()
 				if FileExists(xdgPath) {
 					m.v.SetConfigFile(xdgPath)
 					foundPath = xdgPath
 					configFound = true
-					fmt.Printf("Created default configuration file: %s\n", xdgPath)
+					m.observer.Notify(event.Event{Kind: event.ConfigCreated, Path: xdgPath})
 				}
 			}
 		}
@@ -69,17 +86,23 @@ func (m *Manager) Load(configPath string) error {
 
 	// Read config file if found
 	if configFound {
+		m.file = foundPath
 		if err := m.v.ReadInConfig(); err != nil {
 			return fmt.Errorf("config file error reading %s: %w", foundPath, err)
 		}
-		fmt.Printf("Using config file: %s\n", foundPath)
+		m.observer.Notify(event.Event{Kind: event.ConfigLoaded, Path: foundPath})
 		
 		// Check for legacy config files and show migration hint
 		if !isXDGPath(foundPath) {
-			m.showMigrationHint(foundPath)
+			m.observer.Notify(event.Event{Kind: event.Warning, Reason: event.ReasonLegacyConfig, Path: foundPath})
 		}
 	} else {
-		fmt.Println("No config file found, using defaults")
+		m.observer.Notify(event.Event{Kind: event.ConfigDefault})
+	}
+
+	// Honour the pre-SHA-256 key name in existing config files
+	if m.v.InConfig("behavior.verify_md5") && !m.v.InConfig("behavior.verify_checksum") {
+		m.v.Set("behavior.verify_checksum", m.v.GetBool("behavior.verify_md5"))
 	}
 
 	// Unmarshal to struct
@@ -87,6 +110,14 @@ func (m *Manager) Load(configPath string) error {
 		return fmt.Errorf("config unmarshal error: %w", err)
 	}
 
+	// Environment variables silently override the file, so make them visible
+	for _, env := range os.Environ() {
+		if name, _, ok := strings.Cut(env, "="); ok && strings.HasPrefix(name, "CTX_TOOL_") {
+			log.Debug("environment override", "variable", name)
+		}
+	}
+	log.Info("configuration resolved", "file", foundPath, "repository", git.RedactURL(m.config.Repository.URL), "branch", m.config.Repository.Branch)
+
 	return nil
 }
 
@@ -95,25 +126,52 @@ func isXDGPath(path string) bool {
 	return path == GetXDGConfigPath()
 }
 
-// showMigrationHint shows a helpful message about migrating to XDG config
-func (m *Manager) showMigrationHint(currentPath string) {
-	xdgPath := GetXDGConfigPath()
-	fmt.Printf("\nNote: You're using a legacy config file location: %s\n", currentPath)
-	fmt.Printf("Consider migrating to the XDG-compliant location: %s\n", xdgPath)
-	fmt.Printf("You can copy your current config:\n")
-	fmt.Printf("  mkdir -p %s && cp %s %s\n", GetXDGConfigDir(), currentPath, xdgPath)
-	fmt.Println()
-}
-
 func (m *Manager) setDefaults() {
 	m.v.SetDefault("version", "1.0")
 	m.v.SetDefault("repository.url", "https://github.com/Wirasm/PRPs-agentic-eng")
 	m.v.SetDefault("repository.branch", "development")
+	m.v.SetDefault("repository.tag", "")
+	// Registered so CTX_TOOL_REPOSITORY_AUTH_* variables are picked up
+	m.v.SetDefault("repository.auth.username", "")
+	m.v.SetDefault("repository.auth.token", "")
+	m.v.SetDefault("repository.auth.token_env", "")
+	m.v.SetDefault("repository.auth.ssh_key", "")
+	m.v.SetDefault("repository.auth.ssh_key_passphrase", "")
+	m.v.SetDefault("repository.auth.credential_helper", true)
 	m.v.SetDefault("tracking.file", ".ctx-tool-tracking.json")
 	m.v.SetDefault("directories.allowed", []string{".claude", "PRPs", "claude_md_files"})
 	m.v.SetDefault("behavior.backup_on_conflict", true)
-	m.v.SetDefault("behavior.verify_md5", true)
+	m.v.SetDefault("behavior.verify_checksum", true)
+	m.v.SetDefault("behavior.hash_algorithm", "sha256")
 	m.v.SetDefault("behavior.clean_empty_dirs", true)
+	m.v.SetDefault("behavior.concurrency", 0)
+	m.v.SetDefault("behavior.install_mode", "copy")
+	m.v.SetDefault("verification.require_signature", false)
+	m.v.SetDefault("verification.keyring", []string{})
+	m.v.SetDefault("merge", []map[string]interface{}{
+		{"path": "**/CLAUDE.md", "mode": "block"},
+		{"path": ".claude/settings.json", "mode": "json"},
+		{"path": ".claude/mcp.json", "mode": "mcp"},
+	})
+}
+
+// Source describes where the value of key came from: the environment
+// variable or config file setting it, or "" for the built-in default
+func (m *Manager) Source(key string) string {
+	env := "CTX_TOOL_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+	if _, ok := os.LookupEnv(env); ok {
+		return env
+	}
+	if m.file != "" && m.v.InConfig(key) {
+		return m.file
+	}
+	return ""
+}
+
+// File returns the config file Load read, or "" when only defaults and
+// environment variables apply
+func (m *Manager) File() string {
+	return m.file
 }
 
 func (m *Manager) GetConfig() *Config {
@@ -0,0 +1,40 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/viper"
+)
+
+// ProjectManifestFile holds per-project settings in the project root
+const ProjectManifestFile = ".ctx-tool-project.yaml"
+
+// ProjectManifest is the per-project file checked into a repository
+// alongside the installed configurations
+type ProjectManifest struct {
+	// Vars are template variables; they override templates.vars from the
+	// user configuration
+	Vars map[string]string `mapstructure:"vars"`
+}
+
+// LoadProjectManifest reads the manifest in dir. A missing file yields an
+// empty manifest. Like all viper keys, variable names are lower-cased.
+func LoadProjectManifest(dir string) (*ProjectManifest, error) {
+	manifest := &ProjectManifest{Vars: map[string]string{}}
+
+	path := filepath.Join(dir, ProjectManifestFile)
+	if !FileExists(path) {
+		return manifest, nil
+	}
+
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("read project manifest %s: %w", path, err)
+	}
+	if err := v.Unmarshal(manifest); err != nil {
+		return nil, fmt.Errorf("unmarshal project manifest %s: %w", path, err)
+	}
+	return manifest, nil
+}
@@ -13,6 +13,9 @@ const (
 	XDGConfigFileName  = "config.yaml"
 	LegacyConfigFile1  = ".ctx-tool.yaml"  // Home directory
 	LegacyConfigFile2  = ".ctx-tool.yaml"  // Current directory
+
+	// LegacyGlobalTrackingFile is where older versions kept global tracking data, relative to home
+	LegacyGlobalTrackingFile = ".ctx-tool-tracking.json"
 )
 
 // GetConfigPaths returns configuration file paths in priority order:
@@ -41,6 +44,25 @@ func GetConfigPaths() []string {
 	return paths
 }
 
+// ShadowedConfigs returns the config files in the search path that exist but
+// are ignored because active takes precedence
+func ShadowedConfigs(active string) []string {
+	var shadowed []string
+	found := false
+	for _, path := range GetConfigPaths() {
+		if !FileExists(path) {
+			continue
+		}
+		if found && path != active {
+			shadowed = append(shadowed, path)
+		}
+		if path == active {
+			found = true
+		}
+	}
+	return shadowed
+}
+
 // GetXDGConfigPath returns the XDG-compliant configuration file path
 func GetXDGConfigPath() string {
 	return filepath.Join(xdg.ConfigHome, AppName, XDGConfigFileName)
@@ -51,6 +73,26 @@ func GetXDGConfigDir() string {
 	return filepath.Join(xdg.ConfigHome, AppName)
 }
 
+// GetHashCachePath returns the location of the file checksum cache
+func GetHashCachePath() string {
+	return filepath.Join(xdg.CacheHome, AppName, "hashes.json")
+}
+
+// GetGlobalTrackingPath returns the tracking file of global installations
+func GetGlobalTrackingPath() string {
+	return filepath.Join(xdg.StateHome, AppName, "global-tracking.json")
+}
+
+// GetRegistryPath returns the file listing every project ctx-tool installed into
+func GetRegistryPath() string {
+	return filepath.Join(xdg.DataHome, AppName, "projects.json")
+}
+
+// GetStorePath returns the content-addressed store of upstream files
+func GetStorePath() string {
+	return filepath.Join(xdg.DataHome, AppName, "store")
+}
+
 // EnsureConfigFile creates the XDG configuration file with default content if it doesn't exist
 func EnsureConfigFile() error {
 	configPath := GetXDGConfigPath()
@@ -121,6 +163,16 @@ version: "1.0"
 repository:
   url: "https://github.com/Wirasm/PRPs-agentic-eng"
   branch: "development"  # Use "main" for stable version
+  # Credentials for private repositories
+  # auth:
+  #   token_env: "GITHUB_TOKEN"  # Environment variable holding an HTTPS token
+  #   ssh_key: "~/.ssh/id_ed25519"
+  #   credential_helper: true    # Ask git's credential helpers when needed
+
+# Signature checks of upstream revisions
+verification:
+  require_signature: false  # Refuse revisions not signed by a key in the keyring
+  keyring: []               # Files with trusted PGP or SSH public keys
 
 # Tracking file configuration  
 tracking:
@@ -136,8 +188,11 @@ directories:
 # Behavior configuration
 behavior:
   backup_on_conflict: true  # Create .backup files when overwriting
-  verify_md5: true          # Check MD5 before overwriting files
+  verify_checksum: true     # Compare checksums before overwriting files
+  hash_algorithm: "sha256"  # Checksum algorithm recorded in the tracking file
   clean_empty_dirs: true    # Remove empty directories on uninstall
+  concurrency: 0            # Files hashed and copied in parallel (0 = number of CPUs)
+  install_mode: "copy"      # copy, hardlink or symlink
 
 # Internationalization configuration
 i18n:
@@ -221,4 +221,27 @@ func TestDefaultConfigContent(t *testing.T) {
 	if !strings.Contains(content, "XDG") {
 		t.Errorf("Default config should contain XDG-related comments")
 	}
-}
\ No newline at end of file
+}
+
+func TestShadowedConfigs(t *testing.T) {
+	home := t.TempDir()
+	cwd := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Chdir(cwd)
+
+	homeConfig := filepath.Join(home, LegacyConfigFile1)
+	cwdConfig := filepath.Join(cwd, LegacyConfigFile2)
+	for _, path := range []string{homeConfig, cwdConfig} {
+		if err := os.WriteFile(path, []byte("version: \"1.0\"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	shadowed := ShadowedConfigs(homeConfig)
+	if len(shadowed) != 1 || shadowed[0] != cwdConfig {
+		t.Errorf("ShadowedConfigs(%s) = %v, want [%s]", homeConfig, shadowed, cwdConfig)
+	}
+	if shadowed := ShadowedConfigs(cwdConfig); len(shadowed) != 0 {
+		t.Errorf("The last config in the search path shadows nothing, got %v", shadowed)
+	}
+}
@@ -0,0 +1,188 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/doodleEsc/ctx-tool/internal/git"
+	"gopkg.in/yaml.v3"
+)
+
+// TrustedSourcesKey lists the repositories the user agreed to install from.
+// It is only read from the user's own config file: a project config or an
+// environment variable could otherwise trust the very source it redirects to.
+const TrustedSourcesKey = "trusted_sources"
+
+// TrustConfigPath returns the config file trusted sources are kept in: the
+// loaded config file, so trusting a source never creates a config that
+// shadows it, or the XDG config when none was loaded. A ./.ctx-tool.yaml
+// found in the current directory belongs to the project and holds no trust,
+// so "" is returned for it unless it was given explicitly.
+func TrustConfigPath(loaded string, explicit bool) string {
+	if loaded == "" {
+		return GetXDGConfigPath()
+	}
+	if !explicit {
+		if cwd, err := os.Getwd(); err == nil && filepath.Clean(loaded) == filepath.Join(cwd, LegacyConfigFile2) {
+			return ""
+		}
+	}
+	return loaded
+}
+
+// LoadTrustedSources reads the trusted source list from the config file at
+// path; a missing file trusts nothing
+func LoadTrustedSources(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read trusted sources: %w", err)
+	}
+
+	var doc struct {
+		TrustedSources []string `yaml:"trusted_sources"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return doc.TrustedSources, nil
+}
+
+// IsTrusted reports whether url is one of sources
+func IsTrusted(sources []string, url string) bool {
+	want := normalizeSource(url)
+	for _, source := range sources {
+		if normalizeSource(source) == want {
+			return true
+		}
+	}
+	return false
+}
+
+// AddTrustedSource appends url to the trusted sources in the config file at
+// path. The file is edited as text, so its layout and comments are kept.
+func AddTrustedSource(path, url string) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("read %s: %w", path, err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	var key, list *yaml.Node
+	if len(doc.Content) > 0 {
+		root := doc.Content[0]
+		if root.Kind != yaml.MappingNode {
+			return fmt.Errorf("%s is not a YAML mapping", path)
+		}
+		for i := 0; i+1 < len(root.Content); i += 2 {
+			if root.Content[i].Value == TrustedSourcesKey {
+				key, list = root.Content[i], root.Content[i+1]
+			}
+		}
+	}
+
+	item := strconv.Quote(git.RedactURL(url))
+	lines := strings.SplitAfter(string(data), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	switch {
+	case key == nil:
+		// Append a new list at the end of the file
+		if len(lines) > 0 && !strings.HasSuffix(lines[len(lines)-1], "\n") {
+			lines[len(lines)-1] += "\n"
+		}
+		lines = append(lines, "\n# Repositories confirmed as trusted sources\n", TrustedSourcesKey+":\n", "  - "+item+"\n")
+	case list.Kind == yaml.SequenceNode && list.Style&yaml.FlowStyle == 0 && len(list.Content) > 0:
+		// Add an item below the last one, with the same indentation
+		last := list.Content[len(list.Content)-1]
+		indent := strings.Repeat(" ", max(last.Column-3, 0))
+		at := last.Line
+		lines = append(lines[:at], append([]string{indent + "- " + item + "\n"}, lines[at:]...)...)
+	case list.Kind == yaml.SequenceNode || key.Line == list.Line:
+		// An empty value or a flow-style list, which may span several lines,
+		// becomes a block list
+		end, trailing := key.Line, ""
+		if list.Kind == yaml.SequenceNode {
+			var ok bool
+			if end, trailing, ok = flowEnd(lines, list); !ok {
+				return fmt.Errorf("%s: %s is not a complete list", path, TrustedSourcesKey)
+			}
+		}
+		block := []string{TrustedSourcesKey + ":" + trailing + "\n"}
+		for _, existing := range list.Content {
+			block = append(block, "  - "+strconv.Quote(existing.Value)+"\n")
+		}
+		block = append(block, "  - "+item+"\n")
+		lines = append(lines[:key.Line-1], append(block, lines[end:]...)...)
+	default:
+		return fmt.Errorf("%s: %s must be a list", path, TrustedSourcesKey)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("create config directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "")), 0644); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	return nil
+}
+
+// flowEnd finds the closing bracket of the flow-style list, returning its
+// line and what follows it there, such as a comment
+func flowEnd(lines []string, list *yaml.Node) (int, string, bool) {
+	depth := 0
+	var quote byte
+	for i := list.Line - 1; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], "\r\n")
+		start := 0
+		if i == list.Line-1 {
+			start = max(list.Column-1, 0)
+		}
+		for j := start; j < len(line); j++ {
+			c := line[j]
+			switch {
+			case quote == '"' && c == '\\':
+				j++
+			case quote != 0:
+				if c == quote {
+					quote = 0
+				}
+			case c == '"' || c == '\'':
+				quote = c
+			case c == '#' && (j == 0 || line[j-1] == ' ' || line[j-1] == '\t'):
+				j = len(line)
+			case c == '[':
+				depth++
+			case c == ']':
+				if depth--; depth == 0 {
+					return i + 1, strings.TrimRight(line[j+1:], " \t"), true
+				}
+			}
+		}
+	}
+	return 0, "", false
+}
+
+// normalizeSource makes equivalent spellings of a repository URL compare
+// equal: credentials, a trailing slash or .git and the host's case don't
+// matter
+func normalizeSource(url string) string {
+	url = strings.TrimSpace(git.RedactURL(url))
+	url = strings.TrimSuffix(strings.TrimSuffix(url, "/"), ".git")
+	if scheme, rest, ok := strings.Cut(url, "://"); ok {
+		host, path, _ := strings.Cut(rest, "/")
+		return strings.ToLower(scheme) + "://" + strings.ToLower(host) + "/" + path
+	}
+	return url
+}
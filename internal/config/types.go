@@ -1,17 +1,40 @@
 package config
 
 type Config struct {
-	Version     string            `mapstructure:"version"`
-	Repository  RepositoryConfig  `mapstructure:"repository"`
-	Tracking    TrackingConfig    `mapstructure:"tracking"`
-	Directories DirectoriesConfig `mapstructure:"directories"`
-	Behavior    BehaviorConfig    `mapstructure:"behavior"`
-	I18n        I18nConfig        `mapstructure:"i18n"`
+	Version      string             `mapstructure:"version"`
+	Repository   RepositoryConfig   `mapstructure:"repository"`
+	Tracking     TrackingConfig     `mapstructure:"tracking"`
+	Directories  DirectoriesConfig  `mapstructure:"directories"`
+	Behavior     BehaviorConfig     `mapstructure:"behavior"`
+	Verification VerificationConfig `mapstructure:"verification"`
+	Templates    TemplatesConfig    `mapstructure:"templates"`
+	Merge        []MergeRule        `mapstructure:"merge"`
+	I18n         I18nConfig         `mapstructure:"i18n"`
 }
 
 type RepositoryConfig struct {
-	URL    string `mapstructure:"url"`
-	Branch string `mapstructure:"branch"`
+	URL    string     `mapstructure:"url"`
+	Branch string     `mapstructure:"branch"`
+	Tag    string     `mapstructure:"tag"` // installed instead of the head of Branch when set
+	Auth   AuthConfig `mapstructure:"auth"`
+}
+
+// AuthConfig holds credentials for private repositories. TokenEnv names an
+// environment variable holding the token, which keeps it out of the file.
+type AuthConfig struct {
+	Username         string `mapstructure:"username"`
+	Token            string `mapstructure:"token"`
+	TokenEnv         string `mapstructure:"token_env"`
+	SSHKey           string `mapstructure:"ssh_key"`
+	SSHKeyPassphrase string `mapstructure:"ssh_key_passphrase"`
+	CredentialHelper bool   `mapstructure:"credential_helper"`
+}
+
+// VerificationConfig controls signature checks of upstream revisions
+type VerificationConfig struct {
+	RequireSignature bool `mapstructure:"require_signature"`
+	// Keyring lists files with the trusted PGP or SSH public keys
+	Keyring []string `mapstructure:"keyring"`
 }
 
 type TrackingConfig struct {
@@ -23,9 +46,23 @@ type DirectoriesConfig struct {
 }
 
 type BehaviorConfig struct {
-	BackupOnConflict bool `mapstructure:"backup_on_conflict"`
-	VerifyMD5        bool `mapstructure:"verify_md5"`
-	CleanEmptyDirs   bool `mapstructure:"clean_empty_dirs"`
+	BackupOnConflict bool   `mapstructure:"backup_on_conflict"`
+	VerifyChecksum   bool   `mapstructure:"verify_checksum"`
+	HashAlgorithm    string `mapstructure:"hash_algorithm"`
+	CleanEmptyDirs   bool   `mapstructure:"clean_empty_dirs"`
+	Concurrency      int    `mapstructure:"concurrency"`
+	InstallMode      string `mapstructure:"install_mode"`
+}
+
+type TemplatesConfig struct {
+	Vars map[string]string `mapstructure:"vars"`
+}
+
+// MergeRule selects how installed files matching Path are merged into
+// existing ones. The first matching rule wins.
+type MergeRule struct {
+	Path string `mapstructure:"path"`
+	Mode string `mapstructure:"mode"`
 }
 
 type I18nConfig struct {
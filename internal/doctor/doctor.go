@@ -0,0 +1,261 @@
+// Package doctor checks the configuration and installations for problems
+// and suggests a command fixing each one.
+package doctor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/doodleEsc/ctx-tool/internal/config"
+	"github.com/doodleEsc/ctx-tool/internal/git"
+	"github.com/doodleEsc/ctx-tool/internal/i18n"
+	"github.com/doodleEsc/ctx-tool/internal/sync"
+	"github.com/doodleEsc/ctx-tool/internal/tracker"
+)
+
+// Check results
+const (
+	OK      = "ok"
+	Warning = "warning"
+	Problem = "problem"
+)
+
+// StaleCloneAge is how old a temporary clone must be before it counts as
+// stray; younger ones may belong to a running install
+const StaleCloneAge = time.Hour
+
+// Check is the result of one check. Message is an i18n key the caller
+// localizes with Data.
+type Check struct {
+	Check   string
+	Status  string
+	Message string
+	Data    map[string]interface{}
+	// Fix is a shell command that fixes the problem
+	Fix string
+}
+
+// Install is a tracking file to inspect and where its files are installed
+type Install struct {
+	TrackingFile string
+	BasePath     string
+	Scope        string
+	// Registered is set for installations listed in the project registry
+	Registered bool
+}
+
+// Config reports which config file was loaded, the files it shadows and
+// the environment variables overriding it. explicit is the file given with
+// --config, if any.
+func Config(active, explicit string) []Check {
+	var checks []Check
+	switch {
+	case explicit != "" && !config.FileExists(explicit):
+		checks = append(checks, Check{Check: "config", Status: Problem, Message: i18n.MsgDoctorConfigMissing,
+			Data: map[string]interface{}{"Path": explicit}, Fix: "ctx-tool doctor --config " + ShellQuote(config.GetXDGConfigPath())})
+	case active == "":
+		checks = append(checks, Check{Check: "config", Status: OK, Message: i18n.MsgDoctorConfigDefault})
+	case explicit == "" && active != config.GetXDGConfigPath():
+		checks = append(checks, Check{Check: "config", Status: Warning, Message: i18n.MsgDoctorConfigLegacy,
+			Data: map[string]interface{}{"Path": active, "XDGPath": config.GetXDGConfigPath()},
+			Fix:  fmt.Sprintf("mkdir -p %s && mv %s %s", ShellQuote(config.GetXDGConfigDir()), ShellQuote(active), ShellQuote(config.GetXDGConfigPath()))})
+	default:
+		checks = append(checks, Check{Check: "config", Status: OK, Message: i18n.MsgDoctorConfigFile, Data: map[string]interface{}{"Path": active}})
+	}
+
+	if explicit == "" {
+		for _, path := range config.ShadowedConfigs(active) {
+			checks = append(checks, Check{Check: "config", Status: Warning, Message: i18n.MsgDoctorConfigShadowed,
+				Data: map[string]interface{}{"Path": path, "Active": active}, Fix: "mv " + ShellQuote(path) + " " + ShellQuote(path+".disabled")})
+		}
+	}
+
+	var overrides []string
+	for _, env := range os.Environ() {
+		if name, _, ok := strings.Cut(env, "="); ok && strings.HasPrefix(name, "CTX_TOOL_") {
+			overrides = append(overrides, name)
+		}
+	}
+	sort.Strings(overrides)
+	for _, name := range overrides {
+		checks = append(checks, Check{Check: "config", Status: OK, Message: i18n.MsgDoctorEnvOverride, Data: map[string]interface{}{"Variable": name}})
+	}
+	return checks
+}
+
+// CheckInstall checks that a tracking file parses, belongs where it is and
+// has no leftover backups next to its files. home is the user's home
+// directory and trackingFile the configured name of project tracking files.
+func CheckInstall(inst Install, home, trackingFile string) []Check {
+	// An empty base path keeps the one stored in the file
+	t := tracker.NewTracker(inst.TrackingFile, inst.Scope, "")
+	if err := t.Load(); err != nil {
+		return []Check{{Check: "tracking", Status: Problem, Message: i18n.MsgDoctorUnreadable,
+			Data: map[string]interface{}{"Path": inst.TrackingFile, "Error": err},
+			Fix:  "mv " + ShellQuote(inst.TrackingFile) + " " + ShellQuote(inst.TrackingFile+".broken") + " && " + reinstallCommand(inst)}}
+	}
+	installation := t.Installation
+
+	var checks []Check
+	if installation.Scope == "global" && home != "" {
+		// Legacy global tracking data lives in the home directory
+		inst.Scope, inst.BasePath = "global", filepath.Join(home, ".claude")
+	}
+	switch {
+	case inst.TrackingFile == config.GetGlobalTrackingPath() && installation.Scope != "global":
+		// Hand the data back to the project it tracks, so its files stay
+		// tracked
+		base := installation.BasePath
+		if !filepath.IsAbs(base) {
+			base = home
+		}
+		projectFile := trackingFile
+		if !filepath.IsAbs(projectFile) {
+			projectFile = filepath.Join(base, projectFile)
+		}
+		return []Check{{Check: "scope", Status: Problem, Message: i18n.MsgDoctorGlobalScope,
+			Data: map[string]interface{}{"Path": inst.TrackingFile, "Scope": installation.Scope},
+			Fix:  "mv " + ShellQuote(inst.TrackingFile) + " " + ShellQuote(projectFile) + " && ctx-tool status --target " + ShellQuote(base)}}
+	case installation.Scope == "project" && home != "" && sameDir(inst.BasePath, home):
+		checks = append(checks, Check{Check: "scope", Status: Warning, Message: i18n.MsgDoctorHomeProject,
+			Data: map[string]interface{}{"Path": inst.TrackingFile},
+			Fix:  "ctx-tool remove --force --target " + ShellQuote(home) + " && ctx-tool add --global --all"})
+	}
+
+	recorded := installation.BasePath
+	if filepath.IsAbs(recorded) && !sameDir(recorded, inst.BasePath) {
+		// Updating records where the installation is now
+		fix := reinstallCommand(inst)
+		if inst.Registered && inst.Scope != "global" {
+			fix = "ctx-tool projects update " + ShellQuote(inst.BasePath)
+		}
+		checks = append(checks, Check{Check: "tracking", Status: Warning, Message: i18n.MsgDoctorBaseMoved,
+			Data: map[string]interface{}{"Path": inst.TrackingFile, "Recorded": recorded, "Base": inst.BasePath}, Fix: fix})
+	} else {
+		checks = append(checks, Check{Check: "tracking", Status: OK, Message: i18n.MsgDoctorTrackingOK,
+			Data: map[string]interface{}{"Path": inst.TrackingFile, "Count": len(installation.Files), "Base": inst.BasePath}})
+	}
+
+	backups, err := sync.FindBackups(inst.BasePath, installedTopDirs(t))
+	if err != nil {
+		return append(checks, Check{Check: "backups", Status: Warning, Message: i18n.MsgDoctorUnreadable,
+			Data: map[string]interface{}{"Path": inst.BasePath, "Error": err}})
+	}
+	if len(backups) > 0 {
+		quoted := make([]string, len(backups))
+		for i, b := range backups {
+			quoted[i] = ShellQuote(filepath.Join(inst.BasePath, b))
+		}
+		checks = append(checks, Check{Check: "backups", Status: Warning, Message: i18n.MsgDoctorBackups,
+			Data: map[string]interface{}{"Count": len(backups), "Base": inst.BasePath, "Files": strings.Join(backups, ", ")},
+			Fix:  "rm " + strings.Join(quoted, " ")})
+	}
+	return checks
+}
+
+// installedTopDirs returns the top-level directories holding tracked files
+func installedTopDirs(t *tracker.Tracker) []string {
+	seen := make(map[string]bool)
+	var dirs []string
+	for _, path := range t.GetTrackedFiles() {
+		top, _, found := strings.Cut(filepath.ToSlash(path), "/")
+		if !found || seen[top] {
+			continue
+		}
+		seen[top] = true
+		dirs = append(dirs, top)
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+// reinstallCommand returns the command that reinstalls an installation
+func reinstallCommand(inst Install) string {
+	if inst.Scope == "global" {
+		return "ctx-tool add --global --all"
+	}
+	return "ctx-tool add --all --target " + ShellQuote(inst.BasePath)
+}
+
+// TempClones looks for clones left behind by interrupted installs
+func TempClones(age time.Duration) []Check {
+	stale, err := git.StaleClones(age)
+	if err != nil || len(stale) == 0 {
+		return nil
+	}
+	quoted := make([]string, len(stale))
+	for i, dir := range stale {
+		quoted[i] = ShellQuote(dir)
+	}
+	return []Check{{Check: "temp_clones", Status: Warning, Message: i18n.MsgDoctorTempClones,
+		Data: map[string]interface{}{"Count": len(stale), "Dirs": strings.Join(stale, ", ")},
+		Fix:  "rm -rf " + strings.Join(quoted, " ")}}
+}
+
+// Permissions checks that files can be created in each of dirs
+func Permissions(dirs []string) []Check {
+	var checks []Check
+	seen := make(map[string]bool)
+	for _, dir := range dirs {
+		if seen[dir] {
+			continue
+		}
+		seen[dir] = true
+		existing, err := writableDir(dir)
+		if err != nil {
+			checks = append(checks, Check{Check: "permissions", Status: Problem, Message: i18n.MsgDoctorNotWritable,
+				Data: map[string]interface{}{"Dir": existing, "Error": err}, Fix: "chmod u+w " + ShellQuote(existing)})
+		}
+	}
+	if len(checks) == 0 {
+		checks = append(checks, Check{Check: "permissions", Status: OK, Message: i18n.MsgDoctorWritable})
+	}
+	return checks
+}
+
+// writableDir checks that a file can be created in dir or, if dir doesn't
+// exist yet, in its closest existing parent, which it returns
+func writableDir(dir string) (string, error) {
+	for {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+
+	f, err := os.CreateTemp(dir, ".ctx-tool-doctor-*")
+	if err != nil {
+		return dir, err
+	}
+	f.Close()
+	return dir, os.Remove(f.Name())
+}
+
+// sameDir reports whether a and b name the same directory
+func sameDir(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	return absA == absB
+}
+
+var shellSafe = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./~-]+$`)
+
+// ShellQuote quotes s for a POSIX shell when it needs it
+func ShellQuote(s string) string {
+	if shellSafe.MatchString(s) {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
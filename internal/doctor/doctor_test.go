@@ -0,0 +1,154 @@
+package doctor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/adrg/xdg"
+	"github.com/doodleEsc/ctx-tool/internal/config"
+	"github.com/doodleEsc/ctx-tool/internal/i18n"
+	"github.com/doodleEsc/ctx-tool/internal/tracker"
+)
+
+func TestConfigShadowed(t *testing.T) {
+	home := t.TempDir()
+	cwd := t.TempDir()
+	t.Cleanup(xdg.Reload)
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	xdg.Reload()
+	t.Chdir(cwd)
+
+	homeConfig := filepath.Join(home, config.LegacyConfigFile1)
+	cwdConfig := filepath.Join(cwd, config.LegacyConfigFile2)
+	for _, path := range []string{homeConfig, cwdConfig} {
+		if err := os.WriteFile(path, []byte("version: \"1.0\"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	checks := Config(homeConfig, "")
+	var legacy, shadowed *Check
+	for i, c := range checks {
+		switch c.Message {
+		case i18n.MsgDoctorConfigLegacy:
+			legacy = &checks[i]
+		case i18n.MsgDoctorConfigShadowed:
+			shadowed = &checks[i]
+		}
+	}
+	if legacy == nil || legacy.Status != Warning {
+		t.Fatalf("Expected a legacy config warning, got %+v", checks)
+	}
+	if want := "mkdir -p " + config.GetXDGConfigDir() + " && mv " + homeConfig + " " + config.GetXDGConfigPath(); legacy.Fix != want {
+		t.Errorf("Legacy fix = %q, want %q", legacy.Fix, want)
+	}
+	if shadowed == nil || shadowed.Status != Warning || shadowed.Data["Path"] != cwdConfig {
+		t.Fatalf("Expected %s to be reported as shadowed, got %+v", cwdConfig, checks)
+	}
+	if want := "mv " + cwdConfig + " " + cwdConfig + ".disabled"; shadowed.Fix != want {
+		t.Errorf("Shadowed fix = %q, want %q", shadowed.Fix, want)
+	}
+
+	// An explicit --config shadows nothing
+	for _, c := range Config(homeConfig, homeConfig) {
+		if c.Message == i18n.MsgDoctorConfigShadowed || c.Message == i18n.MsgDoctorConfigLegacy {
+			t.Errorf("Unexpected check for an explicit config: %+v", c)
+		}
+	}
+}
+
+func TestTempClones(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+
+	stale := filepath.Join(tmp, "ctx-tool-stale")
+	fresh := filepath.Join(tmp, "ctx-tool-fresh")
+	other := filepath.Join(tmp, "ctx-tool-other")
+	for _, dir := range []string{stale, fresh} {
+		if err := os.MkdirAll(filepath.Join(dir, ".git"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(other, 0755); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * StaleCloneAge)
+	for _, dir := range []string{stale, other} {
+		if err := os.Chtimes(dir, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	checks := TempClones(StaleCloneAge)
+	if len(checks) != 1 {
+		t.Fatalf("TempClones() = %+v, want one warning", checks)
+	}
+	if checks[0].Status != Warning || checks[0].Data["Dirs"] != stale {
+		t.Errorf("Expected only %s to be stale, got %+v", stale, checks[0])
+	}
+	if want := "rm -rf " + stale; checks[0].Fix != want {
+		t.Errorf("Fix = %q, want %q", checks[0].Fix, want)
+	}
+}
+
+func TestCheckInstallMisScopedGlobal(t *testing.T) {
+	home := t.TempDir()
+	t.Cleanup(xdg.Reload)
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_STATE_HOME", filepath.Join(home, ".local", "state"))
+	xdg.Reload()
+
+	// Project tracking data of the home directory migrated as global data
+	global := config.GetGlobalTrackingPath()
+	if err := os.MkdirAll(filepath.Dir(global), 0755); err != nil {
+		t.Fatal(err)
+	}
+	tr := tracker.NewTracker(global, "project", home)
+	tr.RecordEntry(tracker.FileEntry{Path: ".claude/CLAUDE.md", Hash: "abc"})
+	if err := tr.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	checks := CheckInstall(Install{TrackingFile: global, BasePath: filepath.Join(home, ".claude"), Scope: "global"}, home, ".ctx-tool-tracking.json")
+	if len(checks) != 1 || checks[0].Status != Problem || checks[0].Message != i18n.MsgDoctorGlobalScope {
+		t.Fatalf("CheckInstall() = %+v, want a scope problem", checks)
+	}
+	// The fix hands the data back to the project instead of discarding it
+	want := "mv " + global + " " + filepath.Join(home, ".ctx-tool-tracking.json") + " && ctx-tool status --target " + home
+	if checks[0].Fix != want {
+		t.Errorf("Fix = %q, want %q", checks[0].Fix, want)
+	}
+}
+
+func TestCheckInstallHomeProject(t *testing.T) {
+	home := t.TempDir()
+	path := filepath.Join(home, ".ctx-tool-tracking.json")
+	tr := tracker.NewTracker(path, "project", home)
+	if err := tr.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	checks := CheckInstall(Install{TrackingFile: path, BasePath: home, Scope: "project"}, home, ".ctx-tool-tracking.json")
+	if len(checks) != 2 || checks[0].Message != i18n.MsgDoctorHomeProject || checks[1].Status != OK {
+		t.Fatalf("CheckInstall() = %+v, want a home project warning and an intact tracking file", checks)
+	}
+	if want := "ctx-tool remove --force --target " + home + " && ctx-tool add --global --all"; checks[0].Fix != want {
+		t.Errorf("Fix = %q, want %q", checks[0].Fix, want)
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := map[string]string{
+		"/home/me/.claude": "/home/me/.claude",
+		"/tmp/my dir":      "'/tmp/my dir'",
+		"it's":             `'it'\''s'`,
+	}
+	for in, want := range tests {
+		if got := ShellQuote(in); got != want {
+			t.Errorf("ShellQuote(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
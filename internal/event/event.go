@@ -0,0 +1,130 @@
+package event
+
+// Kind identifies what an Event reports
+type Kind string
+
+const (
+	// Configuration resolution
+	ConfigLoaded  Kind = "config_loaded"
+	ConfigCreated Kind = "config_created"
+	ConfigDefault Kind = "config_default"
+
+	// Repository cloning
+	CloneStarted  Kind = "clone_started"
+	CloneProgress Kind = "clone_progress"
+	CloneFinished Kind = "clone_finished"
+	// SignatureVerified reports the trusted signer of the cloned revision
+	SignatureVerified Kind = "signature_verified"
+
+	// File operations
+	DirectoryStarted Kind = "directory_started"
+	FilePlanned      Kind = "file_planned"
+	FileSkipped      Kind = "file_skipped"
+	FileInstalled    Kind = "file_installed"
+	FileBackedUp     Kind = "file_backed_up"
+	FileRemoved      Kind = "file_removed"
+	FileMoved        Kind = "file_moved"
+	FileDetached     Kind = "file_detached"
+	DirectoryRemoved Kind = "directory_removed"
+
+	// Problems that don't stop the operation
+	Warning Kind = "warning"
+)
+
+// Reasons attached to FileSkipped and Warning events
+const (
+	ReasonIdentical    = "identical"
+	ReasonModified     = "modified"
+	ReasonMissing      = "missing"
+	ReasonDirNotFound  = "dir_not_found"
+	ReasonRemoveFailed = "remove_failed"
+	ReasonLegacyConfig = "legacy_config"
+	ReasonConfigCreate = "config_create_failed"
+	ReasonMoveConflict = "move_conflict"
+	ReasonServerExists = "mcp_server_exists"
+	ReasonUnsetVar     = "unset_variable"
+	// ReasonWholeFileEdited marks an edited file installed whole by an older
+	// version that was backed up before being merged into from scratch
+	ReasonWholeFileEdited = "whole_file_edited"
+	// ReasonSharedLinkEdited marks a file edited in place through a hardlink
+	// other projects share, so their copies changed as well
+	ReasonSharedLinkEdited = "shared_link_edited"
+	// ReasonRegisterFailed marks a symlinked installation missing from the
+	// project registry, whose store objects a prune would remove
+	ReasonRegisterFailed = "register_failed"
+	// ReasonForgottenSymlinks marks a project dropped from the registry that
+	// may still have symlinks into the store, so the store wasn't pruned
+	ReasonForgottenSymlinks = "forgotten_symlinks"
+)
+
+// Event describes a single step of a clone, sync or removal
+type Event struct {
+	Kind Kind
+
+	// Path is the file the event refers to, relative to the target directory
+	Path string
+	// Dir is the directory the event refers to
+	Dir string
+	// Backup is the path of the backup created for Path
+	Backup string
+	// From is the previous location of a moved file
+	From string
+	// Reason explains FileSkipped and Warning events
+	Reason string
+	// Message carries free-form text such as clone progress lines
+	Message string
+	// Name is the MCP server and Variable the environment variable a
+	// warning refers to
+	Name     string
+	Variable string
+	// Err is the underlying error of a Warning
+	Err error
+
+	// Hash is the checksum of the installed content and PreviousHash the
+	// checksum of the file it replaced, both computed with Algorithm
+	Hash         string
+	PreviousHash string
+	Algorithm    string
+
+	// Repository, Branch and Revision describe the clone source
+	Repository string
+	Branch     string
+	Revision   string
+	// Signer and Key identify who signed a verified revision
+	Signer string
+	Key    string
+}
+
+// Observer receives events
+type Observer interface {
+	Notify(Event)
+}
+
+// ObserverFunc adapts a function to the Observer interface
+type ObserverFunc func(Event)
+
+// Notify calls f(e)
+func (f ObserverFunc) Notify(e Event) {
+	f(e)
+}
+
+// Discard is an Observer that ignores all events
+var Discard Observer = ObserverFunc(func(Event) {})
+
+// Multi fans events out to several observers in order
+type Multi []Observer
+
+// Notify forwards e to every observer
+func (m Multi) Notify(e Event) {
+	for _, o := range m {
+		o.Notify(e)
+	}
+}
+
+// OrDiscard returns o, or Discard if o is nil
+func OrDiscard(o Observer) Observer {
+	if o == nil {
+		return Discard
+	}
+	return o
+}
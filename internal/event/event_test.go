@@ -0,0 +1,46 @@
+package event
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestProgressWriterSplitsLines(t *testing.T) {
+	var lines []string
+	w := NewProgressWriter(ObserverFunc(func(e Event) {
+		if e.Kind != CloneProgress {
+			t.Errorf("Unexpected event kind %s", e.Kind)
+		}
+		lines = append(lines, e.Message)
+	}))
+
+	w.Write([]byte("Counting objects: 10% (1/10)\rCounting objects: 100% (10/10)\r\nCompress"))
+	w.Write([]byte("ing objects: 100%\n\nTotal 10"))
+	w.Flush()
+
+	want := []string{
+		"Counting objects: 10% (1/10)",
+		"Counting objects: 100% (10/10)",
+		"Compressing objects: 100%",
+		"Total 10",
+	}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("Got lines %q, want %q", lines, want)
+	}
+}
+
+func TestMultiNotifiesAllObservers(t *testing.T) {
+	var first, second []Kind
+	m := Multi{
+		ObserverFunc(func(e Event) { first = append(first, e.Kind) }),
+		ObserverFunc(func(e Event) { second = append(second, e.Kind) }),
+	}
+
+	m.Notify(Event{Kind: FileInstalled})
+	m.Notify(Event{Kind: FileRemoved})
+
+	want := []Kind{FileInstalled, FileRemoved}
+	if !reflect.DeepEqual(first, want) || !reflect.DeepEqual(second, want) {
+		t.Errorf("Observers got %v and %v, want %v", first, second, want)
+	}
+}
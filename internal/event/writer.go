@@ -0,0 +1,53 @@
+package event
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+)
+
+// ProgressWriter turns the raw progress stream of a git clone into
+// CloneProgress events, one per line. Carriage returns used by git to redraw
+// a line are treated as line breaks.
+type ProgressWriter struct {
+	observer Observer
+	mu       sync.Mutex
+	buf      bytes.Buffer
+}
+
+// NewProgressWriter creates a writer that notifies o for each progress line
+func NewProgressWriter(o Observer) *ProgressWriter {
+	return &ProgressWriter{observer: OrDiscard(o)}
+}
+
+// Write buffers p and emits an event for every complete line
+func (w *ProgressWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf.Write(p)
+	for {
+		data := w.buf.Bytes()
+		i := bytes.IndexAny(data, "\r\n")
+		if i < 0 {
+			break
+		}
+		line := strings.TrimSpace(string(data[:i]))
+		w.buf.Next(i + 1)
+		if line != "" {
+			w.observer.Notify(Event{Kind: CloneProgress, Message: line})
+		}
+	}
+	return len(p), nil
+}
+
+// Flush emits any buffered partial line
+func (w *ProgressWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if line := strings.TrimSpace(w.buf.String()); line != "" {
+		w.observer.Notify(Event{Kind: CloneProgress, Message: line})
+	}
+	w.buf.Reset()
+}
@@ -0,0 +1,197 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/doodleEsc/ctx-tool/internal/logging"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
+)
+
+// Auth holds the credentials used to reach a private repository
+type Auth struct {
+	// Username and Token authenticate HTTP(S) remotes
+	Username string
+	Token    string
+	// SSHKey is a private key file for SSH remotes; without it the SSH
+	// agent or a default key in ~/.ssh is used
+	SSHKey           string
+	SSHKeyPassphrase string
+	// CredentialHelper asks git's credential helpers when an HTTP(S)
+	// remote requires authentication and no token is set
+	CredentialHelper bool
+}
+
+// defaultKeys are tried in order when neither a key nor an agent is available
+var defaultKeys = []string{"id_ed25519", "id_ecdsa", "id_rsa"}
+
+// authMethod returns how to authenticate to the remote at rawURL, or nil to
+// try anonymously
+func (a Auth) authMethod(rawURL string) (transport.AuthMethod, error) {
+	ep, err := transport.NewEndpoint(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	switch ep.Protocol {
+	case "ssh":
+		user := ep.User
+		if user == "" {
+			user = ssh.DefaultUsername
+		}
+		if a.SSHKey != "" {
+			return ssh.NewPublicKeysFromFile(user, expandHome(a.SSHKey), a.SSHKeyPassphrase)
+		}
+		if os.Getenv("SSH_AUTH_SOCK") != "" {
+			return ssh.NewSSHAgentAuth(user)
+		}
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, nil
+		}
+		for _, name := range defaultKeys {
+			key := filepath.Join(home, ".ssh", name)
+			if _, err := os.Stat(key); err == nil {
+				return ssh.NewPublicKeysFromFile(user, key, a.SSHKeyPassphrase)
+			}
+		}
+	case "http", "https":
+		if a.Token != "" {
+			user := a.Username
+			if user == "" {
+				user = ep.User
+			}
+			if user == "" {
+				// Hosts ignore the name for token auth but require one
+				user = "git"
+			}
+			return &http.BasicAuth{Username: user, Password: a.Token}, nil
+		}
+	}
+	return nil, nil
+}
+
+// withAuth runs op with the credentials for rawURL. When an HTTP(S) remote
+// turns out to require authentication and none was configured, op is
+// retried once with credentials from git's credential helpers.
+func (a Auth) withAuth(rawURL string, op func(transport.AuthMethod) error) error {
+	auth, err := a.authMethod(rawURL)
+	if err != nil {
+		return err
+	}
+
+	err = op(auth)
+	if err == nil || auth != nil || !a.CredentialHelper || !errors.Is(err, transport.ErrAuthenticationRequired) {
+		return err
+	}
+
+	creds, ok := credentialFill(rawURL)
+	if !ok {
+		return err
+	}
+	logging.Component("git").Debug("using credentials from git credential helper", "url", RedactURL(rawURL))
+	return op(creds)
+}
+
+// credentialFill asks `git credential fill` for the credentials of an
+// HTTP(S) URL without ever prompting
+func credentialFill(rawURL string) (*http.BasicAuth, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, false
+	}
+
+	var input bytes.Buffer
+	input.WriteString("protocol=" + u.Scheme + "\n")
+	input.WriteString("host=" + u.Host + "\n")
+	if path := strings.TrimPrefix(u.Path, "/"); path != "" {
+		input.WriteString("path=" + path + "\n")
+	}
+	if u.User != nil && u.User.Username() != "" {
+		input.WriteString("username=" + u.User.Username() + "\n")
+	}
+	input.WriteString("\n")
+
+	cmd := exec.Command("git", "credential", "fill")
+	cmd.Stdin = &input
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GIT_ASKPASS=", "SSH_ASKPASS=")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, false
+	}
+
+	creds := &http.BasicAuth{}
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		key, value, _ := strings.Cut(scanner.Text(), "=")
+		switch key {
+		case "username":
+			creds.Username = value
+		case "password":
+			creds.Password = value
+		}
+	}
+	return creds, creds.Password != ""
+}
+
+// RedactURL removes credentials from an HTTP(S) URL so it can be logged or
+// stored. SSH user names aren't secret and are kept.
+func RedactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.User == nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return rawURL
+	}
+	u.User = nil
+	return u.String()
+}
+
+// SameHost reports whether two remote URLs point at the same host
+func SameHost(a, b string) bool {
+	epA, errA := transport.NewEndpoint(a)
+	epB, errB := transport.NewEndpoint(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+	return epA.Protocol == epB.Protocol && strings.EqualFold(epA.Host, epB.Host) && epA.Port == epB.Port
+}
+
+// redactedError hides the credentials embedded in a URL from an error
+// message while keeping the error chain intact
+type redactedError struct {
+	err    error
+	secret string
+}
+
+func (e *redactedError) Error() string {
+	return strings.ReplaceAll(e.err.Error(), e.secret, "")
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+// redactError strips the user info of rawURL from err's message
+func redactError(err error, rawURL string) error {
+	u, parseErr := url.Parse(rawURL)
+	if err == nil || parseErr != nil || u.User == nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return err
+	}
+	return &redactedError{err: err, secret: u.User.String() + "@"}
+}
+
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[1:])
+		}
+	}
+	return path
+}
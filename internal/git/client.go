@@ -3,57 +3,101 @@ package git
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"time"
 
-	"github.com/doodleEsc/ctx-tool/internal/i18n"
+	"github.com/doodleEsc/ctx-tool/internal/apperr"
+	"github.com/doodleEsc/ctx-tool/internal/event"
+	"github.com/doodleEsc/ctx-tool/internal/logging"
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
 )
 
+// TempClonePattern names the temporary directories clones are made in
+const TempClonePattern = "ctx-tool-*"
+
 type Client struct {
-	repoURL string
-	branch  string
+	repoURL  string
+	branch   string
+	tag      string
+	pinned   string
+	revision string
+	auth     Auth
+	observer event.Observer
 }
 
 func NewClient(repoURL, branch string) *Client {
 	return &Client{
-		repoURL: repoURL,
-		branch:  branch,
+		repoURL:  repoURL,
+		branch:   branch,
+		observer: event.Discard,
 	}
 }
 
+// SetAuth sets the credentials used to reach the repository
+func (c *Client) SetAuth(auth Auth) {
+	c.auth = auth
+}
+
+// SetTag makes clones check out tag instead of the head of the branch
+func (c *Client) SetTag(tag string) {
+	c.tag = tag
+}
+
+// SetRevision makes clones check out the given commit of the branch or
+// tag, e.g. to restore files from the revision they were installed from
+func (c *Client) SetRevision(revision string) {
+	c.pinned = revision
+}
+
+// SetObserver sets the observer notified about clone progress
+func (c *Client) SetObserver(o event.Observer) {
+	c.observer = event.OrDiscard(o)
+}
+
 // CloneToTemp clones the repository to a temporary directory
 func (c *Client) CloneToTemp() (string, error) {
 	// Create temp directory
-	tempDir, err := os.MkdirTemp("", "ctx-tool-*")
+	tempDir, err := os.MkdirTemp("", TempClonePattern)
 	if err != nil {
 		return "", fmt.Errorf("create temp dir: %w", err)
 	}
 
-	fmt.Printf("%s\n", i18n.Tf(i18n.MsgCloningRepository, map[string]interface{}{"Repo": c.repoURL, "Branch": c.branch}))
-
-	// Clone with progress output
-	_, err = git.PlainClone(tempDir, false, &git.CloneOptions{
-		URL:           c.repoURL,
-		ReferenceName: plumbing.ReferenceName(fmt.Sprintf("refs/heads/%s", c.branch)),
-		SingleBranch:  true,
-		Depth:         1, // Shallow clone for speed
-		Progress:      os.Stdout,
-	})
-
-	if err != nil {
+	if err := c.clone(tempDir); err != nil {
 		// Clean up temp directory on error
 		os.RemoveAll(tempDir)
-		return "", fmt.Errorf("clone repository: %w", err)
+		return "", err
 	}
 
-	fmt.Printf("%s\n", i18n.Tf(i18n.MsgRepositoryCloned, map[string]interface{}{"Path": tempDir}))
 	return tempDir, nil
 }
 
+// StaleClones returns temporary clone directories last modified more than
+// age ago. They are left behind when a process is killed mid-install.
+func StaleClones(age time.Duration) ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(os.TempDir(), TempClonePattern))
+	if err != nil {
+		return nil, err
+	}
+
+	var stale []string
+	for _, dir := range matches {
+		info, err := os.Stat(dir)
+		if err != nil || !info.IsDir() || time.Since(info.ModTime()) < age {
+			continue
+		}
+		// Only directories holding a clone, not other ctx-tool temp files
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err != nil {
+			continue
+		}
+		stale = append(stale, dir)
+	}
+	return stale, nil
+}
+
 // CloneToDirectory clones the repository to a specific directory
 func (c *Client) CloneToDirectory(targetDir string) error {
-	fmt.Printf("%s\n", i18n.Tf(i18n.MsgCloningRepository, map[string]interface{}{"Repo": c.repoURL, "Branch": c.branch}))
-
 	// Ensure directory doesn't exist or is empty
 	if _, err := os.Stat(targetDir); !os.IsNotExist(err) {
 		// Directory exists, check if it's empty
@@ -66,19 +110,90 @@ func (c *Client) CloneToDirectory(targetDir string) error {
 		}
 	}
 
-	// Clone with progress output
-	_, err := git.PlainClone(targetDir, false, &git.CloneOptions{
-		URL:           c.repoURL,
-		ReferenceName: plumbing.ReferenceName(fmt.Sprintf("refs/heads/%s", c.branch)),
-		SingleBranch:  true,
-		Depth:         1, // Shallow clone for speed
-		Progress:      os.Stdout,
+	return c.clone(targetDir)
+}
+
+// clone performs a shallow single-branch clone into dir, reporting progress
+// to the observer
+func (c *Client) clone(dir string) error {
+	log := logging.Component("git")
+	displayURL := RedactURL(c.repoURL)
+	log.Info("cloning repository", "url", displayURL, "branch", c.branch, "dir", dir)
+	start := time.Now()
+
+	c.observer.Notify(event.Event{Kind: event.CloneStarted, Repository: displayURL, Branch: c.branch})
+
+	var repo *git.Repository
+	err := c.auth.withAuth(c.repoURL, func(auth transport.AuthMethod) error {
+		progress := event.NewProgressWriter(c.observer)
+		defer progress.Flush()
+
+		opts := &git.CloneOptions{
+			URL:           c.repoURL,
+			Auth:          auth,
+			ReferenceName: c.reference(),
+			SingleBranch:  true,
+			Depth:         1, // Shallow clone for speed
+			Progress:      progress,
+		}
+		if c.pinned != "" {
+			// The pinned commit may be behind the head
+			opts.Depth = 0
+		}
+
+		var err error
+		repo, err = git.PlainClone(dir, false, opts)
+		return err
 	})
 
 	if err != nil {
-		return fmt.Errorf("clone repository: %w", err)
+		err = redactError(err, c.repoURL)
+		log.Debug("clone failed", "url", displayURL, "duration", time.Since(start).Round(time.Millisecond), "error", err)
+		return fmt.Errorf("clone repository: %w", classifyCloneError(err))
+	}
+
+	if c.pinned != "" {
+		if err := checkout(repo, c.pinned); err != nil {
+			return fmt.Errorf("%w in %s", err, displayURL)
+		}
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return fmt.Errorf("resolve HEAD: %w", err)
 	}
+	c.revision = head.Hash().String()
+	log.Info("clone finished", "revision", c.revision, "duration", time.Since(start).Round(time.Millisecond))
 
-	fmt.Printf("%s\n", i18n.T(i18n.MsgCloneSuccess))
+	c.observer.Notify(event.Event{Kind: event.CloneFinished, Repository: displayURL, Branch: c.branch, Revision: c.revision, Dir: dir})
 	return nil
 }
+
+// checkout detaches the worktree of repo at revision
+func checkout(repo *git.Repository, revision string) error {
+	hash := plumbing.NewHash(revision)
+	if _, err := repo.CommitObject(hash); err != nil {
+		return apperr.New(apperr.NotFound, "revision %s not found", revision)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("open worktree: %w", err)
+	}
+	if err := wt.Checkout(&git.CheckoutOptions{Hash: hash}); err != nil {
+		return fmt.Errorf("check out %s: %w", revision, err)
+	}
+	return nil
+}
+
+// reference returns the reference clones check out
+func (c *Client) reference() plumbing.ReferenceName {
+	if c.tag != "" {
+		return plumbing.NewTagReferenceName(c.tag)
+	}
+	return plumbing.NewBranchReferenceName(c.branch)
+}
+
+// Revision returns the commit hash checked out by the last clone
+func (c *Client) Revision() string {
+	return c.revision
+}
@@ -0,0 +1,48 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/doodleEsc/ctx-tool/internal/apperr"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestCloneAtRevision(t *testing.T) {
+	dir, repo := commitRepo(t, &git.CommitOptions{})
+	first, _ := repo.Head()
+
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("second"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	wt, _ := repo.Worktree()
+	wt.Add("README.md")
+	if _, err := wt.Commit("second", &git.CommitOptions{Author: &object.Signature{Name: "t", Email: "t@t", When: time.Now()}}); err != nil {
+		t.Fatal(err)
+	}
+	head, _ := repo.Head()
+	branch := head.Name().Short()
+
+	client := NewClient("file://"+dir, branch)
+	client.SetRevision(first.Hash().String())
+	clone, err := client.CloneToTemp()
+	if err != nil {
+		t.Fatalf("CloneToTemp failed: %v", err)
+	}
+	defer os.RemoveAll(clone)
+
+	if client.Revision() != first.Hash().String() {
+		t.Errorf("Revision() = %s, want %s", client.Revision(), first.Hash())
+	}
+	if content, _ := os.ReadFile(filepath.Join(clone, "README.md")); string(content) != "signed" {
+		t.Errorf("Checked out %q, want the first commit's content", content)
+	}
+
+	client.SetRevision("0123456789012345678901234567890123456789")
+	if _, err := client.CloneToTemp(); apperr.KindOf(err) != apperr.NotFound {
+		t.Errorf("Expected a not found error for an unknown revision, got %v", err)
+	}
+}
@@ -0,0 +1,47 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/doodleEsc/ctx-tool/internal/apperr"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+// classifyCloneError tags a clone error with the kind of failure so callers
+// can tell an unreachable upstream from bad credentials or a wrong branch
+func classifyCloneError(err error) error {
+	switch {
+	case errors.Is(err, transport.ErrAuthenticationRequired),
+		errors.Is(err, transport.ErrAuthorizationFailed),
+		errors.Is(err, transport.ErrInvalidAuthMethod):
+		return apperr.Wrap(apperr.Auth, err)
+	case errors.Is(err, transport.ErrRepositoryNotFound),
+		errors.Is(err, transport.ErrEmptyRemoteRepository),
+		errors.Is(err, git.NoMatchingRefSpecError{}):
+		return apperr.Wrap(apperr.NotFound, err)
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded) {
+		return apperr.Wrap(apperr.Network, err)
+	}
+
+	// Some transports only report failures as text
+	msg := strings.ToLower(err.Error())
+	for _, hint := range []string{"ssh: handshake failed", "unable to authenticate", "permission denied (publickey"} {
+		if strings.Contains(msg, hint) {
+			return apperr.Wrap(apperr.Auth, err)
+		}
+	}
+	for _, hint := range []string{"connection refused", "no such host", "i/o timeout", "network is unreachable", "connection reset", "tls handshake"} {
+		if strings.Contains(msg, hint) {
+			return apperr.Wrap(apperr.Network, err)
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,42 @@
+package git
+
+import (
+	"fmt"
+
+	"github.com/doodleEsc/ctx-tool/internal/apperr"
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// RemoteRevision returns the object the remote branch, or the tag when one
+// is set, points to without cloning the repository
+func (c *Client) RemoteRevision() (string, error) {
+	remote := git.NewRemote(memory.NewStorage(), &gitconfig.RemoteConfig{
+		Name: "origin",
+		URLs: []string{c.repoURL},
+	})
+
+	var refs []*plumbing.Reference
+	err := c.auth.withAuth(c.repoURL, func(auth transport.AuthMethod) error {
+		var err error
+		refs, err = remote.List(&git.ListOptions{Auth: auth})
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("list remote references: %w", classifyCloneError(redactError(err, c.repoURL)))
+	}
+
+	name := c.reference()
+	for _, ref := range refs {
+		if ref.Name() == name {
+			return ref.Hash().String(), nil
+		}
+	}
+	if c.tag != "" {
+		return "", apperr.New(apperr.NotFound, "tag %s not found in %s", c.tag, RedactURL(c.repoURL))
+	}
+	return "", apperr.New(apperr.NotFound, "branch %s not found in %s", c.branch, RedactURL(c.repoURL))
+}
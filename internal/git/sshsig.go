@@ -0,0 +1,91 @@
+package git
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/pem"
+	"fmt"
+	"hash"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// SSH signatures follow the SSHSIG format of ssh-keygen -Y sign, which git
+// uses with gpg.format=ssh. Git signs in the "git" namespace.
+const (
+	sshSigMagic     = "SSHSIG"
+	sshSigNamespace = "git"
+)
+
+// sshSigBlob is the decoded armored signature, after the magic preamble
+type sshSigBlob struct {
+	Version       uint32
+	PublicKey     []byte
+	Namespace     string
+	Reserved      string
+	HashAlgorithm string
+	Signature     []byte
+}
+
+// sshSigSigned is what the key actually signs, after the magic preamble
+type sshSigSigned struct {
+	Namespace     string
+	Reserved      string
+	HashAlgorithm string
+	Hash          []byte
+}
+
+// verifySSHSignature checks an armored SSH signature over message and
+// returns the key that made it
+func verifySSHSignature(message []byte, armored string) (ssh.PublicKey, error) {
+	block, _ := pem.Decode([]byte(armored))
+	if block == nil || block.Type != "SSH SIGNATURE" {
+		return nil, fmt.Errorf("malformed SSH signature")
+	}
+	if !bytes.HasPrefix(block.Bytes, []byte(sshSigMagic)) {
+		return nil, fmt.Errorf("malformed SSH signature: missing %s preamble", sshSigMagic)
+	}
+
+	var blob sshSigBlob
+	if err := ssh.Unmarshal(block.Bytes[len(sshSigMagic):], &blob); err != nil {
+		return nil, fmt.Errorf("malformed SSH signature: %w", err)
+	}
+	if blob.Version != 1 {
+		return nil, fmt.Errorf("unsupported SSH signature version %d", blob.Version)
+	}
+	if blob.Namespace != sshSigNamespace {
+		return nil, fmt.Errorf("SSH signature is for namespace %q, not %q", blob.Namespace, sshSigNamespace)
+	}
+
+	pub, err := ssh.ParsePublicKey(blob.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("malformed SSH signature key: %w", err)
+	}
+	var sig ssh.Signature
+	if err := ssh.Unmarshal(blob.Signature, &sig); err != nil {
+		return nil, fmt.Errorf("malformed SSH signature: %w", err)
+	}
+
+	var h hash.Hash
+	switch blob.HashAlgorithm {
+	case "sha256":
+		h = sha256.New()
+	case "sha512":
+		h = sha512.New()
+	default:
+		return nil, fmt.Errorf("unsupported SSH signature hash %q", blob.HashAlgorithm)
+	}
+	h.Write(message)
+
+	signed := append([]byte(sshSigMagic), ssh.Marshal(sshSigSigned{
+		Namespace:     blob.Namespace,
+		Reserved:      blob.Reserved,
+		HashAlgorithm: blob.HashAlgorithm,
+		Hash:          h.Sum(nil),
+	})...)
+	if err := pub.Verify(signed, &sig); err != nil {
+		return nil, fmt.Errorf("bad SSH signature: %w", err)
+	}
+	return pub, nil
+}
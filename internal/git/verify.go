@@ -0,0 +1,218 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/doodleEsc/ctx-tool/internal/apperr"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"golang.org/x/crypto/ssh"
+)
+
+// Signature formats
+const (
+	FormatPGP = "pgp"
+	FormatSSH = "ssh"
+)
+
+const (
+	pgpKeyBlockBegin = "-----BEGIN PGP PUBLIC KEY BLOCK-----"
+	pgpSigBegin      = "-----BEGIN PGP SIGNATURE-----"
+	sshSigBegin      = "-----BEGIN SSH SIGNATURE-----"
+)
+
+// Signature describes a verified signature of an upstream revision
+type Signature struct {
+	// Object is the signed object, "commit" or "tag"
+	Object string
+	Format string
+	// Key is the fingerprint of the signing key
+	Key string
+	// Signer is the key's user ID or principal
+	Signer string
+}
+
+// sshSigner is a trusted SSH key and the principal it belongs to
+type sshSigner struct {
+	key       ssh.PublicKey
+	principal string
+}
+
+// Keyring holds the keys trusted to sign upstream revisions
+type Keyring struct {
+	pgp openpgp.EntityList
+	ssh []sshSigner
+}
+
+// Len returns the number of trusted keys
+func (k *Keyring) Len() int {
+	return len(k.pgp) + len(k.ssh)
+}
+
+// LoadKeyring reads trusted keys from files holding armored PGP public keys
+// or SSH public keys, one per line in authorized_keys or allowed_signers
+// format
+func LoadKeyring(paths []string) (*Keyring, error) {
+	k := &Keyring{}
+	for _, path := range paths {
+		data, err := os.ReadFile(expandHome(path))
+		if err != nil {
+			return nil, fmt.Errorf("read keyring: %w", err)
+		}
+
+		if bytes.Contains(data, []byte(pgpKeyBlockBegin)) {
+			if err := k.addPGP(data); err != nil {
+				return nil, fmt.Errorf("read keyring %s: %w", path, err)
+			}
+			continue
+		}
+		if err := k.addSSH(data); err != nil {
+			return nil, fmt.Errorf("read keyring %s: %w", path, err)
+		}
+	}
+	return k, nil
+}
+
+// addPGP adds every armored key block in data
+func (k *Keyring) addPGP(data []byte) error {
+	for {
+		start := bytes.Index(data, []byte(pgpKeyBlockBegin))
+		if start < 0 {
+			return nil
+		}
+		entities, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(data[start:]))
+		if err != nil {
+			return err
+		}
+		k.pgp = append(k.pgp, entities...)
+		data = data[start+len(pgpKeyBlockBegin):]
+	}
+}
+
+// addSSH adds SSH keys from authorized_keys or allowed_signers lines
+func (k *Keyring) addSSH(data []byte) error {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		signer, ok := parseSSHSigner(text)
+		if !ok {
+			return fmt.Errorf("line %d: not an SSH public key", line)
+		}
+		k.ssh = append(k.ssh, signer)
+	}
+	return scanner.Err()
+}
+
+// parseSSHSigner parses "[principals] [options] keytype key [comment]"
+func parseSSHSigner(line string) (sshSigner, bool) {
+	fields := strings.Fields(line)
+	for i := range fields {
+		key, comment, _, _, err := ssh.ParseAuthorizedKey([]byte(strings.Join(fields[i:], " ")))
+		if err != nil {
+			continue
+		}
+		principal := comment
+		if i > 0 {
+			principal = fields[0]
+		}
+		return sshSigner{key: key, principal: principal}, true
+	}
+	return sshSigner{}, false
+}
+
+// VerifyRevision checks that the commit checked out in dir or, when tag is
+// set, annotated and pointing at that commit, that tag is signed by a key
+// in keyring
+func VerifyRevision(dir, tag string, keyring *Keyring) (*Signature, error) {
+	repo, err := git.PlainOpen(dir)
+	if err != nil {
+		return nil, fmt.Errorf("open repository: %w", err)
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return nil, fmt.Errorf("resolve HEAD: %w", err)
+	}
+
+	if tag != "" {
+		ref, err := repo.Tag(tag)
+		if err != nil {
+			return nil, fmt.Errorf("resolve tag %s: %w", tag, err)
+		}
+		tagObj, err := repo.TagObject(ref.Hash())
+		switch {
+		case err == nil && tagObj.Target == head.Hash():
+			payload := &plumbing.MemoryObject{}
+			if err := tagObj.EncodeWithoutSignature(payload); err != nil {
+				return nil, err
+			}
+			return keyring.verify("tag "+tag, "tag", payload, tagObj.PGPSignature)
+		case err != nil && !errors.Is(err, plumbing.ErrObjectNotFound):
+			return nil, fmt.Errorf("read tag %s: %w", tag, err)
+		}
+		// A lightweight tag has no signature of its own, and a tag of
+		// another commit doesn't vouch for the checked out one
+	}
+
+	commit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return nil, fmt.Errorf("read commit: %w", err)
+	}
+	payload := &plumbing.MemoryObject{}
+	if err := commit.EncodeWithoutSignature(payload); err != nil {
+		return nil, err
+	}
+	return keyring.verify("commit "+head.Hash().String()[:12], "commit", payload, commit.PGPSignature)
+}
+
+// verify checks the armored signature sig over payload; name identifies
+// the object in errors
+func (k *Keyring) verify(name, object string, payload *plumbing.MemoryObject, sig string) (*Signature, error) {
+	if strings.TrimSpace(sig) == "" {
+		return nil, apperr.New(apperr.Untrusted, "%s is not signed", name)
+	}
+	reader, err := payload.Reader()
+	if err != nil {
+		return nil, err
+	}
+	message, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case strings.HasPrefix(sig, sshSigBegin):
+		pub, err := verifySSHSignature(message, sig)
+		if err != nil {
+			return nil, apperr.Wrap(apperr.Untrusted, fmt.Errorf("%s: %w", name, err))
+		}
+		for _, signer := range k.ssh {
+			if bytes.Equal(signer.key.Marshal(), pub.Marshal()) {
+				return &Signature{Object: object, Format: FormatSSH, Key: ssh.FingerprintSHA256(pub), Signer: signer.principal}, nil
+			}
+		}
+		return nil, apperr.New(apperr.Untrusted, "%s is signed by untrusted SSH key %s", name, ssh.FingerprintSHA256(pub))
+
+	case strings.HasPrefix(sig, pgpSigBegin):
+		entity, err := openpgp.CheckArmoredDetachedSignature(k.pgp, bytes.NewReader(message), strings.NewReader(sig), nil)
+		if err != nil {
+			return nil, apperr.Wrap(apperr.Untrusted, fmt.Errorf("%s: PGP signature not made by a trusted key: %w", name, err))
+		}
+		signer := ""
+		if id := entity.PrimaryIdentity(); id != nil {
+			signer = id.Name
+		}
+		return &Signature{Object: object, Format: FormatPGP, Key: fmt.Sprintf("%X", entity.PrimaryKey.Fingerprint), Signer: signer}, nil
+	}
+	return nil, apperr.New(apperr.Untrusted, "%s has an unsupported signature format", name)
+}
@@ -0,0 +1,168 @@
+package git
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/pem"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/doodleEsc/ctx-tool/internal/apperr"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"golang.org/x/crypto/ssh"
+)
+
+// sshTestSigner signs like ssh-keygen -Y sign -n git
+type sshTestSigner struct {
+	key ssh.Signer
+}
+
+func (s sshTestSigner) Sign(message io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(message)
+	if err != nil {
+		return nil, err
+	}
+	h := sha512.Sum512(data)
+	signed := append([]byte(sshSigMagic), ssh.Marshal(sshSigSigned{Namespace: sshSigNamespace, HashAlgorithm: "sha512", Hash: h[:]})...)
+	sig, err := s.key.Sign(rand.Reader, signed)
+	if err != nil {
+		return nil, err
+	}
+	blob := append([]byte(sshSigMagic), ssh.Marshal(sshSigBlob{
+		Version:       1,
+		PublicKey:     s.key.PublicKey().Marshal(),
+		Namespace:     sshSigNamespace,
+		HashAlgorithm: "sha512",
+		Signature:     ssh.Marshal(sig),
+	})...)
+	return pem.EncodeToMemory(&pem.Block{Type: "SSH SIGNATURE", Bytes: blob}), nil
+}
+
+// commitRepo creates a repository with one commit made with opts
+func commitRepo(t *testing.T, opts *git.CommitOptions) (string, *git.Repository) {
+	t.Helper()
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("signed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	wt, _ := repo.Worktree()
+	if _, err := wt.Add("README.md"); err != nil {
+		t.Fatal(err)
+	}
+	opts.Author = &object.Signature{Name: "t", Email: "t@t", When: time.Now()}
+	if _, err := wt.Commit("init", opts); err != nil {
+		t.Fatal(err)
+	}
+	return dir, repo
+}
+
+// pgpKeyring writes the public key of entity to an armored keyring file
+func pgpKeyring(t *testing.T, entity *openpgp.Entity) string {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := entity.Serialize(w); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	path := filepath.Join(t.TempDir(), "trusted.asc")
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func loadKeyring(t *testing.T, paths ...string) *Keyring {
+	t.Helper()
+	keyring, err := LoadKeyring(paths)
+	if err != nil {
+		t.Fatalf("LoadKeyring failed: %v", err)
+	}
+	return keyring
+}
+
+func TestVerifyPGP(t *testing.T) {
+	trusted, err := openpgp.NewEntity("Release Bot", "", "bot@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _ := openpgp.NewEntity("Someone Else", "", "else@example.com", nil)
+
+	dir, repo := commitRepo(t, &git.CommitOptions{SignKey: trusted})
+
+	sig, err := VerifyRevision(dir, "", loadKeyring(t, pgpKeyring(t, trusted)))
+	if err != nil {
+		t.Fatalf("VerifyRevision failed: %v", err)
+	}
+	if sig.Object != "commit" || sig.Format != FormatPGP || sig.Signer != "Release Bot <bot@example.com>" {
+		t.Errorf("Unexpected signature %+v", sig)
+	}
+
+	if _, err := VerifyRevision(dir, "", loadKeyring(t, pgpKeyring(t, other))); apperr.KindOf(err) != apperr.Untrusted {
+		t.Errorf("Expected an untrusted error for another key, got %v", err)
+	}
+
+	// Annotated tags are verified on their own
+	head, _ := repo.Head()
+	if _, err := repo.CreateTag("v1", head.Hash(), &git.CreateTagOptions{
+		Tagger:  &object.Signature{Name: "t", Email: "t@t", When: time.Now()},
+		Message: "v1",
+		SignKey: trusted,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	sig, err = VerifyRevision(dir, "v1", loadKeyring(t, pgpKeyring(t, trusted)))
+	if err != nil || sig.Object != "tag" {
+		t.Errorf("VerifyRevision(tag) = %+v, %v", sig, err)
+	}
+}
+
+func TestVerifySSH(t *testing.T) {
+	_, priv, _ := ed25519.GenerateKey(rand.Reader)
+	signer, _ := ssh.NewSignerFromKey(priv)
+	dir, _ := commitRepo(t, &git.CommitOptions{Signer: sshTestSigner{signer}})
+
+	allowed := filepath.Join(t.TempDir(), "allowed_signers")
+	line := "dev@example.com namespaces=\"git\" " + string(ssh.MarshalAuthorizedKey(signer.PublicKey()))
+	if err := os.WriteFile(allowed, []byte("# trusted signers\n"+line), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	sig, err := VerifyRevision(dir, "", loadKeyring(t, allowed))
+	if err != nil {
+		t.Fatalf("VerifyRevision failed: %v", err)
+	}
+	if sig.Format != FormatSSH || sig.Signer != "dev@example.com" || sig.Key != ssh.FingerprintSHA256(signer.PublicKey()) {
+		t.Errorf("Unexpected signature %+v", sig)
+	}
+
+	_, otherPriv, _ := ed25519.GenerateKey(rand.Reader)
+	otherSigner, _ := ssh.NewSignerFromKey(otherPriv)
+	otherKeys := filepath.Join(t.TempDir(), "authorized_keys")
+	os.WriteFile(otherKeys, ssh.MarshalAuthorizedKey(otherSigner.PublicKey()), 0644)
+	if _, err := VerifyRevision(dir, "", loadKeyring(t, otherKeys)); apperr.KindOf(err) != apperr.Untrusted {
+		t.Errorf("Expected an untrusted error for another key, got %v", err)
+	}
+}
+
+func TestVerifyUnsigned(t *testing.T) {
+	dir, _ := commitRepo(t, &git.CommitOptions{})
+	if _, err := VerifyRevision(dir, "", &Keyring{}); apperr.KindOf(err) != apperr.Untrusted {
+		t.Errorf("Expected an untrusted error for an unsigned commit, got %v", err)
+	}
+}
@@ -3,6 +3,7 @@ package i18n
 import (
 	"embed"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -154,12 +155,12 @@ func Tn(messageID string, count int, data map[string]interface{}) string {
 
 // Error creates a localized error
 func Error(messageID string) error {
-	return fmt.Errorf(T(messageID))
+	return errors.New(T(messageID))
 }
 
 // Errorf creates a localized error with format parameters
 func Errorf(messageID string, data map[string]interface{}) error {
-	return fmt.Errorf(Tf(messageID, data))
+	return errors.New(Tf(messageID, data))
 }
 
 // ListEmbeddedFiles lists all embedded files (for testing)
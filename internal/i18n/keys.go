@@ -15,6 +15,40 @@ const (
 	CmdRemoveShort   = "cmd.remove.short"
 	CmdRemoveLong    = "cmd.remove.long"
 	CmdRemoveExample = "cmd.remove.example"
+
+	// Status command
+	CmdStatusShort   = "cmd.status.short"
+	CmdStatusLong    = "cmd.status.long"
+	CmdStatusExample = "cmd.status.example"
+
+	// Projects and scan commands
+	CmdProjectsShort       = "cmd.projects.short"
+	CmdProjectsLong        = "cmd.projects.long"
+	CmdProjectsExample     = "cmd.projects.example"
+	CmdProjectsListShort   = "cmd.projects.list.short"
+	CmdProjectsUpdateShort = "cmd.projects.update.short"
+	CmdProjectsPruneShort  = "cmd.projects.prune.short"
+	CmdScanShort           = "cmd.scan.short"
+	CmdScanLong            = "cmd.scan.long"
+	CmdScanExample         = "cmd.scan.example"
+
+	// Doctor command
+	CmdDoctorShort   = "cmd.doctor.short"
+	CmdDoctorLong    = "cmd.doctor.long"
+	CmdDoctorExample = "cmd.doctor.example"
+
+	// Verify and repair commands
+	CmdVerifyShort   = "cmd.verify.short"
+	CmdVerifyLong    = "cmd.verify.long"
+	CmdVerifyExample = "cmd.verify.example"
+	CmdRepairShort   = "cmd.repair.short"
+	CmdRepairLong    = "cmd.repair.long"
+	CmdRepairExample = "cmd.repair.example"
+
+	// Adopt command
+	CmdAdoptShort   = "cmd.adopt.short"
+	CmdAdoptLong    = "cmd.adopt.long"
+	CmdAdoptExample = "cmd.adopt.example"
 )
 
 // Message keys for user interactions
@@ -36,18 +70,142 @@ const (
 	MsgRemovalComplete       = "msg.remove.removal_complete"
 	MsgFilesRemoved          = "msg.remove.files_removed"
 	MsgNoTrackedFiles        = "msg.remove.no_tracked_files"
+	MsgNoMatchingFiles       = "msg.remove.no_matching_files"
+	MsgModifiedWillKeep      = "msg.remove.modified_will_keep"
+	MsgModifiedWillDelete    = "msg.remove.modified_will_delete"
+	MsgModifiedFilesKept     = "msg.remove.modified_files_kept"
+	MsgSkipModified          = "msg.remove.skip_modified"
+	MsgFilesKeptModified     = "msg.remove.files_kept_modified"
+	MsgSkipMissing           = "msg.remove.skip_missing"
+	MsgRemoved               = "msg.remove.removed"
+	MsgRemoveFailed          = "msg.remove.failed"
+	MsgRemovedEmptyDir       = "msg.remove.removed_empty_dir"
+
+	// Status command messages
+	MsgStatusTrackingFile    = "msg.status.tracking_file"
+	MsgStatusRepository      = "msg.status.repository"
+	MsgStatusSignature       = "msg.status.signature"
+	MsgStatusInstalledAt     = "msg.status.installed_at"
+	MsgStatusModified        = "msg.status.modified"
+	MsgStatusMissing         = "msg.status.missing"
+	MsgStatusSummary         = "msg.status.summary"
+	MsgStatusClean           = "msg.status.clean"
+
+	// Projects and scan command messages
+	MsgNoProjects            = "msg.projects.none"
+	MsgProjectNotRegistered  = "msg.projects.not_registered"
+	MsgProjectStateCurrent   = "msg.projects.state.current"
+	MsgProjectStateOutdated  = "msg.projects.state.outdated"
+	MsgProjectStateMissing   = "msg.projects.state.missing"
+	MsgProjectStateUnknown   = "msg.projects.state.unknown"
+	MsgUpdatingProject       = "msg.projects.updating"
+	MsgProjectUpdateFailed   = "msg.projects.update_failed"
+	MsgProjectsUpdated       = "msg.projects.updated"
+	MsgProjectPruned         = "msg.projects.pruned"
+	MsgProjectsPruned        = "msg.projects.pruned_count"
+	MsgStorePruned           = "msg.projects.store_pruned"
+	MsgForgottenSymlinks     = "msg.projects.forgotten_symlinks"
+	MsgScanFound             = "msg.scan.found"
+	MsgScanRegistered        = "msg.scan.registered"
+
+	// Config messages
+	MsgUsingConfigFile       = "msg.config.using_file"
+	MsgCreatedDefaultConfig  = "msg.config.created_default"
+	MsgNoConfigFile          = "msg.config.no_file"
+	MsgConfigCreateFailed    = "msg.config.create_failed"
+	MsgLegacyConfigHint      = "msg.config.legacy_hint"
+
+	// Project messages
+	MsgProjectRoot           = "msg.project.root"
+	MsgRootTrackingFile      = "msg.project.reason.tracking_file"
+	MsgRootClaudeDir         = "msg.project.reason.claude_dir"
+	MsgRootGitRoot           = "msg.project.reason.git_root"
+	MsgRootWorkingDir        = "msg.project.reason.working_dir"
 
 	// Git messages
 	MsgCloningRepository     = "msg.git.cloning_repository"
 	MsgRepositoryCloned      = "msg.git.repository_cloned"
+	MsgSignatureVerified     = "msg.git.signature_verified"
 	MsgCloneSuccess          = "msg.git.clone_success"
 
+	// Trusted source messages
+	MsgUntrustedSource       = "msg.trust.untrusted_source"
+	MsgSourceRegistry        = "msg.trust.origin_registry"
+	MsgConfirmTrust          = "msg.trust.confirm"
+	MsgTrustCancelled        = "msg.trust.cancelled"
+	MsgSourceTrusted         = "msg.trust.added"
+	MsgSourceTrustedOnce     = "msg.trust.added_once"
+
+	// Security review messages
+	MsgReviewHeader          = "msg.review.header"
+	MsgReviewNeedsApproval   = "msg.review.needs_approval"
+	MsgConfirmReview         = "msg.review.confirm"
+	MsgReviewCancelled       = "msg.review.cancelled"
+	MsgReviewKindHook        = "msg.review.kind.hook"
+	MsgReviewKindPermission  = "msg.review.kind.permission"
+	MsgReviewKindMCPServer   = "msg.review.kind.mcp_server"
+	MsgReviewKindToolGrant   = "msg.review.kind.tool_grant"
+	MsgReviewKindScript      = "msg.review.kind.script"
+	MsgReviewKindPipeToShell = "msg.review.kind.pipe_to_shell"
+	MsgReviewKindURL         = "msg.review.kind.external_url"
+
+	// Doctor messages
+	MsgDoctorFix             = "msg.doctor.fix"
+	MsgDoctorHealthy         = "msg.doctor.healthy"
+	MsgDoctorSummary         = "msg.doctor.summary"
+	MsgDoctorConfigFile      = "msg.doctor.config_file"
+	MsgDoctorConfigDefault   = "msg.doctor.config_default"
+	MsgDoctorConfigMissing   = "msg.doctor.config_missing"
+	MsgDoctorConfigLegacy    = "msg.doctor.config_legacy"
+	MsgDoctorConfigShadowed  = "msg.doctor.config_shadowed"
+	MsgDoctorEnvOverride     = "msg.doctor.env_override"
+	MsgDoctorRepositoryOK    = "msg.doctor.repository_ok"
+	MsgDoctorRepositoryFailed = "msg.doctor.repository_failed"
+	MsgDoctorSourceDefault   = "msg.doctor.source_default"
+	MsgDoctorUnreadable      = "msg.doctor.unreadable"
+	MsgDoctorProjectMissing  = "msg.doctor.project_missing"
+	MsgDoctorTrackingOK      = "msg.doctor.tracking_ok"
+	MsgDoctorBaseMoved       = "msg.doctor.base_moved"
+	MsgDoctorGlobalScope     = "msg.doctor.global_scope"
+	MsgDoctorHomeProject     = "msg.doctor.home_project"
+	MsgDoctorBackups         = "msg.doctor.backups"
+	MsgDoctorTempClones      = "msg.doctor.temp_clones"
+	MsgDoctorNotWritable     = "msg.doctor.not_writable"
+	MsgDoctorWritable        = "msg.doctor.writable"
+
+	// Verify and repair messages
+	MsgVerifyCorrupted    = "msg.verify.corrupted"
+	MsgVerifyUpstream     = "msg.verify.upstream_mismatch"
+	MsgVerifySummary      = "msg.verify.summary"
+	MsgRepairNothing      = "msg.repair.nothing"
+	MsgRepairRestoring    = "msg.repair.restoring"
+	MsgRepairFailed       = "msg.repair.failed"
+	MsgRepairComplete     = "msg.repair.complete"
+	MsgRepairKeptModified = "msg.repair.kept_modified"
+
+	// Adopt messages
+	MsgAdoptNothing   = "msg.adopt.nothing"
+	MsgAdoptFile      = "msg.adopt.file"
+	MsgAdoptMoved     = "msg.adopt.moved"
+	MsgAdoptDiffers   = "msg.adopt.differs"
+	MsgAdoptComplete  = "msg.adopt.complete"
+	MsgAdoptDiffering = "msg.adopt.differing"
+
 	// Sync messages
 	MsgSyncingDir            = "msg.sync.syncing_directory"
 	MsgSkipIdentical         = "msg.sync.skip_identical"
 	MsgBackedUp              = "msg.sync.backed_up"
 	MsgInstalled             = "msg.sync.installed"
 	MsgWarningDirNotFound    = "msg.sync.warning_dir_not_found"
+	MsgMovedFile             = "msg.sync.moved"
+	MsgMoveConflict          = "msg.sync.move_conflict"
+	MsgDetachedFile          = "msg.sync.detached"
+	MsgServerExists          = "msg.sync.mcp_server_exists"
+	MsgUnsetVariable         = "msg.sync.unset_variable"
+	MsgWholeFileEdited       = "msg.sync.whole_file_edited"
+	MsgSharedLinkEdited      = "msg.sync.shared_link_edited"
+	MsgRegisterFailed        = "msg.sync.register_failed"
+	MsgMigratedTrackingFile  = "msg.sync.migrated_tracking_file"
 )
 
 // Error message keys
@@ -83,4 +241,18 @@ const (
 	ErrInvalidScope          = "err.cmd.invalid_scope"
 	ErrInvalidTarget         = "err.cmd.invalid_target"
 	ErrMissingArgument       = "err.cmd.missing_argument"
+)
+
+// Hints printed below an error, one per error kind
+const (
+	HintUsage      = "hint.usage"
+	HintNetwork    = "hint.network"
+	HintAuth       = "hint.auth"
+	HintNotFound   = "hint.not_found"
+	HintNotAllowed = "hint.not_allowed"
+	HintConflict   = "hint.conflict"
+	HintLocked     = "hint.locked"
+	HintCancelled  = "hint.cancelled"
+	HintPartial    = "hint.partial"
+	HintUntrusted  = "hint.untrusted"
 )
\ No newline at end of file
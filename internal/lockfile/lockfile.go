@@ -0,0 +1,106 @@
+// Package lockfile implements exclusive lock files that record their
+// owner's PID, so a lock left behind by a process that died is taken over
+// instead of blocking every later run.
+package lockfile
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// unknownOwnerGrace is how long a lock file without a readable PID is
+// assumed to be in the middle of being written by its owner
+const unknownOwnerGrace = 10 * time.Second
+
+// HeldError is returned when a running process holds the lock
+type HeldError struct {
+	Path string
+	// PID is the owner's process ID, 0 if it couldn't be read
+	PID int
+}
+
+func (e *HeldError) Error() string {
+	if e.PID == 0 {
+		return fmt.Sprintf("lock file %s is held by another process", e.Path)
+	}
+	return fmt.Sprintf("lock file %s is held by process %d", e.Path, e.PID)
+}
+
+// Acquire creates the lock file at path, waiting up to timeout for another
+// process to release it. A lock whose owner is no longer running is removed
+// and taken over. The returned function releases the lock.
+func Acquire(path string, timeout time.Duration) (func() error, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("create lock directory: %w", err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			fmt.Fprintln(file, strconv.Itoa(os.Getpid()))
+			if err := file.Close(); err != nil {
+				os.Remove(path)
+				return nil, fmt.Errorf("write lock file: %w", err)
+			}
+			return func() error { return os.Remove(path) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("create lock file: %w", err)
+		}
+
+		held, stale, err := inspect(path)
+		if err != nil {
+			return nil, err
+		}
+		if stale {
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, held
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// inspect reports who holds the lock at path and removes it when its owner
+// is gone. stale is set when the lock was removed or vanished meanwhile.
+func inspect(path string) (*HeldError, bool, error) {
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, true, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("read lock file: %w", err)
+	}
+
+	held := &HeldError{Path: path}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(content)))
+	if err != nil || pid <= 0 {
+		// The owner may not have written its PID yet
+		info, err := os.Stat(path)
+		if err != nil || time.Since(info.ModTime()) < unknownOwnerGrace {
+			return held, false, nil
+		}
+	} else {
+		held.PID = pid
+		if pid == os.Getpid() || processAlive(pid) {
+			return held, false, nil
+		}
+	}
+
+	// Only remove the lock we looked at, not one another process took over
+	// since
+	if current, err := os.ReadFile(path); err == nil && !bytes.Equal(current, content) {
+		return held, false, nil
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return nil, false, fmt.Errorf("remove stale lock file: %w", err)
+	}
+	return nil, true, nil
+}
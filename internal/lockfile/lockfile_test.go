@@ -0,0 +1,79 @@
+package lockfile
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestAcquireIsExclusive(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.lock")
+
+	unlock, err := Acquire(path, 0)
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+
+	_, err = Acquire(path, 0)
+	var held *HeldError
+	if !errors.As(err, &held) || held.PID != os.Getpid() {
+		t.Fatalf("Second Acquire = %v, want a HeldError naming this process", err)
+	}
+
+	if err := unlock(); err != nil {
+		t.Fatalf("unlock failed: %v", err)
+	}
+	unlock, err = Acquire(path, 0)
+	if err != nil {
+		t.Fatalf("Acquire after unlock failed: %v", err)
+	}
+	unlock()
+}
+
+func TestAcquireTakesOverStaleLock(t *testing.T) {
+	// A process that has exited leaves its PID behind
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Failed to run helper process: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "state.lock")
+	if err := os.WriteFile(path, []byte(strconv.Itoa(cmd.Process.Pid)+"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write lock file: %v", err)
+	}
+
+	unlock, err := Acquire(path, 0)
+	if err != nil {
+		t.Fatalf("Acquire should take over a stale lock: %v", err)
+	}
+	defer unlock()
+
+	content, _ := os.ReadFile(path)
+	if string(content) != strconv.Itoa(os.Getpid())+"\n" {
+		t.Errorf("Lock file records %q, want this process", content)
+	}
+}
+
+func TestAcquireWaitsForUnknownOwner(t *testing.T) {
+	// A lock without a PID may still be being written
+	path := filepath.Join(t.TempDir(), "state.lock")
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatalf("Failed to write lock file: %v", err)
+	}
+	if _, err := Acquire(path, 0); err == nil {
+		t.Fatal("Acquire took over a fresh lock without an owner")
+	}
+
+	old := time.Now().Add(-2 * unknownOwnerGrace)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatalf("Chtimes failed: %v", err)
+	}
+	unlock, err := Acquire(path, 0)
+	if err != nil {
+		t.Fatalf("Acquire should take over an old lock without an owner: %v", err)
+	}
+	unlock()
+}
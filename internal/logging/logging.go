@@ -0,0 +1,115 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// Options selects where diagnostics go and how detailed they are
+type Options struct {
+	// Level is the minimum level written to Stderr
+	Level slog.Level
+	// Stderr receives console diagnostics; nil means os.Stderr
+	Stderr io.Writer
+	// File, if set, receives every record down to debug level
+	File string
+}
+
+// LevelFromFlags maps the --quiet, --verbose and --debug flags to a level.
+// Without flags only warnings and errors are shown.
+func LevelFromFlags(quiet, verbose, debug bool) slog.Level {
+	switch {
+	case debug:
+		return slog.LevelDebug
+	case verbose:
+		return slog.LevelInfo
+	case quiet:
+		return slog.LevelError
+	default:
+		return slog.LevelWarn
+	}
+}
+
+// Setup installs the default logger and returns a function that closes the
+// log file, if any
+func Setup(opts Options) (func() error, error) {
+	stderr := opts.Stderr
+	if stderr == nil {
+		stderr = os.Stderr
+	}
+
+	handlers := []slog.Handler{
+		slog.NewTextHandler(stderr, &slog.HandlerOptions{Level: opts.Level}),
+	}
+	closer := func() error { return nil }
+
+	if opts.File != "" {
+		file, err := os.OpenFile(opts.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("open log file: %w", err)
+		}
+		handlers = append(handlers, slog.NewTextHandler(file, &slog.HandlerOptions{Level: slog.LevelDebug}))
+		closer = file.Close
+	}
+
+	slog.SetDefault(slog.New(fanout(handlers)))
+	return closer, nil
+}
+
+// Component returns the default logger tagged with a component name
+func Component(name string) *slog.Logger {
+	return slog.Default().With("component", name)
+}
+
+// Phase logs the start of a named phase and returns a function that logs
+// its duration when called
+func Phase(name string) func() {
+	start := time.Now()
+	slog.Debug("phase started", "phase", name)
+	return func() {
+		slog.Info("phase finished", "phase", name, "duration", time.Since(start).Round(time.Millisecond))
+	}
+}
+
+// fanout forwards records to several handlers
+type fanout []slog.Handler
+
+func (f fanout) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range f {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f fanout) Handle(ctx context.Context, r slog.Record) error {
+	for _, h := range f {
+		if h.Enabled(ctx, r.Level) {
+			if err := h.Handle(ctx, r.Clone()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (f fanout) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make(fanout, len(f))
+	for i, h := range f {
+		handlers[i] = h.WithAttrs(attrs)
+	}
+	return handlers
+}
+
+func (f fanout) WithGroup(name string) slog.Handler {
+	handlers := make(fanout, len(f))
+	for i, h := range f {
+		handlers[i] = h.WithGroup(name)
+	}
+	return handlers
+}
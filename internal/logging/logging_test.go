@@ -0,0 +1,63 @@
+package logging
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLevelFromFlags(t *testing.T) {
+	tests := []struct {
+		quiet, verbose, debug bool
+		want                  slog.Level
+	}{
+		{false, false, false, slog.LevelWarn},
+		{true, false, false, slog.LevelError},
+		{false, true, false, slog.LevelInfo},
+		{false, true, true, slog.LevelDebug},
+	}
+
+	for _, tt := range tests {
+		if got := LevelFromFlags(tt.quiet, tt.verbose, tt.debug); got != tt.want {
+			t.Errorf("LevelFromFlags(%v, %v, %v) = %v, want %v", tt.quiet, tt.verbose, tt.debug, got, tt.want)
+		}
+	}
+}
+
+func TestSetupWritesDebugToFile(t *testing.T) {
+	previous := slog.Default()
+	defer slog.SetDefault(previous)
+
+	var stderr bytes.Buffer
+	logFile := filepath.Join(t.TempDir(), "ctx-tool.log")
+
+	closeLog, err := Setup(Options{Level: slog.LevelWarn, Stderr: &stderr, File: logFile})
+	if err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+
+	Component("sync").Debug("file decision", "path", "a.md")
+	slog.Warn("something odd")
+
+	if err := closeLog(); err != nil {
+		t.Fatalf("Closing log file failed: %v", err)
+	}
+
+	if strings.Contains(stderr.String(), "file decision") {
+		t.Error("Debug record should not reach stderr at warn level")
+	}
+	if !strings.Contains(stderr.String(), "something odd") {
+		t.Error("Warning should reach stderr")
+	}
+
+	data, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatalf("Failed to read log file: %v", err)
+	}
+	if !strings.Contains(string(data), "file decision") || !strings.Contains(string(data), "component=sync") {
+		t.Errorf("Log file is missing the debug record: %s", data)
+	}
+}
@@ -0,0 +1,131 @@
+// Package merge installs upstream content into files that also hold
+// project-specific content, instead of replacing them whole.
+package merge
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Merge modes for installed files
+const (
+	// ModeReplace overwrites the whole target file
+	ModeReplace = "replace"
+	// ModeBlock owns only a delimited block inside the target file
+	ModeBlock = "block"
+)
+
+const (
+	blockBeginPrefix = "<!-- ctx-tool:begin "
+	blockBeginSuffix = " -->"
+
+	// BlockEnd closes a managed block
+	BlockEnd = "<!-- ctx-tool:end -->"
+)
+
+// BlockBegin returns the line opening the managed block id
+func BlockBegin(id string) string {
+	return blockBeginPrefix + id + blockBeginSuffix
+}
+
+// span locates a block in a document. The body lies between the begin and
+// end marker lines; start and end include the marker lines themselves.
+type span struct {
+	start, bodyStart, bodyEnd, end int
+}
+
+// findBlock locates block id. Markers must be on lines of their own.
+func findBlock(doc []byte, id string) (span, bool, error) {
+	begin := BlockBegin(id)
+	var s span
+	inBlock := false
+
+	for offset := 0; offset < len(doc); {
+		lineEnd := bytes.IndexByte(doc[offset:], '\n')
+		next := len(doc)
+		if lineEnd >= 0 {
+			next = offset + lineEnd + 1
+		}
+		line := strings.TrimSpace(string(doc[offset:next]))
+
+		switch {
+		case !inBlock && line == begin:
+			inBlock = true
+			s.start, s.bodyStart = offset, next
+		case inBlock && line == BlockEnd:
+			s.bodyEnd, s.end = offset, next
+			return s, true, nil
+		}
+		offset = next
+	}
+
+	if inBlock {
+		return span{}, false, fmt.Errorf("block %s has no %s marker", id, BlockEnd)
+	}
+	return span{}, false, nil
+}
+
+// ExtractBlock returns the body of block id in doc
+func ExtractBlock(doc []byte, id string) ([]byte, bool, error) {
+	s, ok, err := findBlock(doc, id)
+	if !ok || err != nil {
+		return nil, false, err
+	}
+	return doc[s.bodyStart:s.bodyEnd], true, nil
+}
+
+// UpsertBlock replaces the body of block id with content, or appends the
+// block after a blank line when doc doesn't have it yet. Everything outside
+// the block is kept as it is.
+func UpsertBlock(doc []byte, id string, content []byte) ([]byte, error) {
+	body := content
+	if len(body) > 0 && body[len(body)-1] != '\n' {
+		body = append(append([]byte{}, body...), '\n')
+	}
+
+	s, ok, err := findBlock(doc, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	if ok {
+		out.Write(doc[:s.bodyStart])
+		out.Write(body)
+		out.Write(doc[s.bodyEnd:])
+		return out.Bytes(), nil
+	}
+
+	out.Write(doc)
+	if len(doc) > 0 {
+		if doc[len(doc)-1] != '\n' {
+			out.WriteByte('\n')
+		}
+		out.WriteByte('\n')
+	}
+	out.WriteString(BlockBegin(id) + "\n")
+	out.Write(body)
+	out.WriteString(BlockEnd + "\n")
+	return out.Bytes(), nil
+}
+
+// RemoveBlock removes block id including its markers and reports whether
+// it was found. The blank line UpsertBlock put in front of an appended
+// block is removed with it.
+func RemoveBlock(doc []byte, id string) ([]byte, bool, error) {
+	s, ok, err := findBlock(doc, id)
+	if !ok || err != nil {
+		return doc, false, err
+	}
+
+	before, after := doc[:s.start], doc[s.end:]
+	if len(after) == 0 && bytes.HasSuffix(before, []byte("\n\n")) {
+		before = before[:len(before)-1]
+	}
+
+	out := make([]byte, 0, len(before)+len(after))
+	out = append(out, before...)
+	out = append(out, after...)
+	return out, true, nil
+}
@@ -0,0 +1,53 @@
+package merge
+
+import "testing"
+
+func TestUpsertBlock(t *testing.T) {
+	doc := []byte("# Billing\n\nProject notes.\n")
+
+	// Appended after the project's own text
+	got, err := UpsertBlock(doc, "CLAUDE.md", []byte("Shared rules"))
+	if err != nil {
+		t.Fatalf("UpsertBlock failed: %v", err)
+	}
+	want := "# Billing\n\nProject notes.\n\n<!-- ctx-tool:begin CLAUDE.md -->\nShared rules\n<!-- ctx-tool:end -->\n"
+	if string(got) != want {
+		t.Fatalf("UpsertBlock() = %q, want %q", got, want)
+	}
+
+	// Replaced in place, text after the block included
+	edited := append(got, []byte("More notes.\n")...)
+	got, err = UpsertBlock(edited, "CLAUDE.md", []byte("New rules\n"))
+	if err != nil {
+		t.Fatalf("UpsertBlock failed: %v", err)
+	}
+	want = "# Billing\n\nProject notes.\n\n<!-- ctx-tool:begin CLAUDE.md -->\nNew rules\n<!-- ctx-tool:end -->\nMore notes.\n"
+	if string(got) != want {
+		t.Errorf("UpsertBlock() = %q, want %q", got, want)
+	}
+
+	body, ok, err := ExtractBlock(got, "CLAUDE.md")
+	if err != nil || !ok || string(body) != "New rules\n" {
+		t.Errorf("ExtractBlock() = %q, %v, %v", body, ok, err)
+	}
+}
+
+func TestRemoveBlock(t *testing.T) {
+	doc := []byte("# Billing\n\n<!-- ctx-tool:begin CLAUDE.md -->\nShared rules\n<!-- ctx-tool:end -->\n")
+
+	got, ok, err := RemoveBlock(doc, "CLAUDE.md")
+	if err != nil || !ok {
+		t.Fatalf("RemoveBlock() = %v, %v", ok, err)
+	}
+	if string(got) != "# Billing\n" {
+		t.Errorf("RemoveBlock() = %q, want the project's text only", got)
+	}
+
+	if _, ok, _ := RemoveBlock(got, "CLAUDE.md"); ok {
+		t.Error("Removing a missing block should report it wasn't found")
+	}
+
+	if _, _, err := ExtractBlock([]byte("<!-- ctx-tool:begin CLAUDE.md -->\nno end\n"), "CLAUDE.md"); err == nil {
+		t.Error("Expected an error for a block without end marker")
+	}
+}
@@ -0,0 +1,325 @@
+package merge
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// ModeJSON deep-merges JSON documents such as .claude/settings.json
+const ModeJSON = "json"
+
+// Kinds of contributions to a JSON document
+const (
+	// ContribValue is a key ctx-tool set that the document didn't have
+	ContribValue = "value"
+	// ContribItem is an item ctx-tool appended to an existing array
+	ContribItem = "item"
+	// ContribContainer is an object or array ctx-tool created; it is
+	// removed once it is empty
+	ContribContainer = "container"
+)
+
+// Contribution is a piece of a JSON document that came from upstream, so
+// it can be withdrawn later without touching the user's own settings
+type Contribution struct {
+	Path  []string        `json:"path"`
+	Kind  string          `json:"kind"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// JSONOptions adjusts how documents are merged
+type JSONOptions struct {
+	// Atomic lists slash separated path patterns whose values are merged
+	// as a whole: they are added when missing and otherwise left alone
+	Atomic []string
+}
+
+// JSONResult is the outcome of merging an upstream document
+type JSONResult struct {
+	// Data is the merged document; nil when it didn't change
+	Data          []byte
+	Contributions []Contribution
+	// Kept lists paths where the user's value differs from upstream and won
+	Kept [][]string
+}
+
+// MergeJSON merges the upstream document source into target. Contributions
+// from a previous install are withdrawn first, so changed upstream values
+// replace the old ones. Objects are merged key by key, arrays are unioned
+// and the user's scalar values win.
+func MergeJSON(target, source []byte, previous []Contribution, opts JSONOptions) (*JSONResult, error) {
+	doc, err := decodeJSON(target)
+	if err != nil {
+		return nil, fmt.Errorf("parse target: %w", err)
+	}
+	dst, ok := doc.(*object)
+	if !ok {
+		return nil, fmt.Errorf("target is not a JSON object")
+	}
+
+	up, err := decodeJSON(source)
+	if err != nil {
+		return nil, fmt.Errorf("parse source: %w", err)
+	}
+	src, ok := up.(*object)
+	if !ok {
+		return nil, fmt.Errorf("source is not a JSON object")
+	}
+
+	original := canonicalJSON(dst)
+	withdraw(dst, previous)
+
+	m := &merger{opts: opts}
+	m.mergeObject(dst, src, nil)
+
+	result := &JSONResult{Contributions: m.contribs, Kept: m.kept}
+	if string(canonicalJSON(dst)) != string(original) {
+		result.Data = formatJSON(dst)
+	}
+	return result, nil
+}
+
+// WithdrawJSON removes the contributions still intact in target and
+// reports whether anything but an empty object is left
+func WithdrawJSON(target []byte, contribs []Contribution) ([]byte, bool, error) {
+	doc, err := decodeJSON(target)
+	if err != nil {
+		return nil, false, err
+	}
+	obj, ok := doc.(*object)
+	if !ok {
+		return nil, false, fmt.Errorf("not a JSON object")
+	}
+
+	withdraw(obj, contribs)
+	return formatJSON(obj), len(obj.keys) > 0, nil
+}
+
+// IntactJSON returns the contributions still present unchanged in target
+func IntactJSON(target []byte, contribs []Contribution) ([]Contribution, error) {
+	doc, err := decodeJSON(target)
+	if err != nil {
+		return nil, err
+	}
+	obj, ok := doc.(*object)
+	if !ok {
+		return nil, fmt.Errorf("not a JSON object")
+	}
+
+	var intact []Contribution
+	for _, c := range contribs {
+		if present(obj, c) {
+			intact = append(intact, c)
+		}
+	}
+	return intact, nil
+}
+
+// Digest serializes contributions for hashing. Values are canonicalized,
+// so the digest doesn't depend on how the tracking file was formatted.
+func Digest(contribs []Contribution) []byte {
+	normalized := make([]Contribution, len(contribs))
+	for i, c := range contribs {
+		normalized[i] = c
+		if c.Value != nil {
+			normalized[i].Value = canonicalRaw(c.Value)
+		}
+	}
+	data, _ := json.Marshal(normalized)
+	return data
+}
+
+type merger struct {
+	opts     JSONOptions
+	contribs []Contribution
+	kept     [][]string
+}
+
+func (m *merger) record(p []string, kind string, value any) {
+	c := Contribution{Path: append([]string(nil), p...), Kind: kind}
+	if kind != ContribContainer {
+		c.Value = canonicalJSON(value)
+	}
+	m.contribs = append(m.contribs, c)
+}
+
+func (m *merger) atomic(p []string) bool {
+	joined := strings.Join(p, "/")
+	for _, pattern := range m.opts.Atomic {
+		if ok, _ := path.Match(pattern, joined); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *merger) mergeObject(dst, src *object, parent []string) {
+	for _, key := range src.keys {
+		p := append(append([]string(nil), parent...), key)
+		sv := src.values[key]
+		dv, exists := dst.get(key)
+
+		if !exists {
+			dst.set(key, m.adopt(sv, p))
+			continue
+		}
+		if m.atomic(p) {
+			if !equalJSON(dv, sv) {
+				m.kept = append(m.kept, p)
+			}
+			continue
+		}
+
+		switch sv := sv.(type) {
+		case *object:
+			if dobj, ok := dv.(*object); ok {
+				m.mergeObject(dobj, sv, p)
+				continue
+			}
+		case []any:
+			if darr, ok := dv.([]any); ok {
+				for _, item := range sv {
+					if !containsJSON(darr, item) {
+						darr = append(darr, item)
+						m.record(p, ContribItem, item)
+					}
+				}
+				dst.set(key, darr)
+				continue
+			}
+		}
+
+		// Scalars and mismatched types: the user's value wins
+		if !equalJSON(dv, sv) {
+			m.kept = append(m.kept, p)
+		}
+	}
+}
+
+// adopt returns the value to insert for a key the target lacks, recording
+// objects and arrays as containers so the user's later additions to them
+// survive a removal
+func (m *merger) adopt(v any, p []string) any {
+	if m.atomic(p) {
+		m.record(p, ContribValue, v)
+		return v
+	}
+
+	switch v := v.(type) {
+	case *object:
+		m.record(p, ContribContainer, nil)
+		obj := newObject()
+		for _, key := range v.keys {
+			obj.set(key, m.adopt(v.values[key], append(append([]string(nil), p...), key)))
+		}
+		return obj
+	case []any:
+		m.record(p, ContribContainer, nil)
+		for _, item := range v {
+			m.record(p, ContribItem, item)
+		}
+		return append([]any{}, v...)
+	default:
+		m.record(p, ContribValue, v)
+		return v
+	}
+}
+
+// withdraw removes intact contributions in reverse order, so containers
+// are checked after their contents
+func withdraw(doc *object, contribs []Contribution) {
+	for i := len(contribs) - 1; i >= 0; i-- {
+		c := contribs[i]
+		if len(c.Path) == 0 {
+			continue
+		}
+		parent, key, ok := lookupParent(doc, c.Path)
+		if !ok {
+			continue
+		}
+		current, exists := parent.get(key)
+		if !exists {
+			continue
+		}
+
+		switch c.Kind {
+		case ContribValue:
+			if string(canonicalJSON(current)) == string(canonicalRaw(c.Value)) {
+				parent.remove(key)
+			}
+		case ContribItem:
+			arr, ok := current.([]any)
+			if !ok {
+				continue
+			}
+			if j := indexJSON(arr, canonicalRaw(c.Value)); j >= 0 {
+				parent.set(key, append(arr[:j:j], arr[j+1:]...))
+			}
+		case ContribContainer:
+			switch v := current.(type) {
+			case *object:
+				if len(v.keys) == 0 {
+					parent.remove(key)
+				}
+			case []any:
+				if len(v) == 0 {
+					parent.remove(key)
+				}
+			}
+		}
+	}
+}
+
+// present reports whether a contribution is still in doc unchanged
+func present(doc *object, c Contribution) bool {
+	if len(c.Path) == 0 {
+		return false
+	}
+	parent, key, ok := lookupParent(doc, c.Path)
+	if !ok {
+		return false
+	}
+	current, exists := parent.get(key)
+	if !exists {
+		return false
+	}
+
+	switch c.Kind {
+	case ContribValue:
+		return string(canonicalJSON(current)) == string(canonicalRaw(c.Value))
+	case ContribItem:
+		arr, ok := current.([]any)
+		return ok && indexJSON(arr, canonicalRaw(c.Value)) >= 0
+	}
+	return true
+}
+
+// lookupParent returns the object holding the last element of p
+func lookupParent(doc *object, p []string) (*object, string, bool) {
+	obj := doc
+	for _, key := range p[:len(p)-1] {
+		next, ok := obj.get(key)
+		if !ok {
+			return nil, "", false
+		}
+		if obj, ok = next.(*object); !ok {
+			return nil, "", false
+		}
+	}
+	return obj, p[len(p)-1], true
+}
+
+func containsJSON(arr []any, v any) bool {
+	return indexJSON(arr, canonicalJSON(v)) >= 0
+}
+
+func indexJSON(arr []any, canonical []byte) int {
+	for i, item := range arr {
+		if string(canonicalJSON(item)) == string(canonical) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,139 @@
+package merge
+
+import (
+	"strings"
+	"testing"
+)
+
+const userSettings = `{
+  "model": "opus",
+  "permissions": {
+    "allow": ["Bash(ls:*)"]
+  },
+  "hooks": {
+    "PreToolUse": [
+      {"matcher": "Bash", "hooks": [{"type": "command", "command": "audit.sh"}]}
+    ]
+  }
+}
+`
+
+const upstreamSettings = `{
+  "model": "sonnet",
+  "permissions": {
+    "allow": ["Bash(ls:*)", "Bash(npm test:*)"],
+    "deny": ["Read(.env)"]
+  },
+  "hooks": {
+    "PreToolUse": [
+      {"matcher": "Edit", "hooks": [{"type": "command", "command": "fmt.sh"}]}
+    ],
+    "Stop": [
+      {"hooks": [{"type": "command", "command": "notify.sh"}]}
+    ]
+  },
+  "env": {"TEAM": "billing"}
+}`
+
+func TestMergeJSON(t *testing.T) {
+	result, err := MergeJSON([]byte(userSettings), []byte(upstreamSettings), nil, JSONOptions{})
+	if err != nil {
+		t.Fatalf("MergeJSON failed: %v", err)
+	}
+
+	merged := string(result.Data)
+	for _, want := range []string{`"model": "opus"`, `"Bash(npm test:*)"`, `"Read(.env)"`, `"audit.sh"`, `"fmt.sh"`, `"notify.sh"`, `"TEAM": "billing"`} {
+		if !strings.Contains(merged, want) {
+			t.Errorf("Merged settings lack %s:\n%s", want, merged)
+		}
+	}
+	if strings.Count(merged, `"Bash(ls:*)"`) != 1 {
+		t.Errorf("Arrays must be unioned without duplicates:\n%s", merged)
+	}
+	// The user's keys keep their place
+	if strings.Index(merged, `"model"`) > strings.Index(merged, `"permissions"`) {
+		t.Errorf("Key order changed:\n%s", merged)
+	}
+	if len(result.Kept) != 1 || strings.Join(result.Kept[0], ".") != "model" {
+		t.Errorf("Kept = %v, want the user's model", result.Kept)
+	}
+
+	// Merging again is a no-op
+	again, err := MergeJSON(result.Data, []byte(upstreamSettings), result.Contributions, JSONOptions{})
+	if err != nil || again.Data != nil {
+		t.Errorf("Re-merging should not change the document, got %s, %v", again.Data, err)
+	}
+
+	// Withdrawing restores the user's document
+	restored, nonEmpty, err := WithdrawJSON(result.Data, result.Contributions)
+	if err != nil || !nonEmpty {
+		t.Fatalf("WithdrawJSON() = %v, %v", nonEmpty, err)
+	}
+	if !equalDocs(t, restored, []byte(userSettings)) {
+		t.Errorf("Withdrawn document = %s, want the user's settings", restored)
+	}
+}
+
+func TestMergeJSONUpdatesAndModifications(t *testing.T) {
+	first, _ := MergeJSON(nil, []byte(`{"env": {"A": "1", "B": "1"}}`), nil, JSONOptions{})
+
+	// The user changes one of our values
+	edited := strings.Replace(string(first.Data), `"B": "1"`, `"B": "mine"`, 1)
+	intact, err := IntactJSON([]byte(edited), first.Contributions)
+	if err != nil || len(intact) != len(first.Contributions)-1 {
+		t.Errorf("IntactJSON() = %v, %v; the edited value should no longer count", intact, err)
+	}
+
+	// Upstream changes both; only the untouched one follows
+	second, err := MergeJSON([]byte(edited), []byte(`{"env": {"A": "2", "B": "2"}}`), first.Contributions, JSONOptions{})
+	if err != nil {
+		t.Fatalf("MergeJSON failed: %v", err)
+	}
+	if !equalDocs(t, second.Data, []byte(`{"env": {"A": "2", "B": "mine"}}`)) {
+		t.Errorf("Updated document = %s", second.Data)
+	}
+
+	// Atomic values are added whole or not at all
+	atomic, _ := MergeJSON([]byte(`{"servers": {"db": {"command": "mine"}}}`), []byte(`{"servers": {"db": {"command": "theirs", "args": []}, "api": {"command": "api"}}}`), nil, JSONOptions{Atomic: []string{"servers/*"}})
+	if !equalDocs(t, atomic.Data, []byte(`{"servers": {"db": {"command": "mine"}, "api": {"command": "api"}}}`)) {
+		t.Errorf("Atomic merge = %s", atomic.Data)
+	}
+}
+
+func equalDocs(t *testing.T, a, b []byte) bool {
+	t.Helper()
+	da, err := decodeJSON(a)
+	if err != nil {
+		t.Fatalf("Invalid JSON %s: %v", a, err)
+	}
+	db, err := decodeJSON(b)
+	if err != nil {
+		t.Fatalf("Invalid JSON %s: %v", b, err)
+	}
+	return equalJSON(da, db)
+}
+
+func TestUnsetPlaceholders(t *testing.T) {
+	source := []byte(`{"mcpServers": {
+  "db": {"command": "db-mcp", "env": {"DSN": "${DB_DSN}", "TOKEN": "${TOKEN}", "MODE": "${MODE:-ro}"}},
+  "api": {"url": "https://${API_HOST}/mcp", "headers": {"X-Token": "${TOKEN}"}}
+}}`)
+	env := map[string]string{"TOKEN": "t"}
+
+	unset, err := UnsetPlaceholders(source, func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	})
+	if err != nil {
+		t.Fatalf("UnsetPlaceholders failed: %v", err)
+	}
+	want := []Placeholder{{Server: "db", Variable: "DB_DSN"}, {Server: "api", Variable: "API_HOST"}}
+	if len(unset) != len(want) {
+		t.Fatalf("UnsetPlaceholders() = %v, want %v", unset, want)
+	}
+	for i := range want {
+		if unset[i] != want[i] {
+			t.Errorf("UnsetPlaceholders()[%d] = %v, want %v", i, unset[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package merge
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// JSON documents are decoded into *object, []any, string, json.Number,
+// bool and nil. Objects keep their key order so merged files stay close to
+// what the user wrote.
+
+// object is a JSON object that remembers the order of its keys
+type object struct {
+	keys   []string
+	values map[string]any
+}
+
+func newObject() *object {
+	return &object{values: make(map[string]any)}
+}
+
+func (o *object) get(key string) (any, bool) {
+	v, ok := o.values[key]
+	return v, ok
+}
+
+// set replaces the value of key or appends key at the end
+func (o *object) set(key string, value any) {
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = value
+}
+
+func (o *object) remove(key string) {
+	if _, ok := o.values[key]; !ok {
+		return
+	}
+	delete(o.values, key)
+	for i, k := range o.keys {
+		if k == key {
+			o.keys = append(o.keys[:i], o.keys[i+1:]...)
+			break
+		}
+	}
+}
+
+// decodeJSON parses data; empty or blank data is an empty object
+func decodeJSON(data []byte) (any, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return newObject(), nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	v, err := decodeValue(dec)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after JSON value")
+	}
+	return v, nil
+}
+
+func decodeValue(dec *json.Decoder) (any, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return tok, nil
+	}
+
+	switch delim {
+	case '{':
+		obj := newObject()
+		for dec.More() {
+			keyTok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			obj.set(keyTok.(string), value)
+		}
+		_, err := dec.Token()
+		return obj, err
+	case '[':
+		arr := []any{}
+		for dec.More() {
+			value, err := decodeValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, value)
+		}
+		_, err := dec.Token()
+		return arr, err
+	}
+	return nil, fmt.Errorf("unexpected %v", delim)
+}
+
+// formatJSON encodes v indented by two spaces, keeping key order
+func formatJSON(v any) []byte {
+	var buf bytes.Buffer
+	encodeValue(&buf, v, true, "")
+	buf.WriteByte('\n')
+	return buf.Bytes()
+}
+
+// canonicalJSON encodes v compactly with sorted keys, so equal values
+// encode identically
+func canonicalJSON(v any) []byte {
+	var buf bytes.Buffer
+	encodeValue(&buf, v, false, "")
+	return buf.Bytes()
+}
+
+// canonicalRaw re-encodes raw canonically; invalid JSON is returned as is
+func canonicalRaw(raw []byte) []byte {
+	v, err := decodeJSON(raw)
+	if err != nil {
+		return raw
+	}
+	return canonicalJSON(v)
+}
+
+func equalJSON(a, b any) bool {
+	return bytes.Equal(canonicalJSON(a), canonicalJSON(b))
+}
+
+func encodeValue(buf *bytes.Buffer, v any, pretty bool, indent string) {
+	inner := indent + "  "
+	switch v := v.(type) {
+	case *object:
+		keys := v.keys
+		if !pretty {
+			keys = append([]string(nil), keys...)
+			sort.Strings(keys)
+		}
+		if len(keys) == 0 {
+			buf.WriteString("{}")
+			return
+		}
+		buf.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if pretty {
+				buf.WriteString("\n" + inner)
+			}
+			encodeString(buf, key)
+			buf.WriteByte(':')
+			if pretty {
+				buf.WriteByte(' ')
+			}
+			encodeValue(buf, v.values[key], pretty, inner)
+		}
+		if pretty {
+			buf.WriteString("\n" + indent)
+		}
+		buf.WriteByte('}')
+	case []any:
+		if len(v) == 0 {
+			buf.WriteString("[]")
+			return
+		}
+		buf.WriteByte('[')
+		for i, item := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if pretty {
+				buf.WriteString("\n" + inner)
+			}
+			encodeValue(buf, item, pretty, inner)
+		}
+		if pretty {
+			buf.WriteString("\n" + indent)
+		}
+		buf.WriteByte(']')
+	case string:
+		encodeString(buf, v)
+	case json.Number:
+		buf.WriteString(v.String())
+	case bool:
+		if v {
+			buf.WriteString("true")
+		} else {
+			buf.WriteString("false")
+		}
+	default:
+		buf.WriteString("null")
+	}
+}
+
+// encodeString writes s as a JSON string without escaping HTML characters,
+// which are common in shell commands
+func encodeString(buf *bytes.Buffer, s string) {
+	var tmp bytes.Buffer
+	enc := json.NewEncoder(&tmp)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	buf.WriteString(strings.TrimSuffix(tmp.String(), "\n"))
+}
@@ -0,0 +1,61 @@
+package merge
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// ModeMCP merges MCP server definitions into .mcp.json by server name
+const ModeMCP = "mcp"
+
+// MCPOptions merge each server as a whole: servers the user defined under
+// the same name are never changed
+var MCPOptions = JSONOptions{Atomic: []string{"mcpServers/*"}}
+
+// IsJSON reports whether mode merges JSON documents key by key
+func IsJSON(mode string) bool {
+	return mode == ModeJSON || mode == ModeMCP
+}
+
+// placeholderPattern matches ${VAR} and ${VAR:-default}
+var placeholderPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-[^}]*)?\}`)
+
+// Placeholder is an environment variable an MCP server refers to
+type Placeholder struct {
+	Server   string
+	Variable string
+}
+
+// UnsetPlaceholders returns the ${VAR} placeholders in the servers of an
+// .mcp.json document for which lookup finds no value. Placeholders with a
+// default are always satisfied.
+func UnsetPlaceholders(source []byte, lookup func(string) (string, bool)) ([]Placeholder, error) {
+	doc, err := decodeJSON(source)
+	if err != nil {
+		return nil, err
+	}
+	obj, ok := doc.(*object)
+	if !ok {
+		return nil, fmt.Errorf("not a JSON object")
+	}
+	servers, ok := obj.values["mcpServers"].(*object)
+	if !ok {
+		return nil, nil
+	}
+
+	var unset []Placeholder
+	for _, name := range servers.keys {
+		seen := make(map[string]bool)
+		for _, match := range placeholderPattern.FindAllStringSubmatch(string(canonicalJSON(servers.values[name])), -1) {
+			variable, hasDefault := match[1], match[2] != ""
+			if hasDefault || seen[variable] {
+				continue
+			}
+			seen[variable] = true
+			if _, ok := lookup(variable); !ok {
+				unset = append(unset, Placeholder{Server: name, Variable: variable})
+			}
+		}
+	}
+	return unset, nil
+}
@@ -0,0 +1,96 @@
+package project
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/doodleEsc/ctx-tool/internal/git"
+)
+
+// Facts are detected properties of a project, available to templates
+type Facts struct {
+	Name     string
+	Root     string
+	Remote   string
+	Language string
+}
+
+// languageMarkers identify the primary language by files in the project
+// root, checked in order
+var languageMarkers = []struct {
+	File     string
+	Language string
+}{
+	{"go.mod", "Go"},
+	{"Cargo.toml", "Rust"},
+	{"tsconfig.json", "TypeScript"},
+	{"package.json", "JavaScript"},
+	{"pyproject.toml", "Python"},
+	{"requirements.txt", "Python"},
+	{"setup.py", "Python"},
+	{"pom.xml", "Java"},
+	{"build.gradle", "Java"},
+	{"build.gradle.kts", "Kotlin"},
+	{"Gemfile", "Ruby"},
+	{"composer.json", "PHP"},
+}
+
+// Detect gathers facts about the project rooted at root. The name comes
+// from go.mod or package.json and falls back to the directory name. A
+// failure to read the git remote leaves it empty.
+func Detect(root string) Facts {
+	facts := Facts{Root: root, Name: filepath.Base(root)}
+
+	if name := moduleName(filepath.Join(root, "go.mod")); name != "" {
+		facts.Name = name
+	} else if name := packageName(filepath.Join(root, "package.json")); name != "" {
+		facts.Name = name
+	}
+
+	facts.Remote, _ = git.OriginURL(root)
+
+	for _, marker := range languageMarkers {
+		if _, err := os.Stat(filepath.Join(root, marker.File)); err == nil {
+			facts.Language = marker.Language
+			break
+		}
+	}
+	return facts
+}
+
+// moduleName returns the last element of the module path in a go.mod file
+func moduleName(goMod string) string {
+	file, err := os.Open(goMod)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if rest, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "module "); ok {
+			return path.Base(strings.Trim(strings.TrimSpace(rest), `"`))
+		}
+	}
+	return ""
+}
+
+// packageName returns the name in a package.json file without its scope
+func packageName(packageJSON string) string {
+	data, err := os.ReadFile(packageJSON)
+	if err != nil {
+		return ""
+	}
+
+	var pkg struct {
+		Name string `json:"name"`
+	}
+	if json.Unmarshal(data, &pkg) != nil {
+		return ""
+	}
+	return path.Base(pkg.Name)
+}
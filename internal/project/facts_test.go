@@ -0,0 +1,37 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+)
+
+func TestDetect(t *testing.T) {
+	root := t.TempDir()
+	repo, err := git.PlainInit(root, false)
+	if err != nil {
+		t.Fatalf("Failed to init repository: %v", err)
+	}
+	if _, err := repo.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{"git@example.com:acme/billing.git"}}); err != nil {
+		t.Fatalf("Failed to create remote: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "go.mod"), []byte("module example.com/acme/billing-api\n\ngo 1.22\n"), 0644); err != nil {
+		t.Fatalf("Failed to write go.mod: %v", err)
+	}
+
+	facts := Detect(root)
+	want := Facts{Name: "billing-api", Root: root, Remote: "git@example.com:acme/billing.git", Language: "Go"}
+	if facts != want {
+		t.Errorf("Detect() = %+v, want %+v", facts, want)
+	}
+
+	// Without markers the directory name is used
+	plain := filepath.Join(t.TempDir(), "web")
+	mkdirAll(t, plain)
+	if facts := Detect(plain); facts.Name != "web" || facts.Language != "" || facts.Remote != "" {
+		t.Errorf("Detect() = %+v, want name web and nothing else", facts)
+	}
+}
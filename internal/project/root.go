@@ -0,0 +1,104 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/doodleEsc/ctx-tool/internal/apperr"
+	"github.com/doodleEsc/ctx-tool/internal/git"
+)
+
+// Reasons a directory was chosen as project root
+const (
+	ReasonTrackingFile = "tracking_file"
+	ReasonClaudeDir    = "claude_dir"
+	ReasonGitRoot      = "git_root"
+	ReasonWorkingDir   = "working_dir"
+)
+
+// Root is a discovered project root
+type Root struct {
+	Path   string
+	Reason string
+}
+
+// FindRoot walks up from start to the nearest directory holding the tracking
+// file or a .claude directory. The walk stops at the git worktree root, which
+// is used when nothing closer is found; outside a worktree start itself is
+// the root. The home directory is skipped because its .claude directory and
+// tracking file belong to the global scope.
+func FindRoot(start, trackingFile string) (Root, error) {
+	start, err := filepath.Abs(start)
+	if err != nil {
+		return Root{}, err
+	}
+
+	gitRoot, err := git.WorktreeRoot(start)
+	if err != nil {
+		return Root{}, err
+	}
+	homeDir, _ := os.UserHomeDir()
+
+	for dir := start; ; dir = filepath.Dir(dir) {
+		if dir != homeDir {
+			if trackingFile != "" && !filepath.IsAbs(trackingFile) && isFile(filepath.Join(dir, trackingFile)) {
+				return Root{Path: dir, Reason: ReasonTrackingFile}, nil
+			}
+			if isDir(filepath.Join(dir, ".claude")) {
+				return Root{Path: dir, Reason: ReasonClaudeDir}, nil
+			}
+		}
+
+		if dir == gitRoot {
+			return Root{Path: dir, Reason: ReasonGitRoot}, nil
+		}
+		if dir == filepath.Dir(dir) {
+			return Root{Path: start, Reason: ReasonWorkingDir}, nil
+		}
+	}
+}
+
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// RelativeArgs turns path arguments given from cwd into paths relative to
+// root, so "." in a subdirectory selects that directory only. When cwd lies
+// outside root, e.g. with --target, relative arguments already refer to root
+// and are kept. Arguments leading outside root are rejected.
+func RelativeArgs(root, cwd string, args []string) ([]string, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	prefix, err := filepath.Rel(root, cwd)
+	if err != nil || !filepath.IsLocal(prefix) {
+		prefix = ""
+	}
+
+	rebased := make([]string, len(args))
+	for i, arg := range args {
+		path := arg
+		switch {
+		case filepath.IsAbs(arg):
+			if path, err = filepath.Rel(root, arg); err != nil {
+				return nil, apperr.New(apperr.Usage, "%s is outside %s", arg, root)
+			}
+		case prefix != "":
+			path = filepath.Join(prefix, arg)
+		}
+		path = filepath.Clean(path)
+		if path == ".." || strings.HasPrefix(path, ".."+string(filepath.Separator)) {
+			return nil, apperr.New(apperr.Usage, "%s is outside %s", arg, root)
+		}
+		rebased[i] = path
+	}
+	return rebased, nil
+}
@@ -0,0 +1,102 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+)
+
+func mkdirAll(t *testing.T, path string) {
+	t.Helper()
+	if err := os.MkdirAll(path, 0755); err != nil {
+		t.Fatalf("Failed to create %s: %v", path, err)
+	}
+}
+
+func TestFindRoot(t *testing.T) {
+	repo := t.TempDir()
+	if _, err := git.PlainInit(repo, false); err != nil {
+		t.Fatalf("Failed to init repository: %v", err)
+	}
+	subDir := filepath.Join(repo, "src", "api")
+	mkdirAll(t, subDir)
+
+	// Nothing but the worktree: the git root wins
+	root, err := FindRoot(subDir, ".ctx-tool-tracking.json")
+	if err != nil {
+		t.Fatalf("FindRoot failed: %v", err)
+	}
+	if root.Path != repo || root.Reason != ReasonGitRoot {
+		t.Errorf("FindRoot() = %+v, want git root %s", root, repo)
+	}
+
+	// A closer .claude directory wins over the git root
+	mkdirAll(t, filepath.Join(repo, "src", ".claude"))
+	root, _ = FindRoot(subDir, ".ctx-tool-tracking.json")
+	if root.Path != filepath.Join(repo, "src") || root.Reason != ReasonClaudeDir {
+		t.Errorf("FindRoot() = %+v, want .claude dir in src", root)
+	}
+
+	// A tracking file in the start directory wins over everything
+	if err := os.WriteFile(filepath.Join(subDir, ".ctx-tool-tracking.json"), []byte("{}"), 0644); err != nil {
+		t.Fatalf("Failed to write tracking file: %v", err)
+	}
+	root, _ = FindRoot(subDir, ".ctx-tool-tracking.json")
+	if root.Path != subDir || root.Reason != ReasonTrackingFile {
+		t.Errorf("FindRoot() = %+v, want tracking file in %s", root, subDir)
+	}
+}
+
+func TestFindRootOutsideRepository(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "plain")
+	mkdirAll(t, dir)
+
+	root, err := FindRoot(dir, ".ctx-tool-tracking.json")
+	if err != nil {
+		t.Fatalf("FindRoot failed: %v", err)
+	}
+	if root.Path != dir || root.Reason != ReasonWorkingDir {
+		t.Errorf("FindRoot() = %+v, want the start directory", root)
+	}
+}
+
+func TestRelativeArgs(t *testing.T) {
+	root := t.TempDir()
+	commands := filepath.Join(root, ".claude", "commands")
+	mkdirAll(t, commands)
+	t.Chdir(commands)
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := RelativeArgs(root, cwd, []string{".", "review.md", "../agents", "*.md", filepath.Join(root, "PRPs")})
+	if err != nil {
+		t.Fatalf("RelativeArgs failed: %v", err)
+	}
+	want := []string{
+		filepath.Join(".claude", "commands"),
+		filepath.Join(".claude", "commands", "review.md"),
+		filepath.Join(".claude", "agents"),
+		filepath.Join(".claude", "commands", "*.md"),
+		"PRPs",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("RelativeArgs() = %v, want %v", got, want)
+	}
+
+	for _, arg := range []string{"../../..", filepath.Dir(root)} {
+		if _, err := RelativeArgs(root, cwd, []string{arg}); err == nil {
+			t.Errorf("RelativeArgs(%s) should be refused", arg)
+		}
+	}
+
+	// From outside the root, as with --target, arguments refer to the root
+	got, err = RelativeArgs(root, t.TempDir(), []string{".claude"})
+	if err != nil || !reflect.DeepEqual(got, []string{".claude"}) {
+		t.Errorf("RelativeArgs() from outside = %v, %v", got, err)
+	}
+}
@@ -0,0 +1,170 @@
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/doodleEsc/ctx-tool/internal/apperr"
+	"github.com/doodleEsc/ctx-tool/internal/lockfile"
+)
+
+// Project is an installation known to the registry
+type Project struct {
+	// Path is the absolute directory files were installed into
+	Path         string `json:"path"`
+	Scope        string `json:"scope"`
+	TrackingFile string `json:"tracking_file"`
+
+	// Upstream the project was last installed from
+	Repository string `json:"repository,omitempty"`
+	Branch     string `json:"branch,omitempty"`
+	Revision   string `json:"revision,omitempty"`
+	// InstallMode is the strongest link the installation uses into the
+	// store: symlink, hardlink or copy. Empty for older entries.
+	InstallMode string `json:"install_mode,omitempty"`
+
+	UpdatedAt string `json:"updated_at"`
+}
+
+// Registry lists every project ctx-tool installed into on this machine
+type Registry struct {
+	FilePath string
+	Projects []Project
+}
+
+// Load reads the registry at path; a missing file is an empty registry
+func Load(path string) (*Registry, error) {
+	r := &Registry{FilePath: path, Projects: []Project{}}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return r, nil
+		}
+		return nil, fmt.Errorf("read registry: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &r.Projects); err != nil {
+		return nil, fmt.Errorf("unmarshal registry: %w", err)
+	}
+	return r, nil
+}
+
+// Save writes the registry, replacing the file atomically
+func (r *Registry) Save() error {
+	data, err := json.MarshalIndent(r.Projects, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal registry: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.FilePath), 0755); err != nil {
+		return fmt.Errorf("create registry directory: %w", err)
+	}
+
+	tmp := r.FilePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("write registry: %w", err)
+	}
+	if err := os.Rename(tmp, r.FilePath); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("write registry: %w", err)
+	}
+	return nil
+}
+
+// Register adds p or replaces the project with the same path. The list is
+// kept sorted by path.
+func (r *Registry) Register(p Project) {
+	if p.UpdatedAt == "" {
+		p.UpdatedAt = time.Now().Format(time.RFC3339)
+	}
+
+	for i := range r.Projects {
+		if r.Projects[i].Path == p.Path {
+			r.Projects[i] = p
+			return
+		}
+	}
+
+	r.Projects = append(r.Projects, p)
+	sort.Slice(r.Projects, func(i, j int) bool {
+		return r.Projects[i].Path < r.Projects[j].Path
+	})
+}
+
+// Unregister forgets the project at path and reports whether it was known
+func (r *Registry) Unregister(path string) bool {
+	for i := range r.Projects {
+		if r.Projects[i].Path == path {
+			r.Projects = append(r.Projects[:i], r.Projects[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Find returns the project registered at path
+func (r *Registry) Find(path string) (Project, bool) {
+	for _, p := range r.Projects {
+		if p.Path == path {
+			return p, true
+		}
+	}
+	return Project{}, false
+}
+
+// Prune forgets projects whose tracking file no longer exists and returns them
+func (r *Registry) Prune() []Project {
+	var kept, pruned []Project
+	for _, p := range r.Projects {
+		if _, err := os.Stat(p.TrackingFile); os.IsNotExist(err) {
+			pruned = append(pruned, p)
+			continue
+		}
+		kept = append(kept, p)
+	}
+
+	if kept == nil {
+		kept = []Project{}
+	}
+	r.Projects = kept
+	return pruned
+}
+
+// Update loads the registry at path, applies fn and saves the result while
+// holding a lock, so concurrent ctx-tool runs don't lose each other's
+// changes. It waits a few seconds for the lock before giving up.
+func Update(path string, fn func(*Registry) error) error {
+	unlock, err := lock(path+".lock", 5*time.Second)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	r, err := Load(path)
+	if err != nil {
+		return err
+	}
+	if err := fn(r); err != nil {
+		return err
+	}
+	return r.Save()
+}
+
+func lock(lockPath string, timeout time.Duration) (func() error, error) {
+	unlock, err := lockfile.Acquire(lockPath, timeout)
+	var held *lockfile.HeldError
+	if errors.As(err, &held) {
+		owner := "another ctx-tool process"
+		if held.PID != 0 {
+			owner = fmt.Sprintf("ctx-tool process %d", held.PID)
+		}
+		return nil, apperr.New(apperr.Locked, "project registry is locked by %s; if no ctx-tool process is running, delete %s", owner, lockPath)
+	}
+	return unlock, err
+}
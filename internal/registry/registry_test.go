@@ -0,0 +1,112 @@
+package registry
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestRegistryUpdateAndPrune(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "projects.json")
+
+	live := filepath.Join(dir, "live", ".ctx-tool-tracking.json")
+	if err := os.MkdirAll(filepath.Dir(live), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(live, []byte("{}"), 0644); err != nil {
+		t.Fatalf("Failed to write tracking file: %v", err)
+	}
+
+	err := Update(path, func(r *Registry) error {
+		r.Register(Project{Path: filepath.Join(dir, "live"), Scope: "project", TrackingFile: live, Revision: "a"})
+		r.Register(Project{Path: filepath.Join(dir, "gone"), Scope: "project", TrackingFile: filepath.Join(dir, "gone", ".ctx-tool-tracking.json")})
+		// Registering the same path again replaces the entry
+		r.Register(Project{Path: filepath.Join(dir, "live"), Scope: "project", TrackingFile: live, Revision: "b"})
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	r, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(r.Projects) != 2 {
+		t.Fatalf("Expected 2 projects, got %d", len(r.Projects))
+	}
+	if p, ok := r.Find(filepath.Join(dir, "live")); !ok || p.Revision != "b" {
+		t.Errorf("Expected the live project at revision b, got %+v", p)
+	}
+
+	pruned := r.Prune()
+	if len(pruned) != 1 || pruned[0].Path != filepath.Join(dir, "gone") {
+		t.Errorf("Expected the missing project to be pruned, got %+v", pruned)
+	}
+	if len(r.Projects) != 1 {
+		t.Errorf("Expected 1 project left, got %d", len(r.Projects))
+	}
+}
+
+func TestScan(t *testing.T) {
+	root := t.TempDir()
+	for _, rel := range []string{
+		"a/.ctx-tool-tracking.json",
+		"b/c/.ctx-tool-tracking.json",
+		"node_modules/pkg/.ctx-tool-tracking.json",
+		"d/other.json",
+	} {
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte("{}"), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	found, err := Scan(root, ".ctx-tool-tracking.json")
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+
+	want := []string{
+		filepath.Join(root, "a", ".ctx-tool-tracking.json"),
+		filepath.Join(root, "b", "c", ".ctx-tool-tracking.json"),
+	}
+	if len(found) != len(want) {
+		t.Fatalf("Scan() = %v, want %v", found, want)
+	}
+	for i := range want {
+		if found[i] != want[i] {
+			t.Errorf("Scan()[%d] = %s, want %s", i, found[i], want[i])
+		}
+	}
+}
+
+func TestUpdateTakesOverStaleLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "projects.json")
+
+	// A crashed run leaves a lock naming a process that has exited
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Failed to run helper process: %v", err)
+	}
+	if err := os.WriteFile(path+".lock", []byte(strconv.Itoa(cmd.Process.Pid)+"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write lock file: %v", err)
+	}
+
+	err := Update(path, func(r *Registry) error {
+		r.Register(Project{Path: "/src/api", Scope: "project"})
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Update should take over a stale lock: %v", err)
+	}
+	if _, err := os.Stat(path + ".lock"); !os.IsNotExist(err) {
+		t.Errorf("Lock file left behind: %v", err)
+	}
+}
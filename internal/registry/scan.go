@@ -0,0 +1,46 @@
+package registry
+
+import (
+	"io/fs"
+	"path/filepath"
+)
+
+// skipDirs are never descended into while scanning
+var skipDirs = map[string]bool{
+	".git":         true,
+	"node_modules": true,
+	"vendor":       true,
+}
+
+// Scan walks root and returns the absolute paths of all files named
+// trackingFile. Unreadable directories are skipped.
+func Scan(root, trackingFile string) ([]string, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	name := filepath.Base(trackingFile)
+
+	var found []string
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if d != nil && d.IsDir() && path != root {
+				return filepath.SkipDir
+			}
+			return err
+		}
+
+		if d.IsDir() {
+			if path != root && skipDirs[d.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if d.Name() == name {
+			found = append(found, path)
+		}
+		return nil
+	})
+	return found, err
+}
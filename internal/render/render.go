@@ -0,0 +1,123 @@
+// Package render expands upstream files that opt in to Go text/template
+// rendering with per-project values.
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/doodleEsc/ctx-tool/internal/project"
+)
+
+// Suffix marks a template; it is removed from the installed file name
+const Suffix = ".tmpl"
+
+// FrontmatterFlag in a Markdown file's YAML frontmatter marks the file as a
+// template without renaming it. The flag line is removed when rendering.
+const FrontmatterFlag = "ctx-tool-template: true"
+
+// Data is what templates see. Vars are looked up strictly: a missing
+// variable fails the render instead of producing an empty string.
+type Data struct {
+	Project project.Facts
+	Vars    map[string]string
+	// Env holds only the environment variables callers chose to expose
+	Env map[string]string
+}
+
+// TargetPath returns the installed path of a repository file
+func TargetPath(repoPath string) string {
+	return strings.TrimSuffix(repoPath, Suffix)
+}
+
+// Candidate reports whether the file at repoPath may be a template, so
+// other files needn't be read up front
+func Candidate(repoPath string) bool {
+	return strings.HasSuffix(repoPath, Suffix) || filepath.Ext(repoPath) == ".md"
+}
+
+// IsTemplate reports whether the file at repoPath with the given content
+// should be rendered
+func IsTemplate(repoPath string, content []byte) bool {
+	if strings.HasSuffix(repoPath, Suffix) {
+		return true
+	}
+	if filepath.Ext(repoPath) != ".md" {
+		return false
+	}
+	_, ok := stripFlag(content)
+	return ok
+}
+
+// Render executes content as a template named name
+func Render(name string, content []byte, data *Data) ([]byte, error) {
+	if stripped, ok := stripFlag(content); ok {
+		content = stripped
+	}
+
+	tmpl, err := template.New(name).
+		Option("missingkey=error").
+		Funcs(funcs).
+		Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("parse template %s: %w", name, err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("render template %s: %w", name, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// funcs are available in every template
+var funcs = template.FuncMap{
+	// default returns value unless it is empty:
+	// {{ default "make test" (index .Vars "test_command") }}
+	"default": func(fallback, value string) string {
+		if value == "" {
+			return fallback
+		}
+		return value
+	},
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+	"trim":  strings.TrimSpace,
+}
+
+// stripFlag removes the template flag from the frontmatter of content. The
+// frontmatter is dropped entirely when the flag was its only line.
+func stripFlag(content []byte) ([]byte, bool) {
+	text := string(content)
+	rest, ok := strings.CutPrefix(text, "---\n")
+	if !ok {
+		return content, false
+	}
+	end := strings.Index(rest, "\n---\n")
+	if end < 0 {
+		return content, false
+	}
+
+	lines := strings.Split(rest[:end], "\n")
+	kept := lines[:0]
+	found := false
+	for _, line := range lines {
+		if strings.TrimSpace(line) == FrontmatterFlag {
+			found = true
+			continue
+		}
+		kept = append(kept, line)
+	}
+	if !found {
+		return content, false
+	}
+
+	body := rest[end+len("\n---\n"):]
+	if len(kept) == 0 {
+		return []byte(body), true
+	}
+	return []byte("---\n" + strings.Join(kept, "\n") + "\n---\n" + body), true
+}
@@ -0,0 +1,56 @@
+package render
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/doodleEsc/ctx-tool/internal/project"
+)
+
+func TestIsTemplate(t *testing.T) {
+	tests := []struct {
+		path    string
+		content string
+		want    bool
+	}{
+		{"CLAUDE.md.tmpl", "# {{ .Project.Name }}", true},
+		{".claude/agents/a.md", "---\nname: a\nctx-tool-template: true\n---\nbody", true},
+		{".claude/agents/a.md", "---\nname: a\n---\n{{ not a template }}", false},
+		{"scripts/run.py", "---\nctx-tool-template: true\n---\n", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsTemplate(tt.path, []byte(tt.content)); got != tt.want {
+			t.Errorf("IsTemplate(%q, %q) = %v, want %v", tt.path, tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	data := &Data{
+		Project: project.Facts{Name: "billing", Language: "Go"},
+		Vars:    map[string]string{"test_command": "go test ./..."},
+		Env:     map[string]string{},
+	}
+
+	got, err := Render("agent", []byte("---\nname: tester\nctx-tool-template: true\n---\nRun `{{ .Vars.test_command }}` in {{ .Project.Name }} ({{ lower .Project.Language }}), deploy with {{ default \"make deploy\" (index .Vars \"deploy\") }}\n"), data)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	want := "---\nname: tester\n---\nRun `go test ./...` in billing (go), deploy with make deploy\n"
+	if string(got) != want {
+		t.Errorf("Render() = %q, want %q", got, want)
+	}
+
+	// A frontmatter holding only the flag is dropped
+	got, err = Render("claude", []byte("---\nctx-tool-template: true\n---\n# {{ .Project.Name }}\n"), data)
+	if err != nil || string(got) != "# billing\n" {
+		t.Errorf("Render() = %q, %v, want %q", got, err, "# billing\n")
+	}
+
+	// Missing variables are errors, not empty strings
+	_, err = Render("claude", []byte("{{ .Vars.service_name }}"), data)
+	if err == nil || !strings.Contains(err.Error(), "service_name") {
+		t.Errorf("Expected an error naming the missing variable, got %v", err)
+	}
+}
@@ -0,0 +1,265 @@
+// Package review finds content that lets an agent run commands or reach
+// the network, so an install can show what it newly introduces before
+// anything is written.
+package review
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Kinds of capabilities
+const (
+	// KindHook is a hook command in Claude settings
+	KindHook = "hook"
+	// KindPermission is a permission rule or mode in Claude settings
+	KindPermission = "permission"
+	// KindMCPServer is an MCP server the agent can start or connect to
+	KindMCPServer = "mcp_server"
+	// KindToolGrant is frontmatter allowing a prompt or agent to use Bash
+	KindToolGrant = "tool_grant"
+	// KindScript is an executable script, new or changed
+	KindScript = "script"
+	// KindPipeToShell is a download piped into a shell
+	KindPipeToShell = "pipe_to_shell"
+	// KindURL is an external URL in a prompt
+	KindURL = "external_url"
+)
+
+// Finding is one capability a file grants
+type Finding struct {
+	Kind   string
+	Detail string
+}
+
+// Change lists the capabilities a file gains and loses
+type Change struct {
+	Path    string
+	Added   []Finding
+	Removed []Finding
+}
+
+// File is a version of a file to review; nil Content means it doesn't exist
+type File struct {
+	Content []byte
+	Mode    os.FileMode
+}
+
+var scriptExts = map[string]bool{
+	".sh": true, ".bash": true, ".zsh": true, ".fish": true,
+	".py": true, ".js": true, ".mjs": true, ".cjs": true, ".ts": true,
+	".rb": true, ".pl": true, ".php": true, ".ps1": true,
+}
+
+// interpreters run code they read from stdin or a file argument
+const interpreters = `(?:(?:ba|z|da|k)?sh|fish|python[0-9.]*|perl|ruby|node|php)`
+
+var (
+	// pipeToShell matches downloads fed to an interpreter: piped into it,
+	// possibly through sudo, or passed as a process or command substitution
+	pipeToShell = regexp.MustCompile(`\b(?:curl|wget)\b[^|\n]*\|\s*(?:sudo(?:\s+-\S+)*\s+)?` + interpreters + `\b` +
+		`|(?:\b` + interpreters + `|\bsource|(?:^|\s)\.)\s+(?:-\S+\s+)*["']?(?:<|\$)\(\s*(?:curl|wget)\b[^)\n]*\)`)
+	externalURL = regexp.MustCompile(`https?://[^\s)>\]"'` + "`" + `]+`)
+)
+
+// maxDetail keeps long commands readable in a report
+const maxDetail = 120
+
+// Compare reports the capabilities incoming adds to or removes from the
+// installed version of the file at path
+func Compare(path string, incoming, installed File) Change {
+	change := Change{Path: path}
+	before := index(Scan(path, installed))
+	after := index(Scan(path, incoming))
+
+	for key, f := range after {
+		if _, ok := before[key]; !ok {
+			change.Added = append(change.Added, f)
+		}
+	}
+	for key, f := range before {
+		if _, ok := after[key]; !ok {
+			change.Removed = append(change.Removed, f)
+		}
+	}
+
+	// A script that changes can do anything new
+	if isScript(path, incoming) && isScript(path, installed) && !bytes.Equal(incoming.Content, installed.Content) {
+		change.Added = append(change.Added, Finding{Kind: KindScript, Detail: "changed"})
+	}
+
+	sortFindings(change.Added)
+	sortFindings(change.Removed)
+	return change
+}
+
+// Scan returns the capabilities a file grants
+func Scan(path string, f File) []Finding {
+	if f.Content == nil {
+		return nil
+	}
+
+	var findings []Finding
+	if isScript(path, f) {
+		findings = append(findings, Finding{Kind: KindScript})
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		findings = append(findings, scanJSON(f.Content)...)
+	case ".md", ".mdc":
+		findings = append(findings, scanFrontmatter(f.Content)...)
+		for _, url := range externalURL.FindAllString(string(f.Content), -1) {
+			findings = append(findings, Finding{Kind: KindURL, Detail: strings.TrimRight(url, ".,;:")})
+		}
+	}
+
+	for _, command := range pipeToShell.FindAllString(string(f.Content), -1) {
+		findings = append(findings, Finding{Kind: KindPipeToShell, Detail: truncate(command)})
+	}
+	return findings
+}
+
+// isScript reports whether f is a script: by extension, shebang or
+// executable bit
+func isScript(path string, f File) bool {
+	if f.Content == nil {
+		return false
+	}
+	return scriptExts[strings.ToLower(filepath.Ext(path))] ||
+		bytes.HasPrefix(f.Content, []byte("#!")) ||
+		f.Mode&0111 != 0
+}
+
+// scanJSON finds hooks, permissions and MCP servers in Claude settings
+func scanJSON(content []byte) []Finding {
+	var doc map[string]any
+	if err := json.Unmarshal(content, &doc); err != nil {
+		return nil
+	}
+
+	var findings []Finding
+	if hooks, ok := doc["hooks"].(map[string]any); ok {
+		for hookEvent, entries := range hooks {
+			for _, entry := range asSlice(entries) {
+				entry, _ := entry.(map[string]any)
+				matcher, _ := entry["matcher"].(string)
+				for _, hook := range asSlice(entry["hooks"]) {
+					hook, _ := hook.(map[string]any)
+					command, _ := hook["command"].(string)
+					detail := hookEvent
+					if matcher != "" {
+						detail += " [" + matcher + "]"
+					}
+					findings = append(findings, Finding{Kind: KindHook, Detail: truncate(detail + ": " + command)})
+				}
+			}
+		}
+	}
+
+	if permissions, ok := doc["permissions"].(map[string]any); ok {
+		for _, rule := range asSlice(permissions["allow"]) {
+			if rule, ok := rule.(string); ok {
+				findings = append(findings, Finding{Kind: KindPermission, Detail: "allow " + rule})
+			}
+		}
+		if mode, ok := permissions["defaultMode"].(string); ok && mode == "bypassPermissions" {
+			findings = append(findings, Finding{Kind: KindPermission, Detail: "defaultMode " + mode})
+		}
+	}
+
+	if servers, ok := doc["mcpServers"].(map[string]any); ok {
+		for name, server := range servers {
+			server, _ := server.(map[string]any)
+			detail := name
+			if url, ok := server["url"].(string); ok {
+				detail += ": " + url
+			} else if command, ok := server["command"].(string); ok {
+				parts := []string{command}
+				for _, arg := range asSlice(server["args"]) {
+					parts = append(parts, fmt.Sprint(arg))
+				}
+				detail += ": " + strings.Join(parts, " ")
+			}
+			findings = append(findings, Finding{Kind: KindMCPServer, Detail: truncate(detail)})
+		}
+	}
+	return findings
+}
+
+// scanFrontmatter finds tool lists granting Bash in Markdown frontmatter
+func scanFrontmatter(content []byte) []Finding {
+	text := strings.ReplaceAll(string(content), "\r\n", "\n")
+	rest, ok := strings.CutPrefix(text, "---\n")
+	if !ok {
+		return nil
+	}
+	end := strings.Index(rest, "\n---")
+	if end < 0 {
+		return nil
+	}
+
+	var meta map[string]any
+	if err := yaml.Unmarshal([]byte(rest[:end]), &meta); err != nil {
+		return nil
+	}
+
+	var findings []Finding
+	for _, key := range []string{"allowed-tools", "tools"} {
+		var tools []string
+		switch v := meta[key].(type) {
+		case string:
+			for _, tool := range strings.Split(v, ",") {
+				tools = append(tools, strings.TrimSpace(tool))
+			}
+		case []any:
+			for _, tool := range v {
+				tools = append(tools, fmt.Sprint(tool))
+			}
+		}
+		for _, tool := range tools {
+			if tool == "Bash" || strings.HasPrefix(tool, "Bash(") {
+				findings = append(findings, Finding{Kind: KindToolGrant, Detail: key + ": " + tool})
+			}
+		}
+	}
+	return findings
+}
+
+func asSlice(v any) []any {
+	s, _ := v.([]any)
+	return s
+}
+
+func index(findings []Finding) map[string]Finding {
+	m := make(map[string]Finding, len(findings))
+	for _, f := range findings {
+		m[f.Kind+"\x00"+f.Detail] = f
+	}
+	return m
+}
+
+func sortFindings(findings []Finding) {
+	sort.Slice(findings, func(i, j int) bool {
+		if findings[i].Kind != findings[j].Kind {
+			return findings[i].Kind < findings[j].Kind
+		}
+		return findings[i].Detail < findings[j].Detail
+	})
+}
+
+func truncate(s string) string {
+	runes := []rune(s)
+	if len(runes) <= maxDetail {
+		return s
+	}
+	return string(runes[:maxDetail-3]) + "..."
+}
@@ -0,0 +1,16 @@
+//go:build unix
+
+package store
+
+import (
+	"os"
+	"syscall"
+)
+
+// linkCount returns the number of hardlinks to a file
+func linkCount(info os.FileInfo) (uint64, bool) {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(stat.Nlink), true
+	}
+	return 0, false
+}
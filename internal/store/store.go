@@ -0,0 +1,312 @@
+// Package store keeps upstream files in a content-addressed store, so the
+// same file installed into many projects is kept on disk once and can be
+// linked into them.
+package store
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/doodleEsc/ctx-tool/internal/apperr"
+	"github.com/doodleEsc/ctx-tool/internal/checksum"
+	"github.com/doodleEsc/ctx-tool/internal/lockfile"
+)
+
+// Install modes
+const (
+	// Copy writes an independent copy into the project
+	Copy = "copy"
+	// Hardlink links the project file to the stored object
+	Hardlink = "hardlink"
+	// Symlink points the project file at the stored object
+	Symlink = "symlink"
+)
+
+// PruneGrace is how long Prune keeps an object no registered project links
+// to after it was last added, covering installs not registered yet
+const PruneGrace = 24 * time.Hour
+
+// ParseMode validates an install mode; empty means Copy
+func ParseMode(mode string) (string, error) {
+	switch mode {
+	case "", Copy:
+		return Copy, nil
+	case Hardlink, Symlink:
+		return mode, nil
+	}
+	return "", apperr.New(apperr.Usage, "unknown install mode %q (want copy, hardlink or symlink)", mode)
+}
+
+// Store holds objects named by the checksum of their content
+type Store struct {
+	Dir       string
+	Algorithm checksum.Algorithm
+}
+
+func New(dir string, algo checksum.Algorithm) *Store {
+	return &Store{Dir: dir, Algorithm: algo}
+}
+
+// Path returns where the object with checksum sum is kept
+func (s *Store) Path(sum string) string {
+	return filepath.Join(s.Dir, string(s.Algorithm), sum[:2], sum)
+}
+
+// Put adds content to the store and returns its checksum. Objects are
+// read-only and keep the executable bits of perm. An existing object is
+// reused only if it still has its content; one that was edited in place
+// through a hardlink is replaced, so new links don't share the edit.
+func (s *Store) Put(content io.Reader, perm os.FileMode) (string, error) {
+	tmpDir := filepath.Join(s.Dir, "tmp")
+	if err := os.MkdirAll(tmpDir, 0755); err != nil {
+		return "", fmt.Errorf("create store directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(tmpDir, "object-*")
+	if err != nil {
+		return "", fmt.Errorf("create store object: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	h, err := s.Algorithm.New()
+	if err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if _, err := io.Copy(io.MultiWriter(tmp, h), content); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("write store object: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("write store object: %w", err)
+	}
+	sum := fmt.Sprintf("%x", h.Sum(nil))
+
+	objPath := s.Path(sum)
+	if _, err := os.Stat(objPath); err == nil {
+		if ok, err := s.Verify(sum); err == nil && ok {
+			// A reused object is as new as a written one for Prune
+			now := time.Now()
+			os.Chtimes(objPath, now, now)
+			return sum, nil
+		}
+	}
+	if err := os.Chmod(tmp.Name(), perm.Perm()&0555|0444); err != nil {
+		return "", fmt.Errorf("set permissions: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(objPath), 0755); err != nil {
+		return "", fmt.Errorf("create store directory: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), objPath); err != nil {
+		return "", fmt.Errorf("add store object: %w", err)
+	}
+	return sum, nil
+}
+
+// PutFile adds a file to the store
+func (s *Store) PutFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return "", err
+	}
+	return s.Put(file, info.Mode())
+}
+
+// PutBytes adds data to the store
+func (s *Store) PutBytes(data []byte, perm os.FileMode) (string, error) {
+	return s.Put(bytes.NewReader(data), perm)
+}
+
+// Link installs the object sum at target using mode, replacing whatever is
+// there. It fails, e.g. for hardlinks across devices, without touching
+// target; callers fall back to copying.
+func (s *Store) Link(sum, target, mode string) error {
+	objPath := s.Path(sum)
+	tmp := target + ".ctx-tool-link"
+	os.Remove(tmp)
+
+	var err error
+	switch mode {
+	case Hardlink:
+		err = os.Link(objPath, tmp)
+	case Symlink:
+		err = os.Symlink(objPath, tmp)
+	default:
+		return fmt.Errorf("cannot link with install mode %q", mode)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp, target); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// IsLinked reports whether target is still linked to object sum with mode
+func (s *Store) IsLinked(sum, target, mode string) bool {
+	objPath := s.Path(sum)
+	switch mode {
+	case Symlink:
+		dest, err := os.Readlink(target)
+		return err == nil && dest == objPath
+	case Hardlink:
+		targetInfo, err := os.Lstat(target)
+		if err != nil {
+			return false
+		}
+		objInfo, err := os.Stat(objPath)
+		return err == nil && os.SameFile(targetInfo, objInfo)
+	}
+	return false
+}
+
+// OtherLinks returns how many files besides target and the object share the
+// inode of target, a hardlink to object sum. ok is false where that can't
+// be told.
+func (s *Store) OtherLinks(sum, target string) (int, bool) {
+	info, err := os.Lstat(target)
+	if err != nil {
+		return 0, false
+	}
+	n, ok := linkCount(info)
+	if !ok {
+		return 0, false
+	}
+	others := int(n) - 1
+	if objInfo, err := os.Stat(s.Path(sum)); err == nil && os.SameFile(info, objInfo) {
+		others--
+	}
+	return max(others, 0), true
+}
+
+// Verify reports whether object sum still has the content it is named after
+func (s *Store) Verify(sum string) (bool, error) {
+	actual, err := checksum.File(s.Path(sum), s.Algorithm)
+	if err != nil {
+		return false, err
+	}
+	return actual == sum, nil
+}
+
+// Evict removes object sum from the store. Hardlinked copies keep their
+// content; symlinks to it dangle.
+func (s *Store) Evict(sum string) error {
+	err := os.Remove(s.Path(sum))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// Prune removes the objects whose checksum isn't in keep and that weren't
+// added within grace, and returns the checksums removed. Hardlinked copies
+// keep their content; symlinks to a removed object dangle, so keep must
+// hold every object still in use. Callers hold the store lock.
+func (s *Store) Prune(keep map[string]bool, grace time.Duration) ([]string, error) {
+	var removed []string
+	cutoff := time.Now().Add(-grace)
+	err := filepath.WalkDir(filepath.Join(s.Dir, string(s.Algorithm)), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || keep[d.Name()] {
+			return nil
+		}
+		if info, err := d.Info(); err != nil || info.ModTime().After(cutoff) {
+			return nil
+		}
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("remove store object: %w", err)
+		}
+		removed = append(removed, d.Name())
+		return nil
+	})
+	return removed, err
+}
+
+// Lock takes the lock on the store in dir, which installs linking to its
+// objects hold until their project is registered and Prune needs, so
+// objects aren't removed while they are being linked. It waits up to
+// timeout. The returned function releases the lock.
+func Lock(dir string, timeout time.Duration) (func() error, error) {
+	lockPath := filepath.Join(dir, "lock")
+	unlock, err := lockfile.Acquire(lockPath, timeout)
+	var held *lockfile.HeldError
+	if errors.As(err, &held) {
+		owner := "another ctx-tool process"
+		if held.PID != 0 {
+			owner = fmt.Sprintf("ctx-tool process %d", held.PID)
+		}
+		return nil, apperr.New(apperr.Locked, "store is locked by %s; if no ctx-tool process is running, delete %s", owner, lockPath)
+	}
+	return unlock, err
+}
+
+// Algorithms lists the checksum algorithms the store has objects for
+func Algorithms(dir string) ([]checksum.Algorithm, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var algos []checksum.Algorithm
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		algo := checksum.Algorithm(e.Name())
+		if _, err := algo.New(); err == nil {
+			algos = append(algos, algo)
+		}
+	}
+	return algos, nil
+}
+
+// Detach replaces a linked file with a regular, writable copy of its
+// current content
+func Detach(target string) error {
+	data, err := os.ReadFile(target)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		return err
+	}
+
+	tmp := target + ".ctx-tool-detach"
+	if err := os.WriteFile(tmp, data, info.Mode().Perm()|0200); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Chmod(tmp, info.Mode().Perm()|0200); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, target); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
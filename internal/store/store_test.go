@@ -0,0 +1,172 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/doodleEsc/ctx-tool/internal/apperr"
+	"github.com/doodleEsc/ctx-tool/internal/checksum"
+)
+
+func TestPutAndLink(t *testing.T) {
+	st := New(filepath.Join(t.TempDir(), "store"), checksum.SHA256)
+
+	sum, err := st.PutBytes([]byte("shared"), 0755)
+	if err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if again, err := st.PutBytes([]byte("shared"), 0644); err != nil || again != sum {
+		t.Errorf("Putting the same content again = %s, %v, want %s", again, err, sum)
+	}
+	info, err := os.Stat(st.Path(sum))
+	if err != nil {
+		t.Fatalf("Object missing: %v", err)
+	}
+	if info.Mode().Perm() != 0555 {
+		t.Errorf("Object mode = %v, want read-only and executable", info.Mode().Perm())
+	}
+
+	dir := t.TempDir()
+	for _, mode := range []string{Hardlink, Symlink} {
+		target := filepath.Join(dir, mode+".md")
+		if err := os.WriteFile(target, []byte("old"), 0644); err != nil {
+			t.Fatalf("Failed to write target: %v", err)
+		}
+		if err := st.Link(sum, target, mode); err != nil {
+			t.Fatalf("Link(%s) failed: %v", mode, err)
+		}
+		if !st.IsLinked(sum, target, mode) {
+			t.Errorf("%s target should be linked", mode)
+		}
+		if content, _ := os.ReadFile(target); string(content) != "shared" {
+			t.Errorf("%s target content = %q", mode, content)
+		}
+
+		if err := Detach(target); err != nil {
+			t.Fatalf("Detach failed: %v", err)
+		}
+		if st.IsLinked(sum, target, mode) {
+			t.Errorf("%s target should no longer be linked after Detach", mode)
+		}
+		if content, _ := os.ReadFile(target); string(content) != "shared" {
+			t.Errorf("Detached content = %q", content)
+		}
+	}
+
+	if ok, err := st.Verify(sum); err != nil || !ok {
+		t.Errorf("Verify() = %v, %v for an intact object", ok, err)
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	for _, mode := range []string{"", Copy, Hardlink, Symlink} {
+		if _, err := ParseMode(mode); err != nil {
+			t.Errorf("ParseMode(%q) failed: %v", mode, err)
+		}
+	}
+	if _, err := ParseMode("reflink"); err == nil {
+		t.Error("Expected an error for an unknown mode")
+	}
+}
+
+func TestPutReplacesEditedObject(t *testing.T) {
+	st := New(filepath.Join(t.TempDir(), "store"), checksum.SHA256)
+	sum, err := st.PutBytes([]byte("shared"), 0644)
+	if err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	// Another project edits its hardlinked copy in place
+	edited := filepath.Join(t.TempDir(), "edited.md")
+	if err := st.Link(sum, edited, Hardlink); err != nil {
+		t.Fatalf("Link failed: %v", err)
+	}
+	if err := os.Chmod(edited, 0644); err != nil {
+		t.Fatalf("Chmod failed: %v", err)
+	}
+	if err := os.WriteFile(edited, []byte("edited"), 0644); err != nil {
+		t.Fatalf("Failed to edit: %v", err)
+	}
+
+	if again, err := st.PutBytes([]byte("shared"), 0644); err != nil || again != sum {
+		t.Fatalf("Put = %s, %v, want %s", again, err, sum)
+	}
+	if ok, err := st.Verify(sum); err != nil || !ok {
+		t.Errorf("Verify() = %v, %v after Put replaced the edited object", ok, err)
+	}
+	if st.IsLinked(sum, edited, Hardlink) {
+		t.Error("The edited copy should no longer share the object")
+	}
+}
+
+func TestPrune(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "store")
+	st := New(dir, checksum.SHA256)
+	used, err := st.PutBytes([]byte("used"), 0644)
+	if err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	unused, err := st.PutBytes([]byte("unused"), 0644)
+	if err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	// Objects added within the grace period survive, also when an install
+	// reuses an old one
+	fresh, err := st.PutBytes([]byte("fresh"), 0644)
+	if err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	reused, err := st.PutBytes([]byte("reused"), 0644)
+	if err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	old := time.Now().Add(-2 * time.Hour)
+	for _, sum := range []string{used, unused, reused} {
+		if err := os.Chtimes(st.Path(sum), old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := st.PutBytes([]byte("reused"), 0644); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	removed, err := st.Prune(map[string]bool{used: true}, time.Hour)
+	if err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	if len(removed) != 1 || removed[0] != unused {
+		t.Errorf("Prune removed %v, want [%s]", removed, unused)
+	}
+	for _, sum := range []string{used, fresh, reused} {
+		if _, err := os.Stat(st.Path(sum)); err != nil {
+			t.Errorf("Object %s was removed: %v", sum, err)
+		}
+	}
+
+	algos, err := Algorithms(dir)
+	if err != nil || len(algos) != 1 || algos[0] != checksum.SHA256 {
+		t.Errorf("Algorithms() = %v, %v", algos, err)
+	}
+}
+
+func TestLock(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "store")
+	unlock, err := Lock(dir, 0)
+	if err != nil {
+		t.Fatalf("Lock failed: %v", err)
+	}
+	if _, err := Lock(dir, 0); apperr.KindOf(err) != apperr.Locked {
+		t.Errorf("Second Lock() error = %v, want a locked error", err)
+	}
+	if err := unlock(); err != nil {
+		t.Fatalf("unlock failed: %v", err)
+	}
+	if unlock, err := Lock(dir, 0); err != nil {
+		t.Errorf("Lock after unlock failed: %v", err)
+	} else {
+		unlock()
+	}
+}
@@ -0,0 +1,182 @@
+package sync
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/doodleEsc/ctx-tool/internal/checksum"
+	"github.com/doodleEsc/ctx-tool/internal/tracker"
+)
+
+// Adoption is a local file taken over as the installed copy of an upstream
+// file
+type Adoption struct {
+	// RepoPath is the upstream file's path in the repository
+	RepoPath string
+	// Path is the local file's path below the target directory
+	Path string
+	// Hash is the upstream file's checksum, which is what gets recorded
+	Hash string
+	// Identical is set when the local file matches upstream byte for byte
+	Identical bool
+	// Moved is set when the file was found away from the path it would be
+	// installed to
+	Moved bool
+}
+
+// Adopt tracks files that already exist in the target directory as if
+// they had been installed from dirs, without writing to them. A file at
+// the path an upstream file would be installed to is adopted whatever its
+// content; remaining upstream files are matched by content against
+// untracked files elsewhere in the same directories. Merged files and
+// files that are already tracked are left alone.
+func (s *Syncer) Adopt(dirs []string) ([]Adoption, error) {
+	planned, err := s.Plan(dirs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Paths taken by upstream files can only be adopted for that file
+	taken := make(map[string]bool, len(planned))
+	for _, p := range planned {
+		taken[p.Path] = true
+	}
+
+	var adopted []Adoption
+	var remaining []PlannedFile
+	for _, p := range planned {
+		if p.Merged {
+			continue
+		}
+		if _, ok := s.tracker.GetEntry(p.Path); ok {
+			continue
+		}
+
+		fullPath := filepath.Join(s.targetDir, p.Path)
+		info, err := os.Lstat(fullPath)
+		if err != nil || !info.Mode().IsRegular() {
+			remaining = append(remaining, p)
+			continue
+		}
+		a, err := s.adopt(p, p.Path, info.Size())
+		if err != nil {
+			return nil, err
+		}
+		adopted = append(adopted, a)
+	}
+
+	moved, err := s.adoptByContent(remaining, taken)
+	if err != nil {
+		return nil, err
+	}
+	adopted = append(adopted, moved...)
+
+	sort.Slice(adopted, func(i, j int) bool { return adopted[i].Path < adopted[j].Path })
+	return adopted, nil
+}
+
+// adoptByContent adopts untracked files below the top-level directories of
+// planned whose content is identical to one of them
+func (s *Syncer) adoptByContent(planned []PlannedFile, taken map[string]bool) ([]Adoption, error) {
+	if len(planned) == 0 {
+		return nil, nil
+	}
+
+	// Only files of a size some upstream file has are worth hashing
+	sizes := make(map[int64]bool)
+	roots := make(map[string]bool)
+	for _, p := range planned {
+		sizes[int64(len(p.Content))] = true
+		root, _, _ := strings.Cut(filepath.ToSlash(p.Path), "/")
+		if root != ".." {
+			roots[root] = true
+		}
+	}
+
+	var candidates []string
+	for root := range roots {
+		err := filepath.WalkDir(filepath.Join(s.targetDir, root), func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			relPath, err := filepath.Rel(s.targetDir, path)
+			if err != nil {
+				return err
+			}
+			if taken[relPath] || strings.HasSuffix(relPath, BackupSuffix) {
+				return nil
+			}
+			if _, ok := s.tracker.GetEntry(relPath); ok {
+				return nil
+			}
+			candidates = append(candidates, relPath)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("scan %s: %w", root, err)
+		}
+	}
+	sort.Strings(candidates)
+
+	var adopted []Adoption
+	claimed := make(map[string]bool)
+	for _, relPath := range candidates {
+		info, err := os.Stat(filepath.Join(s.targetDir, relPath))
+		if err != nil || !sizes[info.Size()] {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(s.targetDir, relPath))
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", relPath, err)
+		}
+		for _, p := range planned {
+			if claimed[p.RepoPath] || !bytes.Equal(content, p.Content) {
+				continue
+			}
+			claimed[p.RepoPath] = true
+			a, err := s.adopt(p, relPath, info.Size())
+			if err != nil {
+				return nil, err
+			}
+			adopted = append(adopted, a)
+			break
+		}
+	}
+	return adopted, nil
+}
+
+// adopt records the file at relPath as the installed copy of p
+func (s *Syncer) adopt(p PlannedFile, relPath string, size int64) (Adoption, error) {
+	algo := s.tracker.Algorithm
+	sum, err := s.tracker.Cache.File(filepath.Join(s.targetDir, relPath), algo)
+	if err != nil {
+		return Adoption{}, fmt.Errorf("calculate checksum for %s: %w", relPath, err)
+	}
+	upstreamSum, err := checksum.Reader(bytes.NewReader(p.Content), algo)
+	if err != nil {
+		return Adoption{}, err
+	}
+
+	// The upstream checksum with the local size makes a differing file count
+	// as modified, so it is protected like an edit. A file found away from
+	// its mapped path records its upstream path even when it equals relPath,
+	// so it stays where it was found.
+	entry := tracker.FileEntry{Path: relPath, Hash: upstreamSum, Size: size}
+	if relPath != p.Path {
+		entry.Upstream = p.RepoPath
+	}
+	s.recordFile(p.RepoPath, entry)
+
+	return Adoption{RepoPath: p.RepoPath, Path: relPath, Hash: upstreamSum, Identical: sum == upstreamSum, Moved: relPath != p.Path}, nil
+}
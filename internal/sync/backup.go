@@ -0,0 +1,42 @@
+package sync
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// BackupSuffix is appended to a file's name when a differing file is
+// replaced and backup_on_conflict is set
+const BackupSuffix = ".backup"
+
+// FindBackups returns the backup files left in dirs below base, relative to
+// base. Missing directories are skipped.
+func FindBackups(base string, dirs []string) ([]string, error) {
+	var backups []string
+	for _, dir := range dirs {
+		root := filepath.Join(base, dir)
+		if _, err := os.Stat(root); os.IsNotExist(err) {
+			continue
+		}
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || !strings.HasSuffix(d.Name(), BackupSuffix) {
+				return nil
+			}
+			rel, err := filepath.Rel(base, path)
+			if err != nil {
+				return err
+			}
+			backups = append(backups, rel)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return backups, nil
+}
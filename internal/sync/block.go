@@ -0,0 +1,189 @@
+package sync
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/doodleEsc/ctx-tool/internal/apperr"
+	"github.com/doodleEsc/ctx-tool/internal/checksum"
+	"github.com/doodleEsc/ctx-tool/internal/event"
+	"github.com/doodleEsc/ctx-tool/internal/merge"
+	"github.com/doodleEsc/ctx-tool/internal/tracker"
+)
+
+// mergeMode returns the merge mode of the first rule matching the installed
+// path or the repository path of a file
+func (s *Syncer) mergeMode(relPath, repoPath string) (string, error) {
+	mode, err := s.matchMergeRule(relPath, repoPath)
+	if err != nil {
+		return "", err
+	}
+	// Only MCP servers are merged into a file outside the target, such as
+	// ~/.claude.json; whole files are never written there
+	if !filepath.IsLocal(relPath) && mode != merge.ModeMCP {
+		return "", apperr.New(apperr.NotAllowed, "refusing to install %s outside %s", relPath, s.targetDir)
+	}
+	return mode, nil
+}
+
+func (s *Syncer) matchMergeRule(relPath, repoPath string) (string, error) {
+	// The MCP server definitions are always merged, whatever the rules say
+	if filepath.ToSlash(repoPath) == MCPServersFile {
+		return merge.ModeMCP, nil
+	}
+	for _, rule := range s.config.Merge {
+		if !tracker.MatchPath(rule.Path, relPath) && !tracker.MatchPath(rule.Path, repoPath) {
+			continue
+		}
+		switch rule.Mode {
+		case "", merge.ModeReplace:
+			return merge.ModeReplace, nil
+		case merge.ModeBlock, merge.ModeJSON, merge.ModeMCP:
+			return rule.Mode, nil
+		default:
+			return "", apperr.New(apperr.Usage, "unknown merge mode %q for %s", rule.Mode, rule.Path)
+		}
+	}
+	return merge.ModeReplace, nil
+}
+
+// syncBlock installs content as a managed block inside targetPath, leaving
+// the rest of the file alone. The block is named after the repository path
+// and tracked by the checksum of its body.
+func (s *Syncer) syncBlock(result *fileResult, repoPath, sourcePath, targetPath string, content []byte, templateSum string, log *slog.Logger) (*fileResult, error) {
+	algo := s.tracker.Algorithm
+	id := filepath.ToSlash(repoPath)
+	log = log.With("block", id)
+
+	s.mergeMu.Lock()
+	defer s.mergeMu.Unlock()
+
+	doc, err := os.ReadFile(targetPath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("read target: %w", err)
+	}
+	if doc, err = s.convertWholeFile(result, targetPath, doc, log); err != nil {
+		return nil, err
+	}
+
+	updated, err := merge.UpsertBlock(doc, id, content)
+	if err != nil {
+		return nil, fmt.Errorf("update block in %s: %w", result.relPath, err)
+	}
+	body, _, _ := merge.ExtractBlock(updated, id)
+	sum, err := checksum.Reader(bytes.NewReader(body), algo)
+	if err != nil {
+		return nil, fmt.Errorf("calculate block checksum: %w", err)
+	}
+	entry := tracker.FileEntry{Path: result.relPath, Hash: sum, Size: int64(len(body)), TemplateHash: templateSum, Mode: merge.ModeBlock, Block: id}
+
+	if current, found, _ := merge.ExtractBlock(doc, id); found {
+		previous, err := checksum.Reader(bytes.NewReader(current), algo)
+		if err != nil {
+			return nil, fmt.Errorf("calculate block checksum: %w", err)
+		}
+		result.previousHash = previous
+		if previous == sum {
+			log.Debug("skipping identical block", "hash", sum)
+			result.skipped = true
+			result.hash = sum
+			s.recordFile(repoPath, entry)
+			return result, nil
+		}
+
+		log.Debug("block differs from upstream", "source_hash", sum, "target_hash", previous, "backup", s.config.Behavior.BackupOnConflict)
+		if s.config.Behavior.BackupOnConflict {
+			backupPath := targetPath + BackupSuffix
+			if _, _, err := s.copyFile(targetPath, backupPath, nil, ""); err != nil {
+				return nil, fmt.Errorf("backup file: %w", err)
+			}
+			result.backupPath = backupPath
+		}
+	} else {
+		log.Debug("adding block", "existing_file", doc != nil)
+	}
+
+	if err := writeMerged(targetPath, sourcePath, updated); err != nil {
+		return nil, err
+	}
+
+	result.hash = sum
+	s.recordFile(repoPath, entry)
+	return result, nil
+}
+
+// convertWholeFile handles a file older versions installed whole, before a
+// merge rule applied to it. Merging into it would keep the old copy next to
+// the merged content forever, and none of it could be withdrawn later, so
+// the file starts over: an unmodified file
+// is simply replaced, an edited one is backed up first with a warning. It
+// returns the document to merge into.
+func (s *Syncer) convertWholeFile(result *fileResult, targetPath string, doc []byte, log *slog.Logger) ([]byte, error) {
+	// A file outside the target, like ~/.claude.json, was never ours alone
+	previous, ok := s.tracker.GetEntry(result.relPath)
+	if !ok || previous.Mode != "" || doc == nil || !filepath.IsLocal(result.relPath) {
+		return doc, nil
+	}
+
+	modified, err := s.tracker.IsModified(result.relPath, targetPath)
+	if err != nil {
+		return nil, err
+	}
+	log.Debug("converting whole-file install", "modified", modified)
+	if modified {
+		backupPath := targetPath + BackupSuffix
+		if _, _, err := s.copyFile(targetPath, backupPath, nil, ""); err != nil {
+			return nil, fmt.Errorf("backup file: %w", err)
+		}
+		result.backupPath = backupPath
+		result.warnings = append(result.warnings, event.Event{Kind: event.Warning, Reason: event.ReasonWholeFileEdited, Path: result.relPath, Backup: backupPath})
+	}
+	return nil, nil
+}
+
+// writeMerged writes a merged document, keeping the permissions of an
+// existing target and otherwise using the source's
+func writeMerged(targetPath, sourcePath string, data []byte) error {
+	perm := os.FileMode(0644)
+	if info, err := os.Stat(targetPath); err == nil {
+		perm = info.Mode().Perm()
+	} else if info, err := os.Stat(sourcePath); err == nil {
+		perm = info.Mode().Perm()
+	}
+
+	if err := os.MkdirAll(filepath.Dir(targetPath), 0755); err != nil {
+		return fmt.Errorf("create directory: %w", err)
+	}
+	// Replacing the file never writes through a link into the store
+	if err := writeFileAtomic(targetPath, data, perm); err != nil {
+		return fmt.Errorf("write file: %w", err)
+	}
+	return nil
+}
+
+// writeFileAtomic replaces path with data through a temporary file in the
+// same directory, so an interrupted write leaves the old file intact
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp, perm)
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+	}
+	return err
+}
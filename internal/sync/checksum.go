@@ -1,32 +1,16 @@
 package sync
 
 import (
-	"crypto/md5"
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
 	"strings"
-)
-
-// CalculateFileMD5 calculates the MD5 checksum of a file
-func CalculateFileMD5(filePath string) (string, error) {
-	file, err := os.Open(filePath)
-	if err != nil {
-		return "", fmt.Errorf("open file: %w", err)
-	}
-	defer file.Close()
 
-	hash := md5.New()
-	if _, err := io.Copy(hash, file); err != nil {
-		return "", fmt.Errorf("calculate hash: %w", err)
-	}
-
-	return fmt.Sprintf("%x", hash.Sum(nil)), nil
-}
+	"github.com/doodleEsc/ctx-tool/internal/checksum"
+)
 
-// CalculateDirectoryChecksums calculates MD5 checksums for all files in a directory
-func CalculateDirectoryChecksums(dir string) (map[string]string, error) {
+// CalculateDirectoryChecksums calculates checksums for all files in a directory
+func CalculateDirectoryChecksums(dir string, algo checksum.Algorithm) (map[string]string, error) {
 	checksums := make(map[string]string)
 
 	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
@@ -56,13 +40,13 @@ func CalculateDirectoryChecksums(dir string) (map[string]string, error) {
 			return fmt.Errorf("calculate relative path: %w", err)
 		}
 
-		// Calculate MD5
-		checksum, err := CalculateFileMD5(path)
+		// Calculate checksum
+		sum, err := checksum.File(path, algo)
 		if err != nil {
-			return fmt.Errorf("calculate MD5 for %s: %w", relPath, err)
+			return fmt.Errorf("calculate checksum for %s: %w", relPath, err)
 		}
 
-		checksums[relPath] = checksum
+		checksums[relPath] = sum
 		return nil
 	})
 
@@ -73,8 +57,8 @@ func CalculateDirectoryChecksums(dir string) (map[string]string, error) {
 	return checksums, nil
 }
 
-// CompareFiles compares two files using MD5 checksums
-func CompareFiles(file1, file2 string) (bool, error) {
+// CompareFiles compares two files using checksums
+func CompareFiles(file1, file2 string, algo checksum.Algorithm) (bool, error) {
 	// Check if both files exist
 	if _, err := os.Stat(file1); os.IsNotExist(err) {
 		return false, fmt.Errorf("file %s does not exist", file1)
@@ -84,17 +68,17 @@ func CompareFiles(file1, file2 string) (bool, error) {
 	}
 
 	// Calculate checksums
-	md5_1, err := CalculateFileMD5(file1)
+	sum1, err := checksum.File(file1, algo)
 	if err != nil {
-		return false, fmt.Errorf("calculate MD5 for %s: %w", file1, err)
+		return false, fmt.Errorf("calculate checksum for %s: %w", file1, err)
 	}
 
-	md5_2, err := CalculateFileMD5(file2)
+	sum2, err := checksum.File(file2, algo)
 	if err != nil {
-		return false, fmt.Errorf("calculate MD5 for %s: %w", file2, err)
+		return false, fmt.Errorf("calculate checksum for %s: %w", file2, err)
 	}
 
-	return md5_1 == md5_2, nil
+	return sum1 == sum2, nil
 }
 
 // FileExists checks if a file exists
@@ -4,37 +4,9 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
-)
 
-func TestCalculateFileMD5(t *testing.T) {
-	// Create a temporary file
-	tempDir := t.TempDir()
-	testFile := filepath.Join(tempDir, "test.txt")
-	testContent := []byte("Hello, World!")
-	
-	if err := os.WriteFile(testFile, testContent, 0644); err != nil {
-		t.Fatalf("Failed to create test file: %v", err)
-	}
-
-	// Calculate MD5
-	md5sum, err := CalculateFileMD5(testFile)
-	if err != nil {
-		t.Fatalf("CalculateFileMD5 failed: %v", err)
-	}
-
-	// Expected MD5 for "Hello, World!"
-	expected := "65a8e27d8879283831b664bd8b7f0ad4"
-	if md5sum != expected {
-		t.Errorf("MD5 mismatch: got %s, want %s", md5sum, expected)
-	}
-}
-
-func TestCalculateFileMD5_NonExistentFile(t *testing.T) {
-	_, err := CalculateFileMD5("/non/existent/file")
-	if err == nil {
-		t.Error("Expected error for non-existent file, got nil")
-	}
-}
+	"github.com/doodleEsc/ctx-tool/internal/checksum"
+)
 
 func TestFileExists(t *testing.T) {
 	// Create a temporary file
@@ -73,7 +45,7 @@ func TestCompareFiles(t *testing.T) {
 	}
 
 	// Compare identical files
-	same, err := CompareFiles(file1, file2)
+	same, err := CompareFiles(file1, file2, checksum.SHA256)
 	if err != nil {
 		t.Fatalf("CompareFiles failed: %v", err)
 	}
@@ -88,7 +60,7 @@ func TestCompareFiles(t *testing.T) {
 	}
 
 	// Compare different files
-	same, err = CompareFiles(file1, file3)
+	same, err = CompareFiles(file1, file3, checksum.SHA256)
 	if err != nil {
 		t.Fatalf("CompareFiles failed: %v", err)
 	}
@@ -0,0 +1,90 @@
+package sync
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/doodleEsc/ctx-tool/internal/checksum"
+	"github.com/doodleEsc/ctx-tool/internal/event"
+	"github.com/doodleEsc/ctx-tool/internal/merge"
+	"github.com/doodleEsc/ctx-tool/internal/tracker"
+)
+
+// syncJSON deep-merges the upstream document content into targetPath. The
+// keys and array items contributed by a previous install are replaced, the
+// user's own settings are kept, and the new contributions are tracked so
+// remove can withdraw exactly those. In MCP mode servers are merged whole
+// by name; user-defined servers and unset ${VAR} placeholders are reported
+// as warnings.
+func (s *Syncer) syncJSON(result *fileResult, mode, repoPath, sourcePath, targetPath string, content []byte, templateSum string, log *slog.Logger) (*fileResult, error) {
+	algo := s.tracker.Algorithm
+
+	s.mergeMu.Lock()
+	defer s.mergeMu.Unlock()
+
+	doc, err := os.ReadFile(targetPath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("read target: %w", err)
+	}
+	// A document installed whole has no contributions to withdraw; all of
+	// it came from upstream unless it was edited
+	if doc, err = s.convertWholeFile(result, targetPath, doc, log); err != nil {
+		return nil, err
+	}
+
+	var previous []merge.Contribution
+	if entry, ok := s.tracker.GetEntry(result.relPath); ok && entry.Mode == mode {
+		previous = entry.Contributions
+		result.previousHash = entry.Hash
+	}
+
+	opts := merge.JSONOptions{}
+	if mode == merge.ModeMCP {
+		opts = merge.MCPOptions
+	}
+	merged, err := merge.MergeJSON(doc, content, previous, opts)
+	if err != nil {
+		return nil, fmt.Errorf("merge %s: %w", result.relPath, err)
+	}
+	for _, kept := range merged.Kept {
+		log.Debug("keeping user value", "key", strings.Join(kept, "."))
+		if mode == merge.ModeMCP && len(kept) == 2 {
+			result.warnings = append(result.warnings, event.Event{Kind: event.Warning, Reason: event.ReasonServerExists, Path: result.relPath, Name: kept[1]})
+		}
+	}
+
+	if mode == merge.ModeMCP {
+		unset, err := merge.UnsetPlaceholders(content, os.LookupEnv)
+		if err != nil {
+			return nil, fmt.Errorf("check %s: %w", repoPath, err)
+		}
+		for _, p := range unset {
+			result.warnings = append(result.warnings, event.Event{Kind: event.Warning, Reason: event.ReasonUnsetVar, Path: result.relPath, Name: p.Server, Variable: p.Variable})
+		}
+	}
+
+	sum, err := checksum.Reader(bytes.NewReader(merge.Digest(merged.Contributions)), algo)
+	if err != nil {
+		return nil, fmt.Errorf("calculate contribution checksum: %w", err)
+	}
+	result.hash = sum
+	entry := tracker.FileEntry{Path: result.relPath, Hash: sum, Size: int64(len(merged.Data)), TemplateHash: templateSum, Mode: mode, Contributions: merged.Contributions}
+
+	if merged.Data == nil {
+		entry.Size = int64(len(doc))
+		log.Debug("skipping unchanged document", "contributions", len(merged.Contributions))
+		result.skipped = true
+		s.recordFile(repoPath, entry)
+		return result, nil
+	}
+
+	log.Debug("merged document", "contributions", len(merged.Contributions), "kept", len(merged.Kept))
+	if err := writeMerged(targetPath, sourcePath, merged.Data); err != nil {
+		return nil, err
+	}
+	s.recordFile(repoPath, entry)
+	return result, nil
+}
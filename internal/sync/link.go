@@ -0,0 +1,145 @@
+package sync
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/doodleEsc/ctx-tool/internal/checksum"
+	"github.com/doodleEsc/ctx-tool/internal/event"
+	"github.com/doodleEsc/ctx-tool/internal/logging"
+	"github.com/doodleEsc/ctx-tool/internal/store"
+	"github.com/doodleEsc/ctx-tool/internal/tracker"
+)
+
+// SetStore makes the syncer install files by linking them to objects in st
+// when mode is hardlink or symlink. Merged files are always written.
+func (s *Syncer) SetStore(st *store.Store, mode string) {
+	s.store = st
+	s.installMode = mode
+}
+
+// linking reports whether files are installed as links into the store
+func (s *Syncer) linking() bool {
+	return s.store != nil && s.installMode != "" && s.installMode != store.Copy
+}
+
+// install writes the source, or rendered content when set, to targetPath
+// and returns its checksum, size and the link mode used. A known sum of the
+// content saves hashing it while copying. Links that can't be created, e.g.
+// hardlinks across devices, fall back to a copy.
+func (s *Syncer) install(relPath, sourcePath, targetPath string, rendered []byte, sum string, log *slog.Logger) (string, int64, string, error) {
+	if s.linking() {
+		sum, size, err := s.link(sourcePath, targetPath, rendered)
+		if err == nil {
+			return sum, size, s.installMode, nil
+		}
+		log.Debug("linking failed, copying instead", "mode", s.installMode, "error", err)
+	}
+
+	// Never write through a link into the store
+	if entry, ok := s.tracker.GetEntry(relPath); ok && entry.Link != "" {
+		os.Remove(targetPath)
+	} else if info, err := os.Lstat(targetPath); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		os.Remove(targetPath)
+	}
+
+	sum, size, err := s.copyFile(sourcePath, targetPath, rendered, sum)
+	if err != nil {
+		return "", 0, "", fmt.Errorf("copy file: %w", err)
+	}
+	return sum, size, "", nil
+}
+
+// link adds the content to the store and links targetPath to it
+func (s *Syncer) link(sourcePath, targetPath string, rendered []byte) (string, int64, error) {
+	var sum string
+	var err error
+	if rendered != nil {
+		info, statErr := os.Stat(sourcePath)
+		if statErr != nil {
+			return "", 0, statErr
+		}
+		sum, err = s.store.PutBytes(rendered, info.Mode())
+	} else {
+		sum, err = s.store.PutFile(sourcePath)
+	}
+	if err != nil {
+		return "", 0, err
+	}
+
+	if err := s.store.Link(sum, targetPath, s.installMode); err != nil {
+		return "", 0, err
+	}
+
+	info, err := os.Stat(s.store.Path(sum))
+	if err != nil {
+		return "", 0, err
+	}
+	return sum, info.Size(), nil
+}
+
+// DetachEditedLinks finds linked files whose content no longer matches what
+// was installed, which means they were edited in place through the link.
+// Each is replaced by a regular file holding the edit, and the store object
+// the edit went into is evicted so new installs don't link to it. A
+// hardlink shares its inode with every other project linked to the object,
+// so those copies hold the edit too; a warning names each file edited that
+// way. Links an editor already replaced by a regular file are just marked
+// as copies. It returns the number of entries changed.
+func DetachEditedLinks(t *tracker.Tracker, st *store.Store, observer event.Observer) (int, error) {
+	observer = event.OrDiscard(observer)
+	changed := 0
+
+	for _, relPath := range t.GetTrackedFiles() {
+		entry, _ := t.GetEntry(relPath)
+		if entry.Link == "" {
+			continue
+		}
+		fullPath := filepath.Join(t.Installation.BasePath, relPath)
+		if _, err := os.Lstat(fullPath); err != nil {
+			continue
+		}
+
+		if st.Algorithm != entry.Algorithm || !st.IsLinked(entry.Hash, fullPath, entry.Link) {
+			entry.Link = ""
+			t.RecordEntry(entry)
+			changed++
+			continue
+		}
+
+		sum, err := checksum.File(fullPath, entry.Algorithm)
+		if err != nil {
+			return changed, fmt.Errorf("calculate checksum for %s: %w", fullPath, err)
+		}
+		if sum == entry.Hash {
+			continue
+		}
+
+		// Count the other copies before detaching drops this one
+		shared := false
+		if entry.Link == store.Hardlink {
+			others, ok := st.OtherLinks(entry.Hash, fullPath)
+			shared = !ok || others > 0
+		}
+		if err := store.Detach(fullPath); err != nil {
+			return changed, fmt.Errorf("detach %s: %w", fullPath, err)
+		}
+		if ok, err := st.Verify(entry.Hash); err == nil && !ok {
+			if err := st.Evict(entry.Hash); err != nil {
+				return changed, fmt.Errorf("evict store object: %w", err)
+			}
+		}
+
+		logging.Component("store").Info("detached edited link", "path", relPath, "link", entry.Link)
+		observer.Notify(event.Event{Kind: event.FileDetached, Path: relPath, Hash: sum, PreviousHash: entry.Hash, Algorithm: string(entry.Algorithm)})
+		if shared {
+			observer.Notify(event.Event{Kind: event.Warning, Reason: event.ReasonSharedLinkEdited, Path: relPath})
+		}
+		entry.Link = ""
+		t.RecordEntry(entry)
+		changed++
+	}
+	return changed, nil
+}
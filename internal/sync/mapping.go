@@ -0,0 +1,52 @@
+package sync
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// PathMapping moves everything below the repository directory From to To
+// in the target directory. An empty To places the contents directly in the
+// target directory.
+type PathMapping struct {
+	From string
+	To   string
+}
+
+// PathMap rewrites repository paths to target paths. The first mapping
+// whose From contains the path wins; unmapped paths are kept as they are.
+type PathMap []PathMapping
+
+// MCPServersFile is where the repository ships MCP server definitions. It
+// is not hidden, so it is synced with the rest of the .claude directory.
+const MCPServersFile = ".claude/mcp.json"
+
+// ProjectPathMap merges the repository's MCP servers into the project's
+// .mcp.json
+var ProjectPathMap = PathMap{
+	{From: MCPServersFile, To: ".mcp.json"},
+}
+
+// GlobalPathMap installs the repository's .claude directory straight into
+// ~/.claude, where Claude Code reads user-level configuration. Other
+// directories such as PRPs keep their name below ~/.claude. MCP servers go
+// to ~/.claude.json, next to ~/.claude, which holds user-scope servers.
+var GlobalPathMap = PathMap{
+	{From: MCPServersFile, To: filepath.Join("..", ".claude.json")},
+	{From: ".claude", To: ""},
+}
+
+// Map returns the target path for a repository path
+func (m PathMap) Map(repoPath string) string {
+	clean := filepath.Clean(repoPath)
+	for _, mapping := range m {
+		from := filepath.Clean(mapping.From)
+		if clean != from && !strings.HasPrefix(clean, from+string(filepath.Separator)) {
+			continue
+		}
+
+		rest := strings.TrimPrefix(strings.TrimPrefix(clean, from), string(filepath.Separator))
+		return filepath.Join(mapping.To, rest)
+	}
+	return clean
+}
@@ -0,0 +1,71 @@
+package sync
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/doodleEsc/ctx-tool/internal/event"
+	"github.com/doodleEsc/ctx-tool/internal/tracker"
+)
+
+// MigrateLayout moves files that were installed before the path map existed
+// to the location the map gives them and updates their tracking entries.
+// Entries that already record their upstream path are left alone, as are
+// files whose new location is taken. It returns the number of entries moved.
+func MigrateLayout(t *tracker.Tracker, m PathMap, observer event.Observer) (int, error) {
+	observer = event.OrDiscard(observer)
+	basePath := t.Installation.BasePath
+	moved := 0
+
+	for _, relPath := range t.GetTrackedFiles() {
+		entry, ok := t.GetEntry(relPath)
+		if !ok || entry.Upstream != "" {
+			continue
+		}
+		newPath := m.Map(relPath)
+		if newPath == relPath {
+			continue
+		}
+
+		oldFull := filepath.Join(basePath, relPath)
+		newFull := filepath.Join(basePath, newPath)
+
+		if FileExists(newFull) {
+			observer.Notify(event.Event{Kind: event.Warning, Reason: event.ReasonMoveConflict, Path: newPath, From: relPath})
+			continue
+		}
+
+		// A missing file only needs its entry updated
+		if FileExists(oldFull) {
+			if err := os.MkdirAll(filepath.Dir(newFull), 0755); err != nil {
+				return moved, err
+			}
+			if err := os.Rename(oldFull, newFull); err != nil {
+				return moved, err
+			}
+			removeEmptyParents(filepath.Dir(oldFull), basePath)
+		}
+
+		t.RemoveFile(relPath)
+		entry.Path = newPath
+		entry.Upstream = relPath
+		t.RecordEntry(entry)
+		moved++
+
+		observer.Notify(event.Event{Kind: event.FileMoved, Path: newPath, From: relPath, Hash: entry.Hash, Algorithm: string(entry.Algorithm)})
+	}
+
+	return moved, nil
+}
+
+// removeEmptyParents removes dir and its parents while they are empty and
+// below stop
+func removeEmptyParents(dir, stop string) {
+	prefix := filepath.Clean(stop) + string(filepath.Separator)
+	for dir = filepath.Clean(dir); strings.HasPrefix(dir, prefix); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package sync
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/doodleEsc/ctx-tool/internal/merge"
+)
+
+// PlannedFile is a file a sync would install, with the content it would get
+type PlannedFile struct {
+	// RepoPath is the file's path in the repository
+	RepoPath string
+	// Path is the file's path below the target directory
+	Path    string
+	Content []byte
+	Mode    os.FileMode
+	// Merged is set when the file is merged into the target instead of
+	// replacing it
+	Merged bool
+}
+
+// Plan lists the files syncing dirs would install without touching the
+// target; an empty dirs plans every allowed directory in the repository
+func (s *Syncer) Plan(dirs []string) ([]PlannedFile, error) {
+	if len(dirs) == 0 {
+		for _, dir := range s.config.Directories.Allowed {
+			if _, err := os.Stat(filepath.Join(s.sourceDir, dir)); err == nil {
+				dirs = append(dirs, dir)
+			}
+		}
+	}
+
+	var planned []PlannedFile
+	for _, dir := range dirs {
+		files, err := s.listFiles(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, repoPath := range files {
+			p, err := s.planFile(repoPath)
+			if err != nil {
+				return nil, fmt.Errorf("plan %s: %w", repoPath, err)
+			}
+			planned = append(planned, p)
+		}
+	}
+	return planned, nil
+}
+
+func (s *Syncer) planFile(repoPath string) (PlannedFile, error) {
+	relPath := s.targetPath(repoPath)
+	sourcePath := filepath.Join(s.sourceDir, repoPath)
+
+	info, err := os.Stat(sourcePath)
+	if err != nil {
+		return PlannedFile{}, fmt.Errorf("stat source: %w", err)
+	}
+	content, _, err := s.render(repoPath, sourcePath)
+	if err != nil {
+		return PlannedFile{}, err
+	}
+	if content == nil {
+		if content, err = os.ReadFile(sourcePath); err != nil {
+			return PlannedFile{}, fmt.Errorf("read source: %w", err)
+		}
+	}
+	mode, err := s.mergeMode(relPath, repoPath)
+	if err != nil {
+		return PlannedFile{}, err
+	}
+
+	return PlannedFile{
+		RepoPath: repoPath,
+		Path:     relPath,
+		Content:  content,
+		Mode:     info.Mode().Perm(),
+		Merged:   mode != merge.ModeReplace,
+	}, nil
+}
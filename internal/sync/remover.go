@@ -0,0 +1,191 @@
+package sync
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/doodleEsc/ctx-tool/internal/event"
+	"github.com/doodleEsc/ctx-tool/internal/merge"
+	"github.com/doodleEsc/ctx-tool/internal/tracker"
+)
+
+// RemoveOptions controls how tracked files are removed
+type RemoveOptions struct {
+	// ForceModified also deletes files edited since installation
+	ForceModified bool
+	// CleanEmptyDirs removes directories left empty by the removal
+	CleanEmptyDirs bool
+}
+
+// PlannedRemoval is a tracked file selected for removal
+type PlannedRemoval struct {
+	Path     string
+	Modified bool
+}
+
+// RemoveResult counts the outcome of a removal
+type RemoveResult struct {
+	Removed int
+	Kept    int
+	Failed  int
+}
+
+// Remover deletes tracked files and keeps the tracker in step
+type Remover struct {
+	tracker  *tracker.Tracker
+	options  RemoveOptions
+	observer event.Observer
+}
+
+func NewRemover(tracker *tracker.Tracker, options RemoveOptions) *Remover {
+	return &Remover{
+		tracker:  tracker,
+		options:  options,
+		observer: event.Discard,
+	}
+}
+
+// SetObserver sets the observer notified about removed and kept files
+func (r *Remover) SetObserver(o event.Observer) {
+	r.observer = event.OrDiscard(o)
+}
+
+// Plan selects the tracked files matching patterns (all files when empty)
+// and flags the ones modified since installation
+func (r *Remover) Plan(patterns []string) []PlannedRemoval {
+	files := r.tracker.GetTrackedFiles()
+	if len(patterns) > 0 {
+		files = r.tracker.FilterFiles(patterns)
+	}
+
+	basePath := r.tracker.Installation.BasePath
+	plan := make([]PlannedRemoval, 0, len(files))
+	for _, relPath := range files {
+		// Missing or unreadable files are handled during removal
+		modified, err := r.tracker.IsModified(relPath, filepath.Join(basePath, relPath))
+		plan = append(plan, PlannedRemoval{Path: relPath, Modified: err == nil && modified})
+	}
+	return plan
+}
+
+// Remove deletes the planned files, untracks them and cleans up empty directories
+func (r *Remover) Remove(plan []PlannedRemoval) RemoveResult {
+	var result RemoveResult
+	basePath := r.tracker.Installation.BasePath
+	directories := make(map[string]bool)
+
+	for _, item := range plan {
+		fullPath, err := r.tracker.FullPath(item.Path)
+		if err != nil {
+			r.observer.Notify(event.Event{Kind: event.Warning, Path: item.Path, Reason: event.ReasonRemoveFailed, Err: err})
+			result.Failed++
+			continue
+		}
+
+		// Track parent directories for cleanup
+		directories[filepath.Dir(fullPath)] = true
+
+		// Check if file exists
+		if _, err := os.Stat(fullPath); os.IsNotExist(err) {
+			r.observer.Notify(event.Event{Kind: event.FileSkipped, Path: item.Path, Reason: event.ReasonMissing})
+			continue
+		}
+
+		// Keep files the user edited since installation
+		if item.Modified && !r.options.ForceModified {
+			r.observer.Notify(event.Event{Kind: event.FileSkipped, Path: item.Path, Reason: event.ReasonModified})
+			result.Kept++
+			continue
+		}
+
+		// Remove the file, or only our block from it
+		entry, _ := r.tracker.GetEntry(item.Path)
+		if err := removeEntry(fullPath, entry); err != nil {
+			r.observer.Notify(event.Event{Kind: event.Warning, Path: item.Path, Reason: event.ReasonRemoveFailed, Err: err})
+			result.Failed++
+			continue
+		}
+
+		r.observer.Notify(event.Event{Kind: event.FileRemoved, Path: item.Path, Hash: entry.Hash, Algorithm: string(entry.Algorithm)})
+		result.Removed++
+
+		// Update tracker
+		r.tracker.RemoveFile(item.Path)
+	}
+
+	if r.options.CleanEmptyDirs {
+		r.cleanEmptyDirs(basePath, directories)
+	}
+
+	return result
+}
+
+// removeEntry deletes an installed file. For a managed block only the
+// block is removed and for a JSON merge only the contributed keys; the file
+// goes too if nothing else is left.
+func removeEntry(fullPath string, entry tracker.FileEntry) error {
+	if entry.Mode != merge.ModeBlock && !merge.IsJSON(entry.Mode) {
+		return os.Remove(fullPath)
+	}
+
+	doc, err := os.ReadFile(fullPath)
+	if err != nil {
+		return err
+	}
+
+	var updated []byte
+	var keep bool
+	if merge.IsJSON(entry.Mode) {
+		if updated, keep, err = merge.WithdrawJSON(doc, entry.Contributions); err != nil {
+			return err
+		}
+	} else {
+		var found bool
+		if updated, found, err = merge.RemoveBlock(doc, entry.Block); err != nil || !found {
+			return err
+		}
+		keep = len(bytes.TrimSpace(updated)) > 0
+	}
+	if !keep {
+		return os.Remove(fullPath)
+	}
+
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(fullPath, updated, info.Mode().Perm())
+}
+
+// cleanEmptyDirs removes parent directories of removed files that are now empty
+func (r *Remover) cleanEmptyDirs(basePath string, directories map[string]bool) {
+	dirs := make([]string, 0, len(directories))
+	for dir := range directories {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	prefix := filepath.Clean(basePath) + string(filepath.Separator)
+	for _, dir := range dirs {
+		// Don't remove base directories like $HOME/.claude, nor anything
+		// outside them like $HOME
+		if !strings.HasPrefix(filepath.Clean(dir), prefix) {
+			continue
+		}
+
+		// Check if directory is empty
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+
+		if len(entries) == 0 {
+			if err := os.Remove(dir); err == nil {
+				r.observer.Notify(event.Event{Kind: event.DirectoryRemoved, Dir: dir})
+			}
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package sync
+
+import (
+	"os"
+
+	"github.com/doodleEsc/ctx-tool/internal/tracker"
+)
+
+// RepairPlan lists the files Repair restores and the modified files it
+// keeps
+type RepairPlan struct {
+	Restore []tracker.FileState
+	Kept    []tracker.FileState
+}
+
+// PlanRepair picks the files of states to restore: missing and corrupted
+// ones, and modified ones only with forceModified
+func PlanRepair(states []tracker.FileState, forceModified bool) RepairPlan {
+	var plan RepairPlan
+	for _, s := range states {
+		switch {
+		case s.State == tracker.StateMissing || s.State == tracker.StateCorrupted:
+			plan.Restore = append(plan.Restore, s)
+		case s.State == tracker.StateModified && forceModified:
+			plan.Restore = append(plan.Restore, s)
+		case s.State == tracker.StateModified:
+			plan.Kept = append(plan.Kept, s)
+		}
+	}
+	return plan
+}
+
+// RepairFailure is a file Repair couldn't restore
+type RepairFailure struct {
+	Path string
+	Err  error
+}
+
+// Repair restores files from the syncer's source, which must be the
+// revision they were installed from
+func (s *Syncer) Repair(restore []tracker.FileState) []RepairFailure {
+	var failures []RepairFailure
+	for _, st := range restore {
+		entry, _ := s.tracker.GetEntry(st.Path)
+		repoPath := entry.Path
+		if entry.Upstream != "" {
+			repoPath = entry.Upstream
+		}
+
+		// A dangling link or a directory in the file's place would be
+		// written through or fail the copy
+		if st.State == tracker.StateCorrupted {
+			fullPath, err := s.tracker.FullPath(st.Path)
+			if err == nil {
+				err = os.Remove(fullPath)
+			}
+			if err != nil {
+				failures = append(failures, RepairFailure{Path: st.Path, Err: err})
+				continue
+			}
+		}
+		if err := s.SyncFile(repoPath); err != nil {
+			failures = append(failures, RepairFailure{Path: st.Path, Err: err})
+		}
+	}
+	return failures
+}
@@ -1,58 +1,161 @@
 package sync
 
 import (
+	"bytes"
 	"fmt"
+	"hash"
 	"io"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
+	gosync "sync"
+	"sync/atomic"
 
+	"github.com/doodleEsc/ctx-tool/internal/apperr"
+	"github.com/doodleEsc/ctx-tool/internal/checksum"
 	"github.com/doodleEsc/ctx-tool/internal/config"
-	"github.com/doodleEsc/ctx-tool/internal/i18n"
+	"github.com/doodleEsc/ctx-tool/internal/event"
+	"github.com/doodleEsc/ctx-tool/internal/logging"
+	"github.com/doodleEsc/ctx-tool/internal/merge"
+	"github.com/doodleEsc/ctx-tool/internal/render"
+	"github.com/doodleEsc/ctx-tool/internal/store"
 	"github.com/doodleEsc/ctx-tool/internal/tracker"
 )
 
 type Syncer struct {
-	sourceDir string
-	targetDir string
-	tracker   *tracker.Tracker
-	config    *config.Config
+	sourceDir   string
+	targetDir   string
+	tracker     *tracker.Tracker
+	config      *config.Config
+	concurrency int
+	observer    event.Observer
+	pathMap     PathMap
+
+	// templateData enables template rendering when set
+	templateData *render.Data
+
+	// mergeMu serializes updates of files merged with existing content
+	mergeMu gosync.Mutex
+
+	// store and installMode select linking files instead of copying them
+	store       *store.Store
+	installMode string
+
+	// located maps repository paths to where the tracker has them, so
+	// files adopted at another path stay there
+	located    map[string]string
+	locateOnce gosync.Once
+}
+
+// fileResult describes what happened to a single synced file
+type fileResult struct {
+	// relPath is the path below the target directory
+	relPath      string
+	skipped      bool
+	backupPath   string
+	hash         string
+	previousHash string
+	// warnings are reported after the file's own event
+	warnings []event.Event
 }
 
 func NewSyncer(sourceDir, targetDir string, tracker *tracker.Tracker, config *config.Config) *Syncer {
+	concurrency := config.Behavior.Concurrency
+	if concurrency <= 0 {
+		concurrency = runtime.NumCPU()
+	}
+
 	return &Syncer{
-		sourceDir: sourceDir,
-		targetDir: targetDir,
-		tracker:   tracker,
-		config:    config,
+		sourceDir:   sourceDir,
+		targetDir:   targetDir,
+		tracker:     tracker,
+		config:      config,
+		concurrency: concurrency,
+		observer:    event.Discard,
 	}
 }
 
+// SetPathMap sets how repository paths are placed in the target directory
+func (s *Syncer) SetPathMap(m PathMap) {
+	s.pathMap = m
+}
+
+// SetTemplateData enables rendering of template files with data. Without
+// it templates are installed verbatim, suffix included.
+func (s *Syncer) SetTemplateData(data *render.Data) {
+	s.templateData = data
+}
+
+// targetPath returns the path below the target directory a repository file
+// is installed to
+func (s *Syncer) targetPath(repoPath string) string {
+	s.locateOnce.Do(s.locateTracked)
+	if relPath, ok := s.located[repoPath]; ok {
+		return relPath
+	}
+	if s.templateData != nil {
+		repoPath = render.TargetPath(repoPath)
+	}
+	return s.pathMap.Map(repoPath)
+}
+
+// locateTracked indexes the tracked files that record their upstream path
+func (s *Syncer) locateTracked() {
+	s.located = make(map[string]string)
+	for _, relPath := range s.tracker.GetTrackedFiles() {
+		if entry, ok := s.tracker.GetEntry(relPath); ok && entry.Upstream != "" {
+			s.located[entry.Upstream] = entry.Path
+		}
+	}
+}
+
+// SetObserver sets the observer notified about every file decision
+func (s *Syncer) SetObserver(o event.Observer) {
+	s.observer = event.OrDiscard(o)
+}
+
 // SyncDirectory syncs an entire directory from source to target
 func (s *Syncer) SyncDirectory(dirName string) error {
+	files, err := s.listFiles(dirName)
+	if err != nil {
+		return err
+	}
+
+	s.observer.Notify(event.Event{Kind: event.DirectoryStarted, Dir: dirName})
+	for _, relPath := range files {
+		s.observer.Notify(event.Event{Kind: event.FilePlanned, Path: s.targetPath(relPath)})
+	}
+	logging.Component("sync").Info("directory planned", "dir", dirName, "files", len(files), "workers", min(s.concurrency, len(files)))
+
+	return s.syncFiles(files)
+}
+
+// listFiles returns the repository paths of the files in an allowed
+// directory, skipping hidden files
+func (s *Syncer) listFiles(dirName string) ([]string, error) {
 	sourcePath := filepath.Join(s.sourceDir, dirName)
 
 	// Check if source directory exists
 	info, err := os.Stat(sourcePath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return fmt.Errorf("directory %s not found in repository", dirName)
+			return nil, apperr.New(apperr.NotFound, "directory %s not found in repository", dirName)
 		}
-		return fmt.Errorf("stat source directory: %w", err)
+		return nil, fmt.Errorf("stat source directory: %w", err)
 	}
 	if !info.IsDir() {
-		return fmt.Errorf("%s is not a directory", dirName)
+		return nil, apperr.New(apperr.NotFound, "%s is not a directory", dirName)
 	}
 
 	// Check if this directory is allowed
 	if !s.isAllowedDirectory(dirName) {
-		return fmt.Errorf("directory %s is not in allowed list", dirName)
+		return nil, apperr.New(apperr.NotAllowed, "directory %s is not in allowed list", dirName)
 	}
 
-	fmt.Printf("%s\n", i18n.Tf(i18n.MsgSyncingDir, map[string]interface{}{"Dir": dirName}))
-
-	// Walk through the directory and sync files
-	return filepath.Walk(sourcePath, func(path string, info os.FileInfo, err error) error {
+	// Walk through the directory and collect files to sync
+	var files []string
+	err = filepath.Walk(sourcePath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
@@ -74,64 +177,248 @@ func (s *Syncer) SyncDirectory(dirName string) error {
 			return fmt.Errorf("calculate relative path: %w", err)
 		}
 
-		// Sync the file
-		return s.SyncFile(relPath)
+		files = append(files, relPath)
+		return nil
 	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// syncFiles syncs files with a bounded pool of workers and reports the
+// results in the original order once all workers are done, so observers see
+// a deterministic sequence of events
+func (s *Syncer) syncFiles(files []string) error {
+	results := make([]*fileResult, len(files))
+	errs := make([]error, len(files))
+
+	var failed atomic.Bool
+	jobs := make(chan int)
+	var wg gosync.WaitGroup
+
+	for w := 0; w < min(s.concurrency, len(files)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				// Stop picking up work once a file failed
+				if failed.Load() {
+					continue
+				}
+				results[i], errs[i] = s.syncFile(files[i])
+				if errs[i] != nil {
+					failed.Store(true)
+				}
+			}
+		}()
+	}
+
+	for i := range files {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	var firstErr error
+	for i := range files {
+		if errs[i] != nil {
+			if firstErr == nil {
+				firstErr = errs[i]
+			}
+			continue
+		}
+		if results[i] != nil {
+			s.report(results[i])
+		}
+	}
+
+	return firstErr
 }
 
-// SyncFile syncs a single file from source to target
+// SyncFile syncs a single file from source to target. relPath is relative
+// to the repository and placed according to the path map.
 func (s *Syncer) SyncFile(relPath string) error {
-	sourcePath := filepath.Join(s.sourceDir, relPath)
+	result, err := s.syncFile(relPath)
+	if err != nil {
+		return err
+	}
+
+	s.report(result)
+	return nil
+}
+
+// syncFile syncs a single file without reporting, so it can run concurrently.
+// The source is hashed at most once and the hash is reused for the tracking
+// entry; target hashes come from the stat cache when possible.
+func (s *Syncer) syncFile(repoPath string) (*fileResult, error) {
+	relPath := s.targetPath(repoPath)
+	sourcePath := filepath.Join(s.sourceDir, repoPath)
 	targetPath := filepath.Join(s.targetDir, relPath)
+	result := &fileResult{relPath: relPath}
+	algo := s.tracker.Algorithm
+	log := logging.Component("sync").With("path", relPath)
+	if relPath != repoPath {
+		log = log.With("upstream", repoPath)
+	}
+
+	// Render templates up front; the output replaces the file's content
+	rendered, templateSum, err := s.render(repoPath, sourcePath)
+	if err != nil {
+		return nil, err
+	}
+	if rendered != nil {
+		log.Debug("rendered template", "template_hash", templateSum)
+	}
+
+	mode, err := s.mergeMode(relPath, repoPath)
+	if err != nil {
+		return nil, err
+	}
+	if mode != merge.ModeReplace {
+		content := rendered
+		if content == nil {
+			if content, err = os.ReadFile(sourcePath); err != nil {
+				return nil, fmt.Errorf("read source: %w", err)
+			}
+		}
+		if merge.IsJSON(mode) {
+			return s.syncJSON(result, mode, repoPath, sourcePath, targetPath, content, templateSum, log)
+		}
+		return s.syncBlock(result, repoPath, sourcePath, targetPath, content, templateSum, log)
+	}
 
 	// Check if target file exists
-	if FileExists(targetPath) && s.config.Behavior.VerifyMD5 {
-		// Compare MD5 checksums
-		sourceMD5, err := CalculateFileMD5(sourcePath)
+	exists := FileExists(targetPath)
+	if exists && !s.config.Behavior.VerifyChecksum {
+		log.Debug("overwriting existing file", "reason", "checksum verification disabled")
+	}
+	if !exists {
+		log.Debug("installing new file")
+	}
+	var sourceSum string
+	if exists && s.config.Behavior.VerifyChecksum {
+		// Compare checksums
+		var err error
+		if rendered != nil {
+			sourceSum, err = checksum.Reader(bytes.NewReader(rendered), algo)
+		} else {
+			sourceSum, err = checksum.File(sourcePath, algo)
+		}
 		if err != nil {
-			return fmt.Errorf("calculate source MD5: %w", err)
+			return nil, fmt.Errorf("calculate source checksum: %w", err)
 		}
 
-		targetMD5, err := CalculateFileMD5(targetPath)
+		targetSum, err := s.tracker.Cache.File(targetPath, algo)
 		if err != nil {
-			return fmt.Errorf("calculate target MD5: %w", err)
+			return nil, fmt.Errorf("calculate target checksum: %w", err)
 		}
 
-		if sourceMD5 == targetMD5 {
-			fmt.Printf("  %s\n", i18n.Tf(i18n.MsgSkipIdentical, map[string]interface{}{"File": relPath}))
+		result.previousHash = targetSum
+		linked := s.linking() && s.store.IsLinked(sourceSum, targetPath, s.installMode)
+		if sourceSum == targetSum && (linked || !s.linking()) {
+			log.Debug("skipping identical file", "hash", sourceSum)
+			result.skipped = true
+			result.hash = sourceSum
 			// Still track the file even if skipped
-			s.tracker.RecordFile(relPath, targetPath, s.sourceDir)
-			return nil
+			info, err := os.Stat(targetPath)
+			if err != nil {
+				return nil, fmt.Errorf("stat target: %w", err)
+			}
+			entry := tracker.FileEntry{Path: relPath, Hash: sourceSum, Size: info.Size(), TemplateHash: templateSum}
+			if linked {
+				entry.Link = s.installMode
+			} else if previous, ok := s.tracker.GetEntry(relPath); ok {
+				entry.Link = previous.Link
+			}
+			s.recordFile(repoPath, entry)
+			return result, nil
 		}
 
-		// Files are different, backup if configured
-		if s.config.Behavior.BackupOnConflict {
-			backupPath := targetPath + ".backup"
-			if err := s.copyFile(targetPath, backupPath); err != nil {
-				return fmt.Errorf("backup file: %w", err)
+		// Files are different, backup if configured. Identical files are
+		// only replaced by a link, which needs no backup.
+		log.Debug("target differs from upstream", "source_hash", sourceSum, "target_hash", targetSum, "backup", s.config.Behavior.BackupOnConflict)
+		if s.config.Behavior.BackupOnConflict && sourceSum != targetSum {
+			backupPath := targetPath + BackupSuffix
+			if _, _, err := s.copyFile(targetPath, backupPath, nil, ""); err != nil {
+				return nil, fmt.Errorf("backup file: %w", err)
 			}
-			fmt.Printf("  %s\n", i18n.Tf(i18n.MsgBackedUp, map[string]interface{}{"Original": relPath, "Backup": filepath.Base(backupPath)}))
+			result.backupPath = backupPath
 		}
 	}
 
 	// Create target directory if needed
 	targetDir := filepath.Dir(targetPath)
 	if err := os.MkdirAll(targetDir, 0755); err != nil {
-		return fmt.Errorf("create directory: %w", err)
+		return nil, fmt.Errorf("create directory: %w", err)
 	}
 
-	// Copy the file
-	if err := s.copyFile(sourcePath, targetPath); err != nil {
-		return fmt.Errorf("copy file: %w", err)
+	// Copy or link the file, hashing the content on the way unless it was
+	// hashed for the comparison above
+	sum, size, link, err := s.install(relPath, sourcePath, targetPath, rendered, sourceSum, log)
+	if err != nil {
+		return nil, err
 	}
+	s.tracker.Cache.Store(targetPath, algo, sum)
+	result.hash = sum
 
 	// Track the installed file
-	if err := s.tracker.RecordFile(relPath, targetPath, s.sourceDir); err != nil {
-		return fmt.Errorf("track file: %w", err)
+	s.recordFile(repoPath, tracker.FileEntry{Path: relPath, Hash: sum, Size: size, TemplateHash: templateSum, Link: link})
+
+	return result, nil
+}
+
+// render renders the file when it is a template and returns the output
+// and the template's checksum; both are empty for other files
+func (s *Syncer) render(repoPath, sourcePath string) ([]byte, string, error) {
+	if s.templateData == nil || !render.Candidate(repoPath) {
+		return nil, "", nil
+	}
+	content, err := os.ReadFile(sourcePath)
+	if err != nil {
+		return nil, "", fmt.Errorf("read source: %w", err)
+	}
+	if !render.IsTemplate(repoPath, content) {
+		return nil, "", nil
 	}
 
-	fmt.Printf("  %s\n", i18n.Tf(i18n.MsgInstalled, map[string]interface{}{"File": relPath}))
-	return nil
+	rendered, err := render.Render(repoPath, content, s.templateData)
+	if err != nil {
+		return nil, "", err
+	}
+	templateSum, err := checksum.Reader(bytes.NewReader(content), s.tracker.Algorithm)
+	if err != nil {
+		return nil, "", fmt.Errorf("calculate template checksum: %w", err)
+	}
+	return rendered, templateSum, nil
+}
+
+// recordFile tracks an installed file whose checksum is already known. The
+// algorithm, source and upstream path are filled in.
+func (s *Syncer) recordFile(repoPath string, entry tracker.FileEntry) {
+	entry.Algorithm = s.tracker.Algorithm
+	entry.Source = s.sourceDir
+	if repoPath != entry.Path {
+		entry.Upstream = repoPath
+	}
+	s.tracker.RecordEntry(entry)
+}
+
+// report notifies the observer about the outcome of a synced file
+func (s *Syncer) report(result *fileResult) {
+	algo := string(s.tracker.Algorithm)
+	if result.skipped {
+		s.observer.Notify(event.Event{Kind: event.FileSkipped, Path: result.relPath, Reason: event.ReasonIdentical, Hash: result.hash, Algorithm: algo})
+	} else {
+		if result.backupPath != "" {
+			s.observer.Notify(event.Event{Kind: event.FileBackedUp, Path: result.relPath, Backup: result.backupPath, PreviousHash: result.previousHash, Algorithm: algo})
+		}
+		s.observer.Notify(event.Event{Kind: event.FileInstalled, Path: result.relPath, Hash: result.hash, PreviousHash: result.previousHash, Algorithm: algo})
+	}
+
+	for _, warning := range result.warnings {
+		s.observer.Notify(warning)
+	}
 }
 
 // SyncAll syncs all allowed directories
@@ -140,7 +427,7 @@ func (s *Syncer) SyncAll() error {
 		// Check if directory exists in source
 		sourcePath := filepath.Join(s.sourceDir, dir)
 		if _, err := os.Stat(sourcePath); os.IsNotExist(err) {
-			fmt.Printf("%s\n", i18n.Tf(i18n.MsgWarningDirNotFound, map[string]interface{}{"Dir": dir}))
+			s.observer.Notify(event.Event{Kind: event.Warning, Reason: event.ReasonDirNotFound, Dir: dir})
 			continue
 		}
 
@@ -151,35 +438,54 @@ func (s *Syncer) SyncAll() error {
 	return nil
 }
 
-// copyFile copies a file from source to destination
-func (s *Syncer) copyFile(src, dst string) error {
+// copyFile copies a file from source to destination and returns the
+// checksum and size of the copied content. A non-nil content is written
+// instead of the source's data, keeping the source's permissions. A known
+// sum is returned as is instead of hashing the content again.
+func (s *Syncer) copyFile(src, dst string, content []byte, sum string) (string, int64, error) {
 	source, err := os.Open(src)
 	if err != nil {
-		return fmt.Errorf("open source: %w", err)
+		return "", 0, fmt.Errorf("open source: %w", err)
 	}
 	defer source.Close()
 
 	destination, err := os.Create(dst)
 	if err != nil {
-		return fmt.Errorf("create destination: %w", err)
+		return "", 0, fmt.Errorf("create destination: %w", err)
 	}
 	defer destination.Close()
 
-	if _, err := io.Copy(destination, source); err != nil {
-		return fmt.Errorf("copy data: %w", err)
+	var data io.Reader = source
+	if content != nil {
+		data = bytes.NewReader(content)
+	}
+	var w io.Writer = destination
+	var h hash.Hash
+	if sum == "" {
+		if h, err = s.tracker.Algorithm.New(); err != nil {
+			return "", 0, err
+		}
+		w = io.MultiWriter(destination, h)
+	}
+	size, err := io.Copy(w, data)
+	if err != nil {
+		return "", 0, fmt.Errorf("copy data: %w", err)
 	}
 
 	// Preserve file permissions
-	srcInfo, err := os.Stat(src)
+	srcInfo, err := source.Stat()
 	if err != nil {
-		return fmt.Errorf("stat source: %w", err)
+		return "", 0, fmt.Errorf("stat source: %w", err)
 	}
 
 	if err := os.Chmod(dst, srcInfo.Mode()); err != nil {
-		return fmt.Errorf("set permissions: %w", err)
+		return "", 0, fmt.Errorf("set permissions: %w", err)
 	}
 
-	return nil
+	if sum == "" {
+		sum = fmt.Sprintf("%x", h.Sum(nil))
+	}
+	return sum, size, nil
 }
 
 // isAllowedDirectory checks if a directory is in the allowed list
@@ -0,0 +1,810 @@
+package sync
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/doodleEsc/ctx-tool/internal/checksum"
+	"github.com/doodleEsc/ctx-tool/internal/config"
+	"github.com/doodleEsc/ctx-tool/internal/event"
+	"github.com/doodleEsc/ctx-tool/internal/project"
+	"github.com/doodleEsc/ctx-tool/internal/render"
+	"github.com/doodleEsc/ctx-tool/internal/store"
+	"github.com/doodleEsc/ctx-tool/internal/tracker"
+)
+
+func newTestConfig(concurrency int) *config.Config {
+	return &config.Config{
+		Directories: config.DirectoriesConfig{Allowed: []string{".claude", "PRPs"}},
+		Behavior: config.BehaviorConfig{
+			BackupOnConflict: true,
+			VerifyChecksum:   true,
+			Concurrency:      concurrency,
+		},
+	}
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+func TestSyncDirectoryParallel(t *testing.T) {
+	sourceDir := t.TempDir()
+	targetDir := t.TempDir()
+
+	const fileCount = 50
+	for i := 0; i < fileCount; i++ {
+		writeTestFile(t, filepath.Join(sourceDir, ".claude", "commands", fmt.Sprintf("cmd-%02d.md", i)), fmt.Sprintf("command %d", i))
+	}
+
+	// An existing, differing file must be backed up before being replaced
+	writeTestFile(t, filepath.Join(targetDir, ".claude", "commands", "cmd-07.md"), "local edit")
+
+	trackerInstance := tracker.NewTracker(filepath.Join(targetDir, "tracking.json"), "project", targetDir)
+	syncer := NewSyncer(sourceDir, targetDir, trackerInstance, newTestConfig(8))
+
+	var installed []string
+	syncer.SetObserver(event.ObserverFunc(func(e event.Event) {
+		if e.Kind == event.FileInstalled {
+			installed = append(installed, e.Path)
+		}
+	}))
+
+	if err := syncer.SyncDirectory(".claude"); err != nil {
+		t.Fatalf("SyncDirectory failed: %v", err)
+	}
+
+	// Events must arrive in walk order regardless of worker scheduling
+	if len(installed) != fileCount || !sort.StringsAreSorted(installed) {
+		t.Errorf("Expected %d installed events in order, got %v", fileCount, installed)
+	}
+
+	files := trackerInstance.GetTrackedFiles()
+	if len(files) != fileCount {
+		t.Fatalf("Expected %d tracked files, got %d", fileCount, len(files))
+	}
+
+	for i := 0; i < fileCount; i++ {
+		data, err := os.ReadFile(filepath.Join(targetDir, ".claude", "commands", fmt.Sprintf("cmd-%02d.md", i)))
+		if err != nil {
+			t.Fatalf("Installed file missing: %v", err)
+		}
+		if string(data) != fmt.Sprintf("command %d", i) {
+			t.Errorf("Unexpected content for file %d: %q", i, data)
+		}
+	}
+
+	backup, err := os.ReadFile(filepath.Join(targetDir, ".claude", "commands", "cmd-07.md.backup"))
+	if err != nil {
+		t.Fatalf("Backup file missing: %v", err)
+	}
+	if string(backup) != "local edit" {
+		t.Errorf("Backup has unexpected content: %q", backup)
+	}
+}
+
+func TestSyncDirectoryNotAllowed(t *testing.T) {
+	sourceDir := t.TempDir()
+	writeTestFile(t, filepath.Join(sourceDir, "secrets", "token.txt"), "secret")
+
+	trackerInstance := tracker.NewTracker(filepath.Join(t.TempDir(), "tracking.json"), "project", "")
+	syncer := NewSyncer(sourceDir, t.TempDir(), trackerInstance, newTestConfig(2))
+
+	if err := syncer.SyncDirectory("secrets"); err == nil {
+		t.Error("Expected error for directory outside the allowed list")
+	}
+}
+
+func TestRemoverKeepsModifiedFiles(t *testing.T) {
+	baseDir := t.TempDir()
+	trackerInstance := tracker.NewTracker(filepath.Join(baseDir, "tracking.json"), "project", baseDir)
+
+	for _, name := range []string{"keep.md", "edit.md"} {
+		path := filepath.Join(baseDir, ".claude", "commands", name)
+		writeTestFile(t, path, "upstream")
+		if err := trackerInstance.RecordFile(filepath.Join(".claude", "commands", name), path, "/source"); err != nil {
+			t.Fatalf("Failed to record file: %v", err)
+		}
+	}
+	writeTestFile(t, filepath.Join(baseDir, ".claude", "commands", "edit.md"), "hand-tuned")
+
+	var kinds []event.Kind
+	remover := NewRemover(trackerInstance, RemoveOptions{CleanEmptyDirs: true})
+	remover.SetObserver(event.ObserverFunc(func(e event.Event) { kinds = append(kinds, e.Kind) }))
+
+	plan := remover.Plan(nil)
+	result := remover.Remove(plan)
+
+	if result.Removed != 1 || result.Kept != 1 {
+		t.Errorf("Expected 1 removed and 1 kept, got %+v", result)
+	}
+	if _, err := os.Stat(filepath.Join(baseDir, ".claude", "commands", "edit.md")); err != nil {
+		t.Errorf("Modified file should have been kept: %v", err)
+	}
+	if files := trackerInstance.GetTrackedFiles(); len(files) != 1 {
+		t.Errorf("Modified file should stay tracked, got %v", files)
+	}
+	if len(kinds) != 2 {
+		t.Errorf("Expected 2 events, got %v", kinds)
+	}
+}
+
+func TestPathMap(t *testing.T) {
+	tests := []struct {
+		repoPath string
+		want     string
+	}{
+		{".claude/commands/x.md", filepath.Join("commands", "x.md")},
+		{".claude/settings.json", "settings.json"},
+		{"PRPs/templates/base.md", filepath.Join("PRPs", "templates", "base.md")},
+		{".claudex/file.md", filepath.Join(".claudex", "file.md")},
+	}
+
+	for _, tt := range tests {
+		if got := GlobalPathMap.Map(filepath.FromSlash(tt.repoPath)); got != tt.want {
+			t.Errorf("Map(%q) = %q, want %q", tt.repoPath, got, tt.want)
+		}
+	}
+}
+
+func TestSyncDirectoryWithPathMap(t *testing.T) {
+	sourceDir := t.TempDir()
+	targetDir := t.TempDir()
+	writeTestFile(t, filepath.Join(sourceDir, ".claude", "commands", "x.md"), "command")
+
+	trackerInstance := tracker.NewTracker(filepath.Join(t.TempDir(), "tracking.json"), "global", targetDir)
+	syncer := NewSyncer(sourceDir, targetDir, trackerInstance, newTestConfig(1))
+	syncer.SetPathMap(GlobalPathMap)
+
+	if err := syncer.SyncDirectory(".claude"); err != nil {
+		t.Fatalf("SyncDirectory failed: %v", err)
+	}
+
+	if !FileExists(filepath.Join(targetDir, "commands", "x.md")) {
+		t.Error("File should be installed without the .claude prefix")
+	}
+
+	entry, ok := trackerInstance.GetEntry(filepath.Join("commands", "x.md"))
+	if !ok {
+		t.Fatal("Mapped file should be tracked by its target path")
+	}
+	if entry.Upstream != filepath.Join(".claude", "commands", "x.md") {
+		t.Errorf("Upstream = %q, want the repository path", entry.Upstream)
+	}
+}
+
+func TestSyncDirectoryRendersTemplates(t *testing.T) {
+	sourceDir := t.TempDir()
+	targetDir := t.TempDir()
+	writeTestFile(t, filepath.Join(sourceDir, ".claude", "CLAUDE.md.tmpl"), "# {{ .Project.Name }}\nTest with {{ .Vars.test_command }}\n")
+	writeTestFile(t, filepath.Join(sourceDir, ".claude", "plain.md"), "{{ left alone }}")
+
+	trackerInstance := tracker.NewTracker(filepath.Join(t.TempDir(), "tracking.json"), "project", targetDir)
+	syncer := NewSyncer(sourceDir, targetDir, trackerInstance, newTestConfig(1))
+	syncer.SetTemplateData(&render.Data{
+		Project: project.Facts{Name: "billing"},
+		Vars:    map[string]string{"test_command": "make test"},
+	})
+
+	if err := syncer.SyncDirectory(".claude"); err != nil {
+		t.Fatalf("SyncDirectory failed: %v", err)
+	}
+
+	renderedPath := filepath.Join(".claude", "CLAUDE.md")
+	content, err := os.ReadFile(filepath.Join(targetDir, renderedPath))
+	if err != nil {
+		t.Fatalf("Rendered file should be installed without the suffix: %v", err)
+	}
+	if string(content) != "# billing\nTest with make test\n" {
+		t.Errorf("Rendered content = %q", content)
+	}
+
+	entry, ok := trackerInstance.GetEntry(renderedPath)
+	if !ok {
+		t.Fatal("Rendered file should be tracked by its installed path")
+	}
+	if entry.TemplateHash == "" || entry.TemplateHash == entry.Hash {
+		t.Errorf("Expected separate template and rendered hashes, got %+v", entry)
+	}
+	if modified, _ := trackerInstance.IsModified(renderedPath, filepath.Join(targetDir, renderedPath)); modified {
+		t.Error("Freshly rendered file should not be reported as modified")
+	}
+
+	if plain, _ := os.ReadFile(filepath.Join(targetDir, ".claude", "plain.md")); string(plain) != "{{ left alone }}" {
+		t.Errorf("Files that don't opt in must be copied verbatim, got %q", plain)
+	}
+}
+
+func TestSyncAndRemoveManagedBlock(t *testing.T) {
+	sourceDir := t.TempDir()
+	targetDir := t.TempDir()
+	writeTestFile(t, filepath.Join(sourceDir, ".claude", "CLAUDE.md"), "Shared rules\n")
+	targetPath := filepath.Join(targetDir, ".claude", "CLAUDE.md")
+	writeTestFile(t, targetPath, "# Billing\n")
+
+	cfg := newTestConfig(1)
+	cfg.Merge = []config.MergeRule{{Path: "**/CLAUDE.md", Mode: "block"}}
+	trackerInstance := tracker.NewTracker(filepath.Join(t.TempDir(), "tracking.json"), "project", targetDir)
+	syncer := NewSyncer(sourceDir, targetDir, trackerInstance, cfg)
+
+	if err := syncer.SyncDirectory(".claude"); err != nil {
+		t.Fatalf("SyncDirectory failed: %v", err)
+	}
+
+	content, _ := os.ReadFile(targetPath)
+	want := "# Billing\n\n<!-- ctx-tool:begin .claude/CLAUDE.md -->\nShared rules\n<!-- ctx-tool:end -->\n"
+	if string(content) != want {
+		t.Fatalf("CLAUDE.md = %q, want %q", content, want)
+	}
+	relPath := filepath.Join(".claude", "CLAUDE.md")
+	if entry, _ := trackerInstance.GetEntry(relPath); entry.Mode != "block" || entry.Block != ".claude/CLAUDE.md" {
+		t.Errorf("Expected a block entry, got %+v", entry)
+	}
+
+	// Project text around the block isn't a modification
+	writeTestFile(t, targetPath, "Intro\n"+string(content)+"Outro\n")
+	if modified, err := trackerInstance.IsModified(relPath, targetPath); err != nil || modified {
+		t.Errorf("IsModified() = %v, %v; edits outside the block must be ignored", modified, err)
+	}
+
+	remover := NewRemover(trackerInstance, RemoveOptions{})
+	result := remover.Remove(remover.Plan(nil))
+	if result.Removed != 1 {
+		t.Fatalf("Expected the block to be removed, got %+v", result)
+	}
+	content, _ = os.ReadFile(targetPath)
+	if string(content) != "Intro\n# Billing\n\nOutro\n" {
+		t.Errorf("CLAUDE.md after removal = %q, want the project's text only", content)
+	}
+}
+
+func TestSyncBlockConvertsWholeFileInstall(t *testing.T) {
+	sourceDir := t.TempDir()
+	writeTestFile(t, filepath.Join(sourceDir, ".claude", "CLAUDE.md"), "Shared rules\n")
+	relPath := filepath.Join(".claude", "CLAUDE.md")
+	want := "<!-- ctx-tool:begin .claude/CLAUDE.md -->\nShared rules\n<!-- ctx-tool:end -->\n"
+
+	for _, edited := range []bool{false, true} {
+		targetDir := t.TempDir()
+		targetPath := filepath.Join(targetDir, relPath)
+
+		// An older version installed CLAUDE.md whole
+		trackerInstance := tracker.NewTracker(filepath.Join(t.TempDir(), "tracking.json"), "project", targetDir)
+		if err := NewSyncer(sourceDir, targetDir, trackerInstance, newTestConfig(1)).SyncDirectory(".claude"); err != nil {
+			t.Fatalf("SyncDirectory failed: %v", err)
+		}
+		if edited {
+			writeTestFile(t, targetPath, "Shared rules\nMy rule\n")
+		}
+
+		cfg := newTestConfig(1)
+		cfg.Merge = []config.MergeRule{{Path: "**/CLAUDE.md", Mode: "block"}}
+		var warnings []event.Event
+		syncer := NewSyncer(sourceDir, targetDir, trackerInstance, cfg)
+		syncer.SetObserver(event.ObserverFunc(func(e event.Event) {
+			if e.Kind == event.Warning {
+				warnings = append(warnings, e)
+			}
+		}))
+		if err := syncer.SyncDirectory(".claude"); err != nil {
+			t.Fatalf("SyncDirectory failed: %v", err)
+		}
+
+		if content, _ := os.ReadFile(targetPath); string(content) != want {
+			t.Errorf("edited=%v: CLAUDE.md = %q, want only the block", edited, content)
+		}
+		backup, err := os.ReadFile(targetPath + BackupSuffix)
+		if edited && (err != nil || string(backup) != "Shared rules\nMy rule\n" || len(warnings) != 1 || warnings[0].Reason != event.ReasonWholeFileEdited) {
+			t.Errorf("Edited whole file: backup %q (%v), warnings %+v", backup, err, warnings)
+		}
+		if !edited && (err == nil || len(warnings) != 0) {
+			t.Errorf("Unmodified whole file was backed up or warned about: %+v", warnings)
+		}
+
+		// Removing withdraws everything ctx-tool installed
+		remover := NewRemover(trackerInstance, RemoveOptions{})
+		if result := remover.Remove(remover.Plan(nil)); result.Removed != 1 {
+			t.Fatalf("Expected the block to be removed, got %+v", result)
+		}
+		if FileExists(targetPath) {
+			content, _ := os.ReadFile(targetPath)
+			t.Errorf("edited=%v: CLAUDE.md left behind after removal: %q", edited, content)
+		}
+	}
+}
+
+func TestSyncAndRemoveJSONMerge(t *testing.T) {
+	sourceDir := t.TempDir()
+	targetDir := t.TempDir()
+	writeTestFile(t, filepath.Join(sourceDir, ".claude", "settings.json"), `{"permissions": {"allow": ["Bash(make:*)"]}, "model": "sonnet"}`)
+	targetPath := filepath.Join(targetDir, ".claude", "settings.json")
+	userSettings := "{\n  \"model\": \"opus\",\n  \"permissions\": {\n    \"allow\": [\n      \"Bash(ls:*)\"\n    ]\n  }\n}\n"
+	writeTestFile(t, targetPath, userSettings)
+
+	cfg := newTestConfig(1)
+	cfg.Merge = []config.MergeRule{{Path: ".claude/settings.json", Mode: "json"}}
+	trackerInstance := tracker.NewTracker(filepath.Join(t.TempDir(), "tracking.json"), "project", targetDir)
+	syncer := NewSyncer(sourceDir, targetDir, trackerInstance, cfg)
+
+	if err := syncer.SyncDirectory(".claude"); err != nil {
+		t.Fatalf("SyncDirectory failed: %v", err)
+	}
+
+	relPath := filepath.Join(".claude", "settings.json")
+	entry, _ := trackerInstance.GetEntry(relPath)
+	if entry.Mode != "json" || len(entry.Contributions) != 1 {
+		t.Fatalf("Expected one tracked contribution, got %+v", entry)
+	}
+	if modified, err := trackerInstance.IsModified(relPath, targetPath); err != nil || modified {
+		t.Errorf("IsModified() = %v, %v after a fresh merge", modified, err)
+	}
+
+	remover := NewRemover(trackerInstance, RemoveOptions{})
+	if result := remover.Remove(remover.Plan(nil)); result.Removed != 1 {
+		t.Fatalf("Expected the contribution to be withdrawn, got %+v", result)
+	}
+	content, _ := os.ReadFile(targetPath)
+	if string(content) != userSettings {
+		t.Errorf("settings.json after removal = %q, want the user's settings", content)
+	}
+}
+
+func TestSyncJSONConvertsWholeFileInstall(t *testing.T) {
+	sourceDir := t.TempDir()
+	targetDir := t.TempDir()
+	sourcePath := filepath.Join(sourceDir, ".claude", "settings.json")
+	writeTestFile(t, sourcePath, `{"model": "sonnet", "env": {"A": "1"}}`)
+	relPath := filepath.Join(".claude", "settings.json")
+	targetPath := filepath.Join(targetDir, relPath)
+
+	// An older version installed settings.json whole
+	trackerInstance := tracker.NewTracker(filepath.Join(t.TempDir(), "tracking.json"), "project", targetDir)
+	if err := NewSyncer(sourceDir, targetDir, trackerInstance, newTestConfig(1)).SyncDirectory(".claude"); err != nil {
+		t.Fatalf("SyncDirectory failed: %v", err)
+	}
+
+	// Upstream drops a key, which the update must withdraw
+	writeTestFile(t, sourcePath, `{"model": "sonnet"}`)
+	cfg := newTestConfig(1)
+	cfg.Merge = []config.MergeRule{{Path: "**/settings.json", Mode: "json"}}
+	if err := NewSyncer(sourceDir, targetDir, trackerInstance, cfg).SyncDirectory(".claude"); err != nil {
+		t.Fatalf("SyncDirectory failed: %v", err)
+	}
+
+	content, _ := os.ReadFile(targetPath)
+	if strings.Contains(string(content), "env") || !strings.Contains(string(content), "sonnet") {
+		t.Errorf("settings.json = %s, want the dropped key withdrawn", content)
+	}
+	entry, _ := trackerInstance.GetEntry(relPath)
+	if entry.Mode != "json" || len(entry.Contributions) == 0 || entry.Size != int64(len(content)) {
+		t.Errorf("Expected a json entry with contributions and the file's size, got %+v", entry)
+	}
+	if FileExists(targetPath + BackupSuffix) {
+		t.Error("Unmodified settings.json was backed up")
+	}
+
+	remover := NewRemover(trackerInstance, RemoveOptions{})
+	if result := remover.Remove(remover.Plan(nil)); result.Removed != 1 {
+		t.Fatalf("Expected settings.json to be withdrawn, got %+v", result)
+	}
+	if FileExists(targetPath) {
+		content, _ := os.ReadFile(targetPath)
+		t.Errorf("settings.json left behind after removal: %s", content)
+	}
+}
+
+func TestSyncMCPServers(t *testing.T) {
+	sourceDir := t.TempDir()
+	targetDir := t.TempDir()
+	writeTestFile(t, filepath.Join(sourceDir, MCPServersFile), `{"mcpServers": {"db": {"command": "db-mcp"}, "api": {"url": "${CTX_TOOL_TEST_UNSET_HOST}"}}}`)
+	writeTestFile(t, filepath.Join(targetDir, ".mcp.json"), `{"mcpServers": {"db": {"command": "my-db"}}}`)
+
+	cfg := newTestConfig(1)
+	cfg.Merge = []config.MergeRule{{Path: MCPServersFile, Mode: "mcp"}}
+	trackerInstance := tracker.NewTracker(filepath.Join(t.TempDir(), "tracking.json"), "project", targetDir)
+	syncer := NewSyncer(sourceDir, targetDir, trackerInstance, cfg)
+	syncer.SetPathMap(ProjectPathMap)
+
+	var warnings []event.Event
+	syncer.SetObserver(event.ObserverFunc(func(e event.Event) {
+		if e.Kind == event.Warning {
+			warnings = append(warnings, e)
+		}
+	}))
+
+	if err := syncer.SyncDirectory(".claude"); err != nil {
+		t.Fatalf("SyncDirectory failed: %v", err)
+	}
+
+	content, _ := os.ReadFile(filepath.Join(targetDir, ".mcp.json"))
+	if !strings.Contains(string(content), `"my-db"`) || strings.Contains(string(content), `"db-mcp"`) || !strings.Contains(string(content), `"api"`) {
+		t.Errorf("Expected the user's db server kept and api added, got %s", content)
+	}
+
+	if len(warnings) != 2 || warnings[0].Reason != event.ReasonServerExists || warnings[0].Name != "db" ||
+		warnings[1].Reason != event.ReasonUnsetVar || warnings[1].Variable != "CTX_TOOL_TEST_UNSET_HOST" {
+		t.Errorf("Unexpected warnings: %+v", warnings)
+	}
+}
+
+func TestGlobalMCPServersStayMerged(t *testing.T) {
+	sourceDir := t.TempDir()
+	home := t.TempDir()
+	targetDir := filepath.Join(home, ".claude")
+	writeTestFile(t, filepath.Join(sourceDir, MCPServersFile), `{"mcpServers": {"db": {"command": "db-mcp"}}}`)
+	userConfig := filepath.Join(home, ".claude.json")
+	writeTestFile(t, userConfig, `{"theme": "dark"}`)
+	if err := os.Chmod(userConfig, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Merge rules without the MCP rule must not turn ~/.claude.json into a
+	// whole-file install
+	cfg := newTestConfig(1)
+	cfg.Merge = []config.MergeRule{{Path: "**/CLAUDE.md", Mode: "block"}}
+	trackerInstance := tracker.NewTracker(filepath.Join(t.TempDir(), "tracking.json"), "global", targetDir)
+	syncer := NewSyncer(sourceDir, targetDir, trackerInstance, cfg)
+	syncer.SetPathMap(GlobalPathMap)
+	if err := syncer.SyncDirectory(".claude"); err != nil {
+		t.Fatalf("SyncDirectory failed: %v", err)
+	}
+
+	content, _ := os.ReadFile(userConfig)
+	if !strings.Contains(string(content), `"dark"`) || !strings.Contains(string(content), `"db-mcp"`) {
+		t.Errorf("~/.claude.json = %s, want the server merged into the user's settings", content)
+	}
+	remover := NewRemover(trackerInstance, RemoveOptions{CleanEmptyDirs: true})
+	if result := remover.Remove(remover.Plan(nil)); result.Removed != 1 {
+		t.Fatalf("Expected the servers to be withdrawn, got %+v", result)
+	}
+	if content, _ := os.ReadFile(userConfig); !strings.Contains(string(content), `"dark"`) {
+		t.Errorf("~/.claude.json after removal = %s, want the user's settings", content)
+	}
+	// Both writes replace the file whole and keep its mode
+	if info, err := os.Stat(userConfig); err != nil {
+		t.Error(err)
+	} else if info.Mode().Perm() != 0600 {
+		t.Errorf("~/.claude.json mode = %v, want 0600", info.Mode().Perm())
+	}
+	if leftovers, _ := filepath.Glob(filepath.Join(home, ".*.tmp-*")); len(leftovers) > 0 {
+		t.Errorf("Temporary files left behind: %v", leftovers)
+	}
+	if _, err := os.Stat(home); err != nil {
+		t.Errorf("Removal cleaned up a directory outside the installation: %v", err)
+	}
+}
+
+func TestRemoverRefusesPathsOutsideBase(t *testing.T) {
+	home := t.TempDir()
+	targetDir := filepath.Join(home, ".claude")
+	outside := filepath.Join(home, "notes.md")
+	writeTestFile(t, outside, "mine")
+
+	trackerInstance := tracker.NewTracker(filepath.Join(t.TempDir(), "tracking.json"), "global", targetDir)
+	trackerInstance.RecordEntry(tracker.FileEntry{Path: filepath.Join("..", "notes.md"), Hash: "x"})
+
+	remover := NewRemover(trackerInstance, RemoveOptions{ForceModified: true, CleanEmptyDirs: true})
+	if result := remover.Remove(remover.Plan(nil)); result.Failed != 1 || result.Removed != 0 {
+		t.Errorf("Expected the removal to be refused, got %+v", result)
+	}
+	if !FileExists(outside) {
+		t.Error("A tracked path outside the installation was deleted")
+	}
+}
+
+func TestSyncWithSymlinksAndDetach(t *testing.T) {
+	sourceDir := t.TempDir()
+	targetDir := t.TempDir()
+	writeTestFile(t, filepath.Join(sourceDir, ".claude", "agents", "a.md"), "agent")
+
+	st := store.New(filepath.Join(t.TempDir(), "store"), checksum.SHA256)
+	trackerInstance := tracker.NewTracker(filepath.Join(t.TempDir(), "tracking.json"), "project", targetDir)
+	trackerInstance.Algorithm = checksum.SHA256
+	syncer := NewSyncer(sourceDir, targetDir, trackerInstance, newTestConfig(1))
+	syncer.SetStore(st, store.Symlink)
+
+	if err := syncer.SyncDirectory(".claude"); err != nil {
+		t.Fatalf("SyncDirectory failed: %v", err)
+	}
+
+	relPath := filepath.Join(".claude", "agents", "a.md")
+	targetPath := filepath.Join(targetDir, relPath)
+	entry, _ := trackerInstance.GetEntry(relPath)
+	if entry.Link != store.Symlink || !st.IsLinked(entry.Hash, targetPath, store.Symlink) {
+		t.Fatalf("Expected a symlink into the store, got %+v", entry)
+	}
+
+	// Edit the file in place through the link
+	object := st.Path(entry.Hash)
+	if err := os.Chmod(object, 0644); err != nil {
+		t.Fatalf("Failed to make object writable: %v", err)
+	}
+	if err := os.WriteFile(targetPath, []byte("edited"), 0644); err != nil {
+		t.Fatalf("Failed to edit through link: %v", err)
+	}
+
+	changed, err := DetachEditedLinks(trackerInstance, st, nil)
+	if err != nil || changed != 1 {
+		t.Fatalf("DetachEditedLinks() = %d, %v", changed, err)
+	}
+	if info, _ := os.Lstat(targetPath); info.Mode()&os.ModeSymlink != 0 {
+		t.Error("Edited file should be a regular file now")
+	}
+	if content, _ := os.ReadFile(targetPath); string(content) != "edited" {
+		t.Errorf("Detached content = %q, want the edit", content)
+	}
+	if _, err := os.Stat(object); !os.IsNotExist(err) {
+		t.Error("The corrupted store object should be evicted")
+	}
+	if modified, _ := trackerInstance.IsModified(relPath, targetPath); !modified {
+		t.Error("The detached file should be reported as modified")
+	}
+}
+
+func TestDetachSharedHardlink(t *testing.T) {
+	sourceDir := t.TempDir()
+	writeTestFile(t, filepath.Join(sourceDir, ".claude", "agents", "a.md"), "agent")
+	st := store.New(filepath.Join(t.TempDir(), "store"), checksum.SHA256)
+
+	// Two projects hardlinked to the same object
+	var trackers []*tracker.Tracker
+	for range 2 {
+		trackerInstance := tracker.NewTracker(filepath.Join(t.TempDir(), "tracking.json"), "project", t.TempDir())
+		trackerInstance.Algorithm = checksum.SHA256
+		syncer := NewSyncer(sourceDir, trackerInstance.Installation.BasePath, trackerInstance, newTestConfig(1))
+		syncer.SetStore(st, store.Hardlink)
+		if err := syncer.SyncDirectory(".claude"); err != nil {
+			t.Fatalf("SyncDirectory failed: %v", err)
+		}
+		trackers = append(trackers, trackerInstance)
+	}
+
+	relPath := filepath.Join(".claude", "agents", "a.md")
+	entry, _ := trackers[0].GetEntry(relPath)
+	edited := filepath.Join(trackers[0].Installation.BasePath, relPath)
+	if others, ok := st.OtherLinks(entry.Hash, edited); ok && others != 1 {
+		t.Fatalf("OtherLinks() = %d, want the other project's copy", others)
+	}
+	if err := os.Chmod(edited, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(edited, []byte("edited"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var warnings []event.Event
+	observer := event.ObserverFunc(func(e event.Event) {
+		if e.Kind == event.Warning {
+			warnings = append(warnings, e)
+		}
+	})
+	if changed, err := DetachEditedLinks(trackers[0], st, observer); err != nil || changed != 1 {
+		t.Fatalf("DetachEditedLinks() = %d, %v", changed, err)
+	}
+	if len(warnings) != 1 || warnings[0].Reason != event.ReasonSharedLinkEdited || warnings[0].Path != relPath {
+		t.Errorf("Expected a warning that the edit reached the other project, got %+v", warnings)
+	}
+	if content, _ := os.ReadFile(filepath.Join(trackers[1].Installation.BasePath, relPath)); string(content) != "edited" {
+		t.Errorf("Other project's copy = %q, want the shared edit", content)
+	}
+}
+
+func TestMigrateLayout(t *testing.T) {
+	baseDir := t.TempDir()
+	oldPath := filepath.Join(".claude", "commands", "x.md")
+	writeTestFile(t, filepath.Join(baseDir, oldPath), "command")
+	writeTestFile(t, filepath.Join(baseDir, ".claude", "agents", "a.md"), "agent")
+	writeTestFile(t, filepath.Join(baseDir, "agents", "a.md"), "user's own agent")
+
+	trackerInstance := tracker.NewTracker(filepath.Join(t.TempDir(), "tracking.json"), "global", baseDir)
+	for _, relPath := range []string{oldPath, filepath.Join(".claude", "agents", "a.md")} {
+		if err := trackerInstance.RecordFile(relPath, filepath.Join(baseDir, relPath), "/source"); err != nil {
+			t.Fatalf("RecordFile failed: %v", err)
+		}
+	}
+
+	var conflicts int
+	moved, err := MigrateLayout(trackerInstance, GlobalPathMap, event.ObserverFunc(func(e event.Event) {
+		if e.Reason == event.ReasonMoveConflict {
+			conflicts++
+		}
+	}))
+	if err != nil {
+		t.Fatalf("MigrateLayout failed: %v", err)
+	}
+	if moved != 1 || conflicts != 1 {
+		t.Errorf("moved = %d, conflicts = %d, want 1 and 1", moved, conflicts)
+	}
+
+	newPath := filepath.Join("commands", "x.md")
+	if !FileExists(filepath.Join(baseDir, newPath)) {
+		t.Error("File should have been moved to its mapped location")
+	}
+	if _, err := os.Stat(filepath.Join(baseDir, ".claude", "commands")); !os.IsNotExist(err) {
+		t.Error("Empty legacy directory should have been removed")
+	}
+	if entry, ok := trackerInstance.GetEntry(newPath); !ok || entry.Upstream != oldPath {
+		t.Errorf("Entry should be tracked at %s with upstream %s, got %+v", newPath, oldPath, entry)
+	}
+
+	// The conflicting file stays where it was
+	if _, ok := trackerInstance.GetEntry(filepath.Join(".claude", "agents", "a.md")); !ok {
+		t.Error("Conflicting entry should be left untouched")
+	}
+}
+
+func TestPlanLeavesTargetUntouched(t *testing.T) {
+	sourceDir := t.TempDir()
+	targetDir := t.TempDir()
+	writeTestFile(t, filepath.Join(sourceDir, ".claude", "settings.json"), `{"hooks": {}}`)
+	writeTestFile(t, filepath.Join(sourceDir, "PRPs", "base.md"), "prp")
+
+	trackerInstance := tracker.NewTracker(filepath.Join(targetDir, "tracking.json"), "project", targetDir)
+	syncer := NewSyncer(sourceDir, targetDir, trackerInstance, newTestConfig(1))
+
+	planned, err := syncer.Plan(nil)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	var paths []string
+	for _, p := range planned {
+		paths = append(paths, p.Path)
+	}
+	sort.Strings(paths)
+	want := []string{filepath.Join(".claude", "settings.json"), filepath.Join("PRPs", "base.md")}
+	if strings.Join(paths, ",") != strings.Join(want, ",") {
+		t.Errorf("Planned %v, want %v", paths, want)
+	}
+	if entries, _ := os.ReadDir(targetDir); len(entries) != 0 {
+		t.Errorf("Plan wrote to the target: %v", entries)
+	}
+}
+
+func TestFindBackups(t *testing.T) {
+	base := t.TempDir()
+	writeTestFile(t, filepath.Join(base, ".claude", "commands", "review.md"+BackupSuffix), "old")
+	writeTestFile(t, filepath.Join(base, ".claude", "commands", "review.md"), "new")
+	writeTestFile(t, filepath.Join(base, "notes", "todo.md"+BackupSuffix), "not ours")
+
+	backups, err := FindBackups(base, []string{".claude", "PRPs"})
+	if err != nil {
+		t.Fatalf("FindBackups failed: %v", err)
+	}
+	want := filepath.Join(".claude", "commands", "review.md"+BackupSuffix)
+	if len(backups) != 1 || backups[0] != want {
+		t.Errorf("FindBackups = %v, want [%s]", backups, want)
+	}
+}
+
+func TestAdoptExistingFiles(t *testing.T) {
+	sourceDir := t.TempDir()
+	targetDir := t.TempDir()
+	writeTestFile(t, filepath.Join(sourceDir, ".claude", "commands", "review.md"), "review")
+	writeTestFile(t, filepath.Join(sourceDir, ".claude", "commands", "plan.md"), "plan")
+	writeTestFile(t, filepath.Join(sourceDir, "PRPs", "base.md"), "prp")
+	writeTestFile(t, filepath.Join(targetDir, ".claude", "commands", "review.md"), "review, edited")
+	writeTestFile(t, filepath.Join(targetDir, ".claude", "commands", "custom", "plan.md"), "plan")
+	writeTestFile(t, filepath.Join(targetDir, ".claude", "commands", "mine.md"), "mine")
+
+	trackerInstance := tracker.NewTracker(filepath.Join(targetDir, "tracking.json"), "project", targetDir)
+	adopted, err := NewSyncer(sourceDir, targetDir, trackerInstance, newTestConfig(1)).Adopt(nil)
+	if err != nil {
+		t.Fatalf("Adopt failed: %v", err)
+	}
+
+	moved := filepath.Join(".claude", "commands", "custom", "plan.md")
+	review := filepath.Join(".claude", "commands", "review.md")
+	if len(adopted) != 2 || adopted[0].Path != moved || adopted[1].Path != review {
+		t.Fatalf("Adopted %+v, want %s and %s", adopted, moved, review)
+	}
+	if !adopted[0].Identical || adopted[1].Identical {
+		t.Errorf("Identical = %v, %v; want true, false", adopted[0].Identical, adopted[1].Identical)
+	}
+	if files := trackerInstance.GetTrackedFiles(); len(files) != 2 {
+		t.Errorf("Tracked %v, want the adopted files only", files)
+	}
+	if content, _ := os.ReadFile(filepath.Join(targetDir, review)); string(content) != "review, edited" {
+		t.Errorf("Adopt rewrote %s: %q", review, content)
+	}
+
+	// The differing file counts as modified, so remove keeps it
+	if state, err := trackerInstance.State(review, filepath.Join(targetDir, review)); err != nil || state != tracker.StateModified {
+		t.Errorf("State(%s) = %s, %v; want modified", review, state, err)
+	}
+	if state, err := trackerInstance.State(moved, filepath.Join(targetDir, moved)); err != nil || state != tracker.StateIntact {
+		t.Errorf("State(%s) = %s, %v; want intact", moved, state, err)
+	}
+	protected := false
+	for _, item := range NewRemover(trackerInstance, RemoveOptions{}).Plan(nil) {
+		protected = protected || item.Path == review && item.Modified
+	}
+	if !protected {
+		t.Errorf("Remove would delete the differing adopted file %s", review)
+	}
+
+	// Updates keep the adopted file where it was found and back up the
+	// local version before replacing it
+	if err := NewSyncer(sourceDir, targetDir, trackerInstance, newTestConfig(1)).SyncDirectory(".claude"); err != nil {
+		t.Fatalf("SyncDirectory failed: %v", err)
+	}
+	if FileExists(filepath.Join(targetDir, ".claude", "commands", "plan.md")) {
+		t.Error("Update installed a second copy of the adopted file")
+	}
+	if content, _ := os.ReadFile(filepath.Join(targetDir, review+BackupSuffix)); string(content) != "review, edited" {
+		t.Errorf("Backup of %s = %q, want the local version", review, content)
+	}
+	if content, _ := os.ReadFile(filepath.Join(targetDir, review)); string(content) != "review" {
+		t.Errorf("Update left %s at %q", review, content)
+	}
+}
+
+func TestRepair(t *testing.T) {
+	sourceDir := t.TempDir()
+	targetDir := t.TempDir()
+	for _, name := range []string{"deleted.md", "emptied.md", "edited.md"} {
+		writeTestFile(t, filepath.Join(sourceDir, ".claude", "commands", name), "upstream "+name)
+	}
+
+	trackerInstance := tracker.NewTracker(filepath.Join(targetDir, "tracking.json"), "project", targetDir)
+	syncer := NewSyncer(sourceDir, targetDir, trackerInstance, newTestConfig(1))
+	if err := syncer.SyncDirectory(".claude"); err != nil {
+		t.Fatalf("SyncDirectory failed: %v", err)
+	}
+
+	commands := filepath.Join(targetDir, ".claude", "commands")
+	os.Remove(filepath.Join(commands, "deleted.md"))
+	writeTestFile(t, filepath.Join(commands, "emptied.md"), "")
+	writeTestFile(t, filepath.Join(commands, "edited.md"), "my edit")
+
+	states, err := trackerInstance.States(nil)
+	if err != nil {
+		t.Fatalf("States failed: %v", err)
+	}
+
+	// Edits are kept by default
+	plan := PlanRepair(states, false)
+	if len(plan.Restore) != 2 || len(plan.Kept) != 1 || plan.Kept[0].Path != filepath.Join(".claude", "commands", "edited.md") {
+		t.Fatalf("PlanRepair() = %+v, want the deleted and emptied files restored and the edit kept", plan)
+	}
+	if failures := syncer.Repair(plan.Restore); len(failures) != 0 {
+		t.Fatalf("Repair failed: %+v", failures)
+	}
+	for _, name := range []string{"deleted.md", "emptied.md"} {
+		if data, _ := os.ReadFile(filepath.Join(commands, name)); string(data) != "upstream "+name {
+			t.Errorf("%s = %q after repair", name, data)
+		}
+	}
+	if data, _ := os.ReadFile(filepath.Join(commands, "edited.md")); string(data) != "my edit" {
+		t.Errorf("Edited file = %q, should have been kept", data)
+	}
+
+	// --force-modified restores the edit too, keeping a backup
+	states, _ = trackerInstance.States(nil)
+	plan = PlanRepair(states, true)
+	if len(plan.Restore) != 1 || len(plan.Kept) != 0 {
+		t.Fatalf("PlanRepair(force) = %+v, want only the edited file", plan)
+	}
+	if failures := syncer.Repair(plan.Restore); len(failures) != 0 {
+		t.Fatalf("Repair failed: %+v", failures)
+	}
+	if data, _ := os.ReadFile(filepath.Join(commands, "edited.md")); string(data) != "upstream edited.md" {
+		t.Errorf("Edited file = %q after forced repair", data)
+	}
+	if data, _ := os.ReadFile(filepath.Join(commands, "edited.md"+BackupSuffix)); string(data) != "my edit" {
+		t.Errorf("Backup = %q, want the edit", data)
+	}
+	states, _ = trackerInstance.States(nil)
+	if err := tracker.Verify(states); err != nil {
+		t.Errorf("Verify() = %v after repair", err)
+	}
+}
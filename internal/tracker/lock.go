@@ -0,0 +1,35 @@
+package tracker
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/doodleEsc/ctx-tool/internal/apperr"
+	"github.com/doodleEsc/ctx-tool/internal/lockfile"
+)
+
+// LockPath returns the path of the lock file guarding the tracking file
+func (t *Tracker) LockPath() string {
+	return t.FilePath + ".lock"
+}
+
+// Lock takes an exclusive lock on the tracking file so two ctx-tool runs
+// can't modify the same installation at once. A lock left by a process
+// that is no longer running is taken over. The returned function releases
+// the lock.
+func (t *Tracker) Lock() (func() error, error) {
+	unlock, err := lockfile.Acquire(t.LockPath(), 0)
+	var held *lockfile.HeldError
+	if errors.As(err, &held) {
+		return nil, apperr.New(apperr.Locked, "tracking file %s is locked by %s; if no ctx-tool process is running, delete %s", t.FilePath, lockOwner(held), held.Path)
+	}
+	return unlock, err
+}
+
+// lockOwner describes who holds a lock
+func lockOwner(held *lockfile.HeldError) string {
+	if held.PID == 0 {
+		return "another ctx-tool process"
+	}
+	return fmt.Sprintf("ctx-tool process %d", held.PID)
+}
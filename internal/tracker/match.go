@@ -0,0 +1,95 @@
+package tracker
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// FilterFiles returns the tracked paths selected by any of the given patterns.
+// A pattern selects a tracked path when it names the file itself, a directory
+// containing it, or is a glob matching it ("**" matches any number of directories).
+// Patterns may also refer to a file by its path in the upstream repository.
+func (t *Tracker) FilterFiles(patterns []string) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var files []string
+	for _, entry := range t.Installation.Files {
+		for _, pattern := range patterns {
+			if MatchPath(pattern, entry.Path) || (entry.Upstream != "" && MatchPath(pattern, entry.Upstream)) {
+				files = append(files, entry.Path)
+				break
+			}
+		}
+	}
+	return files
+}
+
+// MatchPath reports whether relPath is selected by pattern
+func MatchPath(pattern, relPath string) bool {
+	pattern = normalizePattern(pattern)
+	relPath = filepath.ToSlash(filepath.Clean(relPath))
+
+	if pattern == "" || pattern == "." {
+		return true
+	}
+
+	// Exact file or directory prefix
+	if relPath == pattern || strings.HasPrefix(relPath, pattern+"/") {
+		return true
+	}
+
+	if !strings.ContainsAny(pattern, "*?[") {
+		return false
+	}
+
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(relPath, "/"))
+}
+
+// normalizePattern converts a user supplied pattern to a clean, slash separated form
+func normalizePattern(pattern string) string {
+	pattern = filepath.ToSlash(strings.TrimSpace(pattern))
+	pattern = strings.TrimPrefix(pattern, "./")
+	pattern = strings.TrimSuffix(pattern, "/")
+	if pattern == "" {
+		return ""
+	}
+	return path.Clean(pattern)
+}
+
+// matchSegments matches path segments against pattern segments, expanding "**"
+func matchSegments(pattern, segments []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			// Collapse consecutive ** segments
+			rest := pattern[1:]
+			for len(rest) > 0 && rest[0] == "**" {
+				rest = rest[1:]
+			}
+			if len(rest) == 0 {
+				return true
+			}
+			for i := 0; i <= len(segments); i++ {
+				if matchSegments(rest, segments[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(segments) == 0 {
+			return false
+		}
+
+		ok, err := path.Match(pattern[0], segments[0])
+		if err != nil || !ok {
+			return false
+		}
+		pattern = pattern[1:]
+		segments = segments[1:]
+	}
+
+	// A glob matching a directory selects everything below it
+	return true
+}
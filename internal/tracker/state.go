@@ -0,0 +1,100 @@
+package tracker
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/doodleEsc/ctx-tool/internal/apperr"
+)
+
+// File states reported by State
+const (
+	// StateIntact means the file still has the recorded content
+	StateIntact = "intact"
+	// StateMissing means the file was deleted
+	StateMissing = "missing"
+	// StateModified means the content changed, usually by an edit
+	StateModified = "modified"
+	// StateCorrupted means the file can't be an edit: a dangling link,
+	// something other than a regular file, or emptied content
+	StateCorrupted = "corrupted"
+)
+
+// State compares the file at fullPath with what was recorded for relPath.
+// Whole files whose size differs from the recorded one are reported as
+// modified without hashing them.
+func (t *Tracker) State(relPath, fullPath string) (string, error) {
+	entry, ok := t.GetEntry(relPath)
+	if !ok {
+		return "", fmt.Errorf("%s is not tracked", relPath)
+	}
+
+	if _, err := os.Lstat(fullPath); os.IsNotExist(err) {
+		return StateMissing, nil
+	} else if err != nil {
+		return "", fmt.Errorf("stat %s: %w", fullPath, err)
+	}
+	info, err := os.Stat(fullPath)
+	if err != nil || !info.Mode().IsRegular() {
+		return StateCorrupted, nil
+	}
+
+	if entry.Mode == "" && entry.Size > 0 {
+		switch info.Size() {
+		case 0:
+			return StateCorrupted, nil
+		case entry.Size:
+		default:
+			return StateModified, nil
+		}
+	}
+
+	modified, err := t.IsModified(relPath, fullPath)
+	if err != nil {
+		return "", err
+	}
+	if modified {
+		return StateModified, nil
+	}
+	return StateIntact, nil
+}
+
+// FileState is the state of a tracked file
+type FileState struct {
+	Path  string
+	State string
+}
+
+// States checks the tracked files selected by patterns, or every tracked
+// file without patterns
+func (t *Tracker) States(patterns []string) ([]FileState, error) {
+	files := t.GetTrackedFiles()
+	if len(patterns) > 0 {
+		files = t.FilterFiles(patterns)
+	}
+
+	states := make([]FileState, 0, len(files))
+	for _, relPath := range files {
+		state, err := t.State(relPath, filepath.Join(t.Installation.BasePath, relPath))
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, FileState{Path: relPath, State: state})
+	}
+	return states, nil
+}
+
+// Verify fails with a conflict when any of states isn't intact
+func Verify(states []FileState) error {
+	failed := 0
+	for _, s := range states {
+		if s.State != StateIntact {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return apperr.New(apperr.Conflict, "%d of %d tracked files failed verification", failed, len(states))
+	}
+	return nil
+}
@@ -1,17 +1,33 @@
 package tracker
 
 import (
-	"crypto/md5"
+	"bytes"
 	"encoding/json"
 	"fmt"
-	"io"
 	"os"
+	"path/filepath"
+	"sort"
+	"sync"
 	"time"
+
+	"github.com/doodleEsc/ctx-tool/internal/checksum"
+	"github.com/doodleEsc/ctx-tool/internal/merge"
 )
 
 type Tracker struct {
 	FilePath     string
 	Installation *Installation
+
+	// Algorithm is used to hash newly recorded files
+	Algorithm checksum.Algorithm
+
+	// Cache avoids re-hashing unchanged files; nil disables caching
+	Cache *checksum.Cache
+
+	// mu guards Installation.Files so files can be recorded concurrently
+	mu sync.Mutex
+	// index maps a tracked path to its position in Installation.Files
+	index map[string]int
 }
 
 type Installation struct {
@@ -19,13 +35,48 @@ type Installation struct {
 	Scope     string      `json:"scope"`
 	BasePath  string      `json:"base_path"`
 	Files     []FileEntry `json:"files"`
+
+	// Upstream the files were last installed from
+	Repository string `json:"repository,omitempty"`
+	Branch     string `json:"branch,omitempty"`
+	Tag        string `json:"tag,omitempty"`
+	Revision   string `json:"revision,omitempty"`
+	// Signature is the verified signature of Revision, if one was required
+	Signature *Signature `json:"signature,omitempty"`
+}
+
+// Signature records who signed an installed revision
+type Signature struct {
+	// Object is the signed object, "commit" or "tag"
+	Object string `json:"object"`
+	Format string `json:"format"`
+	Key    string `json:"key"`
+	Signer string `json:"signer,omitempty"`
 }
 
 type FileEntry struct {
-	Path   string `json:"path"`
-	MD5    string `json:"md5"`
-	Size   int64  `json:"size"`
-	Source string `json:"source"`
+	Path      string             `json:"path"`
+	Hash      string             `json:"hash"`
+	Algorithm checksum.Algorithm `json:"algorithm"`
+	Size      int64              `json:"size"`
+	Source    string             `json:"source"`
+	// Upstream is the path in the repository when it differs from Path
+	Upstream string `json:"upstream,omitempty"`
+	// TemplateHash is the checksum of the template a rendered file came
+	// from; Hash is the checksum of the rendered output
+	TemplateHash string `json:"template_hash,omitempty"`
+	// Mode is how the file was merged; empty means the whole file is ours.
+	// For blocks Hash covers only the body of block Block; for JSON merges
+	// it covers the Contributions made to the document.
+	Mode          string               `json:"mode,omitempty"`
+	Block         string               `json:"block,omitempty"`
+	Contributions []merge.Contribution `json:"contributions,omitempty"`
+	// Link is "hardlink" or "symlink" when the file is linked to the
+	// content-addressed store instead of copied
+	Link string `json:"link,omitempty"`
+
+	// MD5 is only read from tracking files written by older versions
+	MD5 string `json:"md5,omitempty"`
 }
 
 func NewTracker(filePath, scope, basePath string) *Tracker {
@@ -37,9 +88,13 @@ func NewTracker(filePath, scope, basePath string) *Tracker {
 			BasePath:  basePath,
 			Files:     []FileEntry{},
 		},
+		Algorithm: checksum.Default,
 	}
 }
 
+// Load reads the tracking file. A base path passed to NewTracker takes
+// precedence over the stored one, so an installation can be managed from
+// another working directory or after the checkout was moved.
 func (t *Tracker) Load() error {
 	data, err := os.ReadFile(t.FilePath)
 	if err != nil {
@@ -50,15 +105,58 @@ func (t *Tracker) Load() error {
 		return fmt.Errorf("read tracking file: %w", err)
 	}
 
+	basePath := t.Installation.BasePath
 	if err := json.Unmarshal(data, t.Installation); err != nil {
 		return fmt.Errorf("unmarshal tracking data: %w", err)
 	}
+	if basePath != "" {
+		t.Installation.BasePath = basePath
+	}
+
+	t.index = nil
+	t.migrateLegacyHashes()
 
 	return nil
 }
 
+// migrateLegacyHashes converts entries written with a bare MD5 field and
+// re-hashes files that are still unmodified with the current algorithm.
+// Modified or missing files keep their MD5 so local edits are still detected.
+func (t *Tracker) migrateLegacyHashes() {
+	for i := range t.Installation.Files {
+		entry := &t.Installation.Files[i]
+		if entry.Hash == "" && entry.MD5 != "" {
+			entry.Hash = entry.MD5
+			entry.Algorithm = checksum.MD5
+		}
+		entry.MD5 = ""
+
+		if entry.Algorithm == t.Algorithm || entry.Hash == "" || entry.Mode != "" {
+			continue
+		}
+
+		fullPath := filepath.Join(t.Installation.BasePath, entry.Path)
+		current, err := t.Cache.File(fullPath, entry.Algorithm)
+		if err != nil || current != entry.Hash {
+			continue
+		}
+
+		upgraded, err := t.Cache.File(fullPath, t.Algorithm)
+		if err != nil {
+			continue
+		}
+		entry.Hash = upgraded
+		entry.Algorithm = t.Algorithm
+	}
+}
+
+// Save writes the tracking file with its entries sorted by path, so files
+// recorded concurrently don't reorder it from run to run
 func (t *Tracker) Save() error {
+	t.mu.Lock()
+	t.sortFiles()
 	data, err := json.MarshalIndent(t.Installation, "", "  ")
+	t.mu.Unlock()
 	if err != nil {
 		return fmt.Errorf("marshal tracking data: %w", err)
 	}
@@ -70,42 +168,78 @@ func (t *Tracker) Save() error {
 	return nil
 }
 
+// SetSource records the upstream repository and commit of an installation
+// and, when it was verified, its signature
+func (t *Tracker) SetSource(repository, branch, tag, revision string, signature *Signature) {
+	t.Installation.Repository = repository
+	t.Installation.Branch = branch
+	t.Installation.Tag = tag
+	t.Installation.Revision = revision
+	t.Installation.Signature = signature
+	t.Installation.Timestamp = time.Now().Format(time.RFC3339)
+}
+
 func (t *Tracker) RecordFile(relPath, fullPath, source string) error {
 	info, err := os.Stat(fullPath)
 	if err != nil {
 		return fmt.Errorf("stat file %s: %w", fullPath, err)
 	}
 
-	md5sum, err := calculateFileMD5(fullPath)
+	sum, err := t.Cache.File(fullPath, t.Algorithm)
 	if err != nil {
-		return fmt.Errorf("calculate MD5 for %s: %w", fullPath, err)
+		return fmt.Errorf("calculate checksum for %s: %w", fullPath, err)
 	}
 
-	entry := FileEntry{
-		Path:   relPath,
-		MD5:    md5sum,
-		Size:   info.Size(),
-		Source: source,
-	}
+	t.RecordEntry(FileEntry{
+		Path:      relPath,
+		Hash:      sum,
+		Algorithm: t.Algorithm,
+		Size:      info.Size(),
+		Source:    source,
+	})
+
+	return nil
+}
+
+// RecordEntry adds or replaces the entry for an already hashed file
+func (t *Tracker) RecordEntry(entry FileEntry) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 
 	// Check if file already tracked and update it
-	found := false
-	for i, existing := range t.Installation.Files {
-		if existing.Path == relPath {
-			t.Installation.Files[i] = entry
-			found = true
-			break
-		}
+	if i, ok := t.lookup(entry.Path); ok {
+		t.Installation.Files[i] = entry
+		return
 	}
 
-	if !found {
-		t.Installation.Files = append(t.Installation.Files, entry)
+	t.Installation.Files = append(t.Installation.Files, entry)
+	t.index[entry.Path] = len(t.Installation.Files) - 1
+}
+
+// lookup finds the position of relPath, rebuilding the index when it is
+// stale. Callers must hold t.mu.
+func (t *Tracker) lookup(relPath string) (int, bool) {
+	if i, ok := t.index[relPath]; ok && i < len(t.Installation.Files) && t.Installation.Files[i].Path == relPath {
+		return i, true
 	}
 
-	return nil
+	if t.index == nil || len(t.index) != len(t.Installation.Files) {
+		t.index = make(map[string]int, len(t.Installation.Files))
+		for i, entry := range t.Installation.Files {
+			t.index[entry.Path] = i
+		}
+	}
+
+	i, ok := t.index[relPath]
+	return i, ok
 }
 
+// GetTrackedFiles returns the tracked paths in sorted order
 func (t *Tracker) GetTrackedFiles() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.sortFiles()
 	var files []string
 	for _, entry := range t.Installation.Files {
 		files = append(files, entry.Path)
@@ -113,27 +247,102 @@ func (t *Tracker) GetTrackedFiles() []string {
 	return files
 }
 
-func (t *Tracker) RemoveFile(relPath string) {
-	var filtered []FileEntry
-	for _, entry := range t.Installation.Files {
-		if entry.Path != relPath {
-			filtered = append(filtered, entry)
+// sortFiles orders the entries by path. Callers must hold t.mu.
+func (t *Tracker) sortFiles() {
+	if sort.SliceIsSorted(t.Installation.Files, t.less) {
+		return
+	}
+	sort.SliceStable(t.Installation.Files, t.less)
+	t.index = nil
+}
+
+func (t *Tracker) less(i, j int) bool {
+	return t.Installation.Files[i].Path < t.Installation.Files[j].Path
+}
+
+// GetEntry returns the tracking entry for relPath
+func (t *Tracker) GetEntry(relPath string) (FileEntry, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if i, ok := t.lookup(relPath); ok {
+		return t.Installation.Files[i], true
+	}
+	return FileEntry{}, false
+}
+
+// FullPath returns where a tracked file lives. Only merged MCP servers may
+// live outside the base path, as ~/.claude.json does for the global scope;
+// any other entry escaping it is refused.
+func (t *Tracker) FullPath(relPath string) (string, error) {
+	if !filepath.IsLocal(relPath) {
+		if entry, ok := t.GetEntry(relPath); !ok || entry.Mode != merge.ModeMCP {
+			return "", fmt.Errorf("tracked path %s is outside %s", relPath, t.Installation.BasePath)
 		}
 	}
-	t.Installation.Files = filtered
+	return filepath.Join(t.Installation.BasePath, relPath), nil
 }
 
-func calculateFileMD5(filePath string) (string, error) {
-	file, err := os.Open(filePath)
-	if err != nil {
-		return "", err
+// IsModified reports whether the file at fullPath differs from the content
+// recorded for relPath at install time. For managed blocks only the block
+// is compared; a block that was deleted counts as modified. For JSON merges
+// every contribution must still be present unchanged.
+func (t *Tracker) IsModified(relPath, fullPath string) (bool, error) {
+	entry, ok := t.GetEntry(relPath)
+	if !ok || entry.Hash == "" {
+		return false, nil
+	}
+
+	if entry.Mode == merge.ModeBlock {
+		doc, err := os.ReadFile(fullPath)
+		if err != nil {
+			return false, fmt.Errorf("read %s: %w", fullPath, err)
+		}
+		body, found, err := merge.ExtractBlock(doc, entry.Block)
+		if err != nil || !found {
+			return true, nil
+		}
+		sum, err := checksum.Reader(bytes.NewReader(body), entry.Algorithm)
+		if err != nil {
+			return false, err
+		}
+		return sum != entry.Hash, nil
 	}
-	defer file.Close()
 
-	hash := md5.New()
-	if _, err := io.Copy(hash, file); err != nil {
-		return "", err
+	if merge.IsJSON(entry.Mode) {
+		doc, err := os.ReadFile(fullPath)
+		if err != nil {
+			return false, fmt.Errorf("read %s: %w", fullPath, err)
+		}
+		intact, err := merge.IntactJSON(doc, entry.Contributions)
+		if err != nil {
+			return true, nil
+		}
+		sum, err := checksum.Reader(bytes.NewReader(merge.Digest(intact)), entry.Algorithm)
+		if err != nil {
+			return false, err
+		}
+		return sum != entry.Hash, nil
 	}
 
-	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+	sum, err := t.Cache.File(fullPath, entry.Algorithm)
+	if err != nil {
+		return false, fmt.Errorf("calculate checksum for %s: %w", fullPath, err)
+	}
+
+	return sum != entry.Hash, nil
+}
+
+func (t *Tracker) RemoveFile(relPath string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var filtered []FileEntry
+	for _, entry := range t.Installation.Files {
+		if entry.Path != relPath {
+			filtered = append(filtered, entry)
+		}
+	}
+	t.Installation.Files = filtered
+	t.index = nil
 }
@@ -4,7 +4,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/doodleEsc/ctx-tool/internal/apperr"
+	"github.com/doodleEsc/ctx-tool/internal/checksum"
 )
 
 func TestTrackerSaveAndLoad(t *testing.T) {
@@ -106,4 +110,280 @@ func TestTrackerLoadNonExistent(t *testing.T) {
 	if len(tracker.GetTrackedFiles()) != 0 {
 		t.Error("Expected empty file list for new tracker")
 	}
-}
\ No newline at end of file
+}
+
+func TestMatchPath(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{".claude/commands/typescript", ".claude/commands/typescript/TS-review-general.md", true},
+		{".claude/commands/typescript/", ".claude/commands/typescript/TS-review-general.md", true},
+		{"./PRPs/README.md", "PRPs/README.md", true},
+		{".claude/commands/type", ".claude/commands/typescript/TS-review-general.md", false},
+		{"**/experimental/**", ".claude/commands/rapid-development/experimental/prp-validate.md", true},
+		{"**/experimental/**", ".claude/commands/development/onboarding.md", false},
+		{"PRPs/*.md", "PRPs/README.md", true},
+		{"PRPs/*.md", "PRPs/templates/prp_base.md", false},
+		{"**/*.py", "PRPs/scripts/prp_runner.py", true},
+		{".claude/agents/*", ".claude/agents/codebase-analyst.md", true},
+	}
+
+	for _, tt := range tests {
+		if got := MatchPath(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("MatchPath(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestTrackerFilterFiles(t *testing.T) {
+	tracker := NewTracker(filepath.Join(t.TempDir(), "tracking.json"), "project", "")
+	tracker.Installation.Files = []FileEntry{
+		{Path: ".claude/commands/typescript/TS-create-base-prp.md"},
+		{Path: ".claude/commands/rapid-development/experimental/hackathon-research.md"},
+		{Path: "PRPs/README.md"},
+	}
+
+	files := tracker.FilterFiles([]string{".claude/commands/typescript", "**/experimental/**"})
+	if len(files) != 2 {
+		t.Fatalf("Expected 2 matching files, got %d: %v", len(files), files)
+	}
+
+	if files := tracker.FilterFiles([]string{"claude_md_files"}); len(files) != 0 {
+		t.Errorf("Expected no matching files, got %v", files)
+	}
+}
+
+func TestTrackerIsModified(t *testing.T) {
+	tempDir := t.TempDir()
+	tracker := NewTracker(filepath.Join(tempDir, "tracking.json"), "project", tempDir)
+
+	testFile := filepath.Join(tempDir, "prompt.md")
+	if err := os.WriteFile(testFile, []byte("original"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if err := tracker.RecordFile("prompt.md", testFile, "/source"); err != nil {
+		t.Fatalf("Failed to record file: %v", err)
+	}
+
+	modified, err := tracker.IsModified("prompt.md", testFile)
+	if err != nil {
+		t.Fatalf("IsModified failed: %v", err)
+	}
+	if modified {
+		t.Error("Unchanged file reported as modified")
+	}
+
+	if err := os.WriteFile(testFile, []byte("hand-tuned"), 0644); err != nil {
+		t.Fatalf("Failed to modify test file: %v", err)
+	}
+
+	modified, err = tracker.IsModified("prompt.md", testFile)
+	if err != nil {
+		t.Fatalf("IsModified failed: %v", err)
+	}
+	if !modified {
+		t.Error("Edited file not reported as modified")
+	}
+}
+
+func TestTrackerMigratesLegacyMD5(t *testing.T) {
+	tempDir := t.TempDir()
+	trackingFile := filepath.Join(tempDir, "tracking.json")
+
+	if err := os.WriteFile(filepath.Join(tempDir, "unchanged.md"), []byte("Hello, World!"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "edited.md"), []byte("edited"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	legacy := `{"timestamp":"2025-01-01T00:00:00Z","scope":"project","base_path":"` + filepath.ToSlash(tempDir) + `","files":[
+		{"path":"unchanged.md","md5":"65a8e27d8879283831b664bd8b7f0ad4","size":13,"source":"/source"},
+		{"path":"edited.md","md5":"65a8e27d8879283831b664bd8b7f0ad4","size":13,"source":"/source"}]}`
+	if err := os.WriteFile(trackingFile, []byte(legacy), 0644); err != nil {
+		t.Fatalf("Failed to write tracking file: %v", err)
+	}
+
+	tracker := NewTracker(trackingFile, "", "")
+	if err := tracker.Load(); err != nil {
+		t.Fatalf("Failed to load tracker: %v", err)
+	}
+
+	unchanged, _ := tracker.GetEntry("unchanged.md")
+	if unchanged.Algorithm != checksum.SHA256 || unchanged.Hash != "dffd6021bb2bd5b0af676290809ec3a53191dd81c7f70a4b28688a362182986f" {
+		t.Errorf("Unchanged file not re-hashed: %+v", unchanged)
+	}
+
+	edited, _ := tracker.GetEntry("edited.md")
+	if edited.Algorithm != checksum.MD5 || edited.Hash != "65a8e27d8879283831b664bd8b7f0ad4" {
+		t.Errorf("Edited file should keep its MD5: %+v", edited)
+	}
+
+	modified, err := tracker.IsModified("edited.md", filepath.Join(tempDir, "edited.md"))
+	if err != nil {
+		t.Fatalf("IsModified failed: %v", err)
+	}
+	if !modified {
+		t.Error("Edited file should still be reported as modified after migration")
+	}
+}
+
+func TestTrackerRecordEntryReplacesExisting(t *testing.T) {
+	tracker := NewTracker(filepath.Join(t.TempDir(), "tracking.json"), "project", "")
+
+	for i := 0; i < 100; i++ {
+		tracker.RecordEntry(FileEntry{Path: fmt.Sprintf("file%d.md", i), Hash: "old"})
+	}
+	tracker.RecordEntry(FileEntry{Path: "file42.md", Hash: "new"})
+	tracker.RemoveFile("file0.md")
+	tracker.RecordEntry(FileEntry{Path: "file99.md", Hash: "new"})
+
+	if got := len(tracker.GetTrackedFiles()); got != 99 {
+		t.Fatalf("Expected 99 tracked files, got %d", got)
+	}
+	for _, path := range []string{"file42.md", "file99.md"} {
+		entry, ok := tracker.GetEntry(path)
+		if !ok || entry.Hash != "new" {
+			t.Errorf("Entry %s not replaced: %+v", path, entry)
+		}
+	}
+}
+
+func TestTrackerKeepsEntriesSorted(t *testing.T) {
+	trackingFile := filepath.Join(t.TempDir(), "tracking.json")
+	tracker := NewTracker(trackingFile, "project", "")
+	for _, path := range []string{"b.md", "c.md", "a.md"} {
+		tracker.RecordEntry(FileEntry{Path: path, Hash: path})
+	}
+
+	if got := strings.Join(tracker.GetTrackedFiles(), ","); got != "a.md,b.md,c.md" {
+		t.Errorf("GetTrackedFiles() = %s, want sorted paths", got)
+	}
+	// The index must follow the entries after sorting
+	tracker.RecordEntry(FileEntry{Path: "0.md", Hash: "0.md"})
+	for _, path := range []string{"0.md", "a.md", "b.md", "c.md"} {
+		if entry, ok := tracker.GetEntry(path); !ok || entry.Hash != path {
+			t.Errorf("GetEntry(%s) = %+v", path, entry)
+		}
+	}
+
+	if err := tracker.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	loaded := NewTracker(trackingFile, "project", "")
+	if err := loaded.Load(); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	var saved []string
+	for _, entry := range loaded.Installation.Files {
+		saved = append(saved, entry.Path)
+	}
+	if got := strings.Join(saved, ","); got != "0.md,a.md,b.md,c.md" {
+		t.Errorf("Saved entries in order %s", got)
+	}
+}
+
+func TestTrackerLockIsExclusive(t *testing.T) {
+	tr := NewTracker(filepath.Join(t.TempDir(), "tracking.json"), "project", "")
+
+	unlock, err := tr.Lock()
+	if err != nil {
+		t.Fatalf("Lock failed: %v", err)
+	}
+
+	if _, err := tr.Lock(); apperr.KindOf(err) != apperr.Locked {
+		t.Errorf("Second Lock() error = %v, want a locked error", err)
+	}
+
+	if err := unlock(); err != nil {
+		t.Fatalf("Unlock failed: %v", err)
+	}
+
+	unlock, err = tr.Lock()
+	if err != nil {
+		t.Fatalf("Lock after unlock failed: %v", err)
+	}
+	unlock()
+}
+
+func TestTrackerLoadKeepsGivenBasePath(t *testing.T) {
+	trackingFile := filepath.Join(t.TempDir(), "tracking.json")
+	if err := NewTracker(trackingFile, "project", ".").Save(); err != nil {
+		t.Fatalf("Failed to save tracker: %v", err)
+	}
+
+	moved := NewTracker(trackingFile, "", "/srv/checkout")
+	if err := moved.Load(); err != nil {
+		t.Fatalf("Failed to load tracker: %v", err)
+	}
+	if moved.Installation.BasePath != "/srv/checkout" {
+		t.Errorf("BasePath = %q, want the path given to NewTracker", moved.Installation.BasePath)
+	}
+
+	stored := NewTracker(trackingFile, "", "")
+	if err := stored.Load(); err != nil {
+		t.Fatalf("Failed to load tracker: %v", err)
+	}
+	if stored.Installation.BasePath != "." {
+		t.Errorf("BasePath = %q, want the stored path", stored.Installation.BasePath)
+	}
+}
+
+func TestTrackerState(t *testing.T) {
+	tempDir := t.TempDir()
+	tracker := NewTracker(filepath.Join(tempDir, "tracking.json"), "project", tempDir)
+
+	paths := map[string]string{}
+	for _, name := range []string{"intact.md", "edited.md", "resized.md", "emptied.md", "deleted.md", "dangling.md"} {
+		path := filepath.Join(tempDir, name)
+		if err := os.WriteFile(path, []byte("original"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := tracker.RecordFile(name, path, "/source"); err != nil {
+			t.Fatalf("Failed to record file: %v", err)
+		}
+		paths[name] = path
+	}
+
+	os.WriteFile(paths["edited.md"], []byte("Original"), 0644)
+	os.WriteFile(paths["resized.md"], []byte("original, extended"), 0644)
+	os.WriteFile(paths["emptied.md"], nil, 0644)
+	os.Remove(paths["deleted.md"])
+	os.Remove(paths["dangling.md"])
+	if err := os.Symlink(filepath.Join(tempDir, "gone"), paths["dangling.md"]); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"intact.md":   StateIntact,
+		"edited.md":   StateModified,
+		"resized.md":  StateModified,
+		"emptied.md":  StateCorrupted,
+		"deleted.md":  StateMissing,
+		"dangling.md": StateCorrupted,
+	}
+	for name, state := range want {
+		got, err := tracker.State(name, paths[name])
+		if err != nil {
+			t.Fatalf("State(%s) failed: %v", name, err)
+		}
+		if got != state {
+			t.Errorf("State(%s) = %s, want %s", name, got, state)
+		}
+	}
+
+	// Verify fails the run while any file isn't intact
+	states, err := tracker.States([]string{"edited.md", "intact.md"})
+	if err != nil || len(states) != 2 {
+		t.Fatalf("States() = %v, %v", states, err)
+	}
+	if err := Verify(states); apperr.ExitCode(err) != apperr.Conflict.ExitCode() {
+		t.Errorf("Verify() = %v, want a conflict", err)
+	}
+	states, _ = tracker.States([]string{"intact.md"})
+	if err := Verify(states); err != nil {
+		t.Errorf("Verify() = %v for an intact file", err)
+	}
+}